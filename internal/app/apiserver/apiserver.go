@@ -3,13 +3,29 @@ package apiserver
 import (
 	"database/sql"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gorilla/sessions"
 	"github.com/pyuldashev912/todoapp/internal/app/store/sqlstore"
+	"github.com/sirupsen/logrus"
 )
 
+// trashPurgeInterval is how often the trash is checked for expired tasks
+const trashPurgeInterval = time.Hour
+
 // Start starts server
 func Start(config *Config) error {
+	retention, err := config.trashRetention()
+	if err != nil {
+		return err
+	}
+
+	requireVerified, err := config.requireVerifiedEmail()
+	if err != nil {
+		return err
+	}
+
 	db, err := newDB(config.DatabaseURL)
 	if err != nil {
 		return err
@@ -17,13 +33,51 @@ func Start(config *Config) error {
 
 	defer db.Close()
 
+	blobs, err := config.blobStore()
+	if err != nil {
+		return err
+	}
+
+	mailer, err := config.mailer(logrus.StandardLogger())
+	if err != nil {
+		return err
+	}
+
 	store := sqlstore.New(db)
 	sessionStore := sessions.NewCookieStore([]byte(config.SessioKey))
-	srv := newServer(store, sessionStore)
+	srv := newServer(store, sessionStore, blobs, mailer)
+	srv.appURL = strings.TrimSuffix(config.AppURL, "/")
+	srv.requireVerified = requireVerified
+	if config.SMTP.Host == "" {
+		srv.logger.Warn("SMTP_HOST is not set, emails are not delivered")
+	}
+
+	if retention > 0 {
+		go srv.purgeTrash(retention, time.Tick(trashPurgeInterval))
+	}
 
 	return http.ListenAndServe(config.BindAddr, srv)
 }
 
+// purgeTrash permanently deletes the tasks that stayed in the trash longer
+// than the retention along with the files of their attachments, once right
+// away and then on every tick
+func (s *server) purgeTrash(retention time.Duration, tick <-chan time.Time) {
+	for {
+		n, err := s.store.Task().PurgeDeleted(time.Now().Add(-retention))
+		if err != nil {
+			s.logger.Errorf("purging trash: %v", err)
+		} else if n > 0 {
+			s.logger.Infof("purged %d tasks from the trash", n)
+		}
+		s.cleanupAttachments()
+
+		if _, ok := <-tick; !ok {
+			return
+		}
+	}
+}
+
 func newDB(databaseURL string) (*sql.DB, error) {
 	db, err := sql.Open("postgres", databaseURL)
 	if err != nil {
@@ -1,20 +1,141 @@
 package apiserver
 
-import "os"
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/pyuldashev912/todoapp/internal/app/blobstore"
+	"github.com/pyuldashev912/todoapp/internal/app/blobstore/filestore"
+	"github.com/pyuldashev912/todoapp/internal/app/blobstore/s3store"
+	"github.com/pyuldashev912/todoapp/internal/app/mailer"
+	"github.com/pyuldashev912/todoapp/internal/app/mailer/logmailer"
+	"github.com/pyuldashev912/todoapp/internal/app/mailer/smtpmailer"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultTrashRetention is how long deleted tasks stay in the trash if TRASH_RETENTION is not set
+const defaultTrashRetention = 30 * 24 * time.Hour
+
+// defaultBlobDir is where attached files are kept if BLOB_DIR is not set
+const defaultBlobDir = "attachments"
+
+// defaultSMTPPort is the submission port used if SMTP_PORT is not set
+const defaultSMTPPort = "587"
 
 type Config struct {
 	BindAddr    string
 	LogLevel    string
 	DatabaseURL string
 	SessioKey   string
+	// TrashRetention is a duration like "720h" after which deleted tasks are
+	// purged from the trash, "0" keeps them forever
+	TrashRetention string
+	// BlobStore is where attached files are kept, "local" for BlobDir on the
+	// disk or "s3" for a bucket of an S3 compatible service
+	BlobStore string
+	BlobDir   string
+	S3        s3store.Config
+	// SMTP is the server emails are sent through, without a host they are
+	// only logged and never delivered
+	SMTP smtpmailer.Config
+	// AppURL is the address of the app the links in emails lead to
+	AppURL string
+	// RequireVerifiedEmail is "true" to keep the users who didn't verify
+	// their email out of everything but their account
+	RequireVerifiedEmail string
 }
 
 // NewConfig return new Config instance
 func NewConfig() *Config {
 	return &Config{
-		BindAddr:    os.Getenv("BIND_ADDR"),
-		LogLevel:    os.Getenv("LOG_LEVEL"),
-		DatabaseURL: os.Getenv("DATABASE_URL"),
-		SessioKey:   os.Getenv("SESSION_KEY"),
+		BindAddr:       os.Getenv("BIND_ADDR"),
+		LogLevel:       os.Getenv("LOG_LEVEL"),
+		DatabaseURL:    os.Getenv("DATABASE_URL"),
+		SessioKey:      os.Getenv("SESSION_KEY"),
+		TrashRetention: os.Getenv("TRASH_RETENTION"),
+		BlobStore:      os.Getenv("BLOB_STORE"),
+		BlobDir:        os.Getenv("BLOB_DIR"),
+		S3: s3store.Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		},
+		SMTP: smtpmailer.Config{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		},
+		AppURL:               os.Getenv("APP_URL"),
+		RequireVerifiedEmail: os.Getenv("REQUIRE_VERIFIED_EMAIL"),
+	}
+}
+
+// trashRetention parses TrashRetention falling back to defaultTrashRetention
+func (c *Config) trashRetention() (time.Duration, error) {
+	if c.TrashRetention == "" {
+		return defaultTrashRetention, nil
+	}
+
+	return time.ParseDuration(c.TrashRetention)
+}
+
+// requireVerifiedEmail parses RequireVerifiedEmail, unverified users are
+// let in by default
+func (c *Config) requireVerifiedEmail() (bool, error) {
+	if c.RequireVerifiedEmail == "" {
+		return false, nil
+	}
+
+	return strconv.ParseBool(c.RequireVerifiedEmail)
+}
+
+// blobStore builds the configured blob store, the local one by default
+func (c *Config) blobStore() (blobstore.BlobStore, error) {
+	switch c.BlobStore {
+	case "", "local":
+		dir := c.BlobDir
+		if dir == "" {
+			dir = defaultBlobDir
+		}
+
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+
+		return filestore.New(dir), nil
+	case "s3":
+		if c.S3.Endpoint == "" || c.S3.Bucket == "" {
+			return nil, errors.New("BLOB_STORE s3 needs S3_ENDPOINT and S3_BUCKET")
+		}
+
+		return s3store.New(c.S3), nil
+	default:
+		return nil, fmt.Errorf("invalid BLOB_STORE %q, expected local or s3", c.BlobStore)
 	}
 }
+
+// mailer builds the SMTP mailer, or one only logging the emails to the
+// logger if no SMTP host is set
+func (c *Config) mailer(logger logrus.FieldLogger) (mailer.Mailer, error) {
+	if c.SMTP.Host == "" {
+		return logmailer.New(logger), nil
+	}
+
+	if c.SMTP.From == "" {
+		return nil, errors.New("SMTP_HOST needs MAIL_FROM")
+	}
+
+	smtp := c.SMTP
+	if smtp.Port == "" {
+		smtp.Port = defaultSMTPPort
+	}
+
+	return smtpmailer.New(smtp), nil
+}
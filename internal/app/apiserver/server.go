@@ -1,29 +1,74 @@
 package apiserver
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/sessions"
+	"github.com/pyuldashev912/todoapp/internal/app/blobstore"
+	"github.com/pyuldashev912/todoapp/internal/app/mailer"
 	"github.com/pyuldashev912/todoapp/internal/app/model"
 	"github.com/pyuldashev912/todoapp/internal/app/store"
+	"github.com/pyuldashev912/todoapp/internal/app/store/filter"
 	"github.com/sirupsen/logrus"
 )
 
 const (
 	sessionName        = "todoapp"
 	ctxKeyUser  ctxKey = iota
+	ctxKeyOrganization
+	// ctxKeyAccount holds the *model.User of the session, ctxKeyUser its id
+	ctxKeyAccount
+)
+
+const (
+	defaultTaskLimit   = 50
+	maxTaskLimit       = 100
+	maxBatchOperations = 100
+	// defaultReportPeriod is how far back time reports go without from
+	defaultReportPeriod = 7 * 24 * time.Hour
+	// defaultBurndownDays is the length of a burndown without from, two
+	// weeks of a usual sprint
+	defaultBurndownDays = 14
+	maxBurndownDays     = 366
+	// multipartOverhead is what an upload may take on top of the file for
+	// the boundaries and headers of the form
+	multipartOverhead = 1 << 20
 )
 
 var (
 	ErrIncorrectEmailOrPassword = errors.New("incorrect email or password")
 	ErrNotAuthenticated         = errors.New("not authenticated")
+	ErrInvalidDate              = errors.New("invalid date, expected RFC 3339 or YYYY-MM-DD")
+	ErrInvalidLimit             = fmt.Errorf("invalid limit, expected a number from 1 to %d", maxTaskLimit)
+	ErrInvalidOrder             = errors.New("invalid order, expected asc or desc")
+	ErrInvalidTagMode           = errors.New("invalid tag_mode, expected any or all")
+	ErrInvalidMoveTo            = errors.New("invalid move_to, expected a project id without delete_tasks")
+	ErrInvalidPriority          = errors.New("invalid priority, expected none, low, medium, high or urgent")
+	ErrEmptySearch              = errors.New("missing search query q")
+	ErrInvalidPosition          = errors.New("invalid position, expected either before or after a task id")
+	ErrInvalidBatch             = fmt.Errorf("invalid batch, expected 1 to %d operations", maxBatchOperations)
+	ErrInvalidEntryId           = errors.New("invalid time entry id")
+	ErrInvalidPeriod            = errors.New("invalid period, from must be before to")
+	ErrPeriodTooLong            = fmt.Errorf("period too long, expected at most %d days", maxBurndownDays)
+	ErrMissingFile              = errors.New("missing file, expected a multipart form with a file field")
+	ErrInvalidAssignee          = errors.New("invalid assignee, expected me or a user id")
+	ErrRoleForbidden            = errors.New("your role in the organization doesn't allow this")
+	ErrEmailNotVerified         = errors.New("verify your email to use the account")
+	ErrAlreadyVerified          = errors.New("the email is already verified")
 )
 
 type ctxKey int8
@@ -33,15 +78,31 @@ type server struct {
 	logger       *logrus.Logger
 	store        store.Store
 	sessionStore sessions.Store
+	blobs        blobstore.BlobStore
+	mailer       mailer.Mailer
+	// appURL is the address of the app the links in emails lead to
+	appURL string
+	// requireVerified keeps the users who didn't verify their email out of
+	// everything but unverifiedPaths
+	requireVerified bool
+}
+
+// unverifiedPaths are open to the users who didn't verify their email
+var unverifiedPaths = map[string]bool{
+	"/users/me":                  true,
+	"/users/logout":              true,
+	"/users/verify-email/resend": true,
 }
 
 // newStore returns a new instance of server.
-func newServer(store store.Store, sessionStore sessions.Store) *server {
+func newServer(store store.Store, sessionStore sessions.Store, blobs blobstore.BlobStore, mailer mailer.Mailer) *server {
 	s := &server{
 		router:       mux.NewRouter(),
 		logger:       logrus.New(),
 		store:        store,
 		sessionStore: sessionStore,
+		blobs:        blobs,
+		mailer:       mailer,
 	}
 
 	s.configureRouter()
@@ -52,18 +113,102 @@ func newServer(store store.Store, sessionStore sessions.Store) *server {
 func (s *server) configureRouter() {
 	s.router.HandleFunc("/sign-up", s.handleUserCreate()).Methods("POST")
 	s.router.HandleFunc("/sign-in", s.handleUserLogin()).Methods("POST")
+	s.router.HandleFunc("/password/forgot", s.handlePasswordForgot()).Methods("POST")
+	s.router.HandleFunc("/password/reset", s.handlePasswordReset()).Methods("POST")
+	s.router.HandleFunc("/verify-email", s.handleEmailVerify()).Methods("POST")
 
 	auth := s.router.PathPrefix("/users").Subrouter()
-	auth.Use(s.authUserMW)
+	auth.Use(s.authUserMW, s.verifiedMW)
 	auth.HandleFunc("/logout", s.handleUserLogout()).Methods("POST")
 	auth.HandleFunc("/me", s.handleWhoAmI()).Methods("GET")
+	auth.HandleFunc("/verify-email/resend", s.handleEmailVerifyResend()).Methods("POST")
 
-	auth.HandleFunc("/tasks", s.handleTaskGetDone()).Methods("GET").Queries("done", "{done}")
 	auth.HandleFunc("/tasks", s.handleTaskGetAll()).Methods("GET")
 	auth.HandleFunc("/tasks", s.handleTaskAdd()).Methods("POST")
+	auth.HandleFunc("/tasks/search", s.handleTaskSearch()).Methods("GET")
+	auth.HandleFunc("/tasks/batch", s.handleTaskBatch()).Methods("POST")
+	auth.HandleFunc("/tasks/batch/complete-all", s.handleTaskCompleteAll()).Methods("POST")
+	auth.HandleFunc("/tasks/batch/delete-done", s.handleTaskDeleteDone()).Methods("POST")
 	auth.HandleFunc("/tasks/{id}", s.handleTaskGet()).Methods("GET")
-	auth.HandleFunc("/tasks/{id}", s.handleTaskDone()).Methods("PATCH")
+	auth.HandleFunc("/tasks/{id}", s.handleTaskPatch()).Methods("PATCH")
+	auth.HandleFunc("/tasks/{id}", s.handleTaskReplace()).Methods("PUT")
 	auth.HandleFunc("/tasks/{id}", s.handleTaskDelete()).Methods("DELETE")
+	auth.HandleFunc("/tasks/{id}/restore", s.handleTaskRestore()).Methods("POST")
+	auth.HandleFunc("/tasks/{id}/move", s.handleTaskMove()).Methods("POST")
+	auth.HandleFunc("/tasks/{id}/children", s.handleTaskGetChildren()).Methods("GET")
+	auth.HandleFunc("/tasks/{id}/series", s.handleTaskGetSeries()).Methods("GET")
+	auth.HandleFunc("/tasks/{id}/history", s.handleTaskGetHistory()).Methods("GET")
+	auth.HandleFunc("/tasks/{id}/tags/{tag_id}", s.handleTaskTagAttach()).Methods("PUT")
+	auth.HandleFunc("/tasks/{id}/tags/{tag_id}", s.handleTaskTagDetach()).Methods("DELETE")
+	auth.HandleFunc("/tasks/{id}/blockers/{blocker_id}", s.handleTaskBlockerAdd()).Methods("PUT")
+	auth.HandleFunc("/tasks/{id}/blockers/{blocker_id}", s.handleTaskBlockerRemove()).Methods("DELETE")
+	auth.HandleFunc("/tasks/{id}/comments", s.handleCommentGetAll()).Methods("GET")
+	auth.HandleFunc("/tasks/{id}/comments", s.handleCommentCreate()).Methods("POST")
+	auth.HandleFunc("/tasks/{id}/comments/{comment_id}", s.handleCommentUpdate()).Methods("PATCH")
+	auth.HandleFunc("/tasks/{id}/comments/{comment_id}", s.handleCommentDelete()).Methods("DELETE")
+	auth.HandleFunc("/tasks/{id}/attachments", s.handleAttachmentGetAll()).Methods("GET")
+	auth.HandleFunc("/tasks/{id}/attachments", s.handleAttachmentCreate()).Methods("POST")
+	auth.HandleFunc("/tasks/{id}/attachments/{attachment_id}", s.handleAttachmentGet()).Methods("GET")
+	auth.HandleFunc("/tasks/{id}/attachments/{attachment_id}", s.handleAttachmentDelete()).Methods("DELETE")
+	auth.HandleFunc("/tasks/{id}/shares", s.handleTaskShareGetAll()).Methods("GET")
+	auth.HandleFunc("/tasks/{id}/shares", s.handleTaskShareCreate()).Methods("POST")
+	auth.HandleFunc("/tasks/{id}/timer/start", s.handleTimerStart()).Methods("POST")
+	auth.HandleFunc("/tasks/{id}/timer/stop", s.handleTimerStop()).Methods("POST")
+	auth.HandleFunc("/tasks/{id}/time", s.handleTaskTimeGet()).Methods("GET")
+	auth.HandleFunc("/tasks/{id}/time", s.handleTimeEntryCreate()).Methods("POST")
+
+	auth.HandleFunc("/trash", s.handleTrashGet()).Methods("GET")
+	auth.HandleFunc("/trash", s.handleTrashEmpty()).Methods("DELETE")
+	auth.HandleFunc("/trash/{id}", s.handleTrashPurge()).Methods("DELETE")
+
+	auth.HandleFunc("/projects", s.handleProjectGetAll()).Methods("GET")
+	auth.HandleFunc("/projects", s.handleProjectCreate()).Methods("POST")
+	auth.HandleFunc("/projects/{id}", s.handleProjectGet()).Methods("GET")
+	auth.HandleFunc("/projects/{id}", s.handleProjectUpdate()).Methods("PATCH")
+	auth.HandleFunc("/projects/{id}", s.handleProjectDelete()).Methods("DELETE")
+	auth.HandleFunc("/projects/{id}/workflow", s.handleWorkflowGet()).Methods("GET")
+	auth.HandleFunc("/projects/{id}/workflow", s.handleWorkflowSave()).Methods("PUT")
+	auth.HandleFunc("/projects/{id}/workflow", s.handleWorkflowDelete()).Methods("DELETE")
+	auth.HandleFunc("/projects/{id}/board", s.handleBoardGet()).Methods("GET")
+	auth.HandleFunc("/projects/{id}/shares", s.handleProjectShareGetAll()).Methods("GET")
+	auth.HandleFunc("/projects/{id}/shares", s.handleProjectShareCreate()).Methods("POST")
+
+	auth.HandleFunc("/shared", s.handleSharedGet()).Methods("GET")
+	auth.HandleFunc("/shares/{id}", s.handleShareDelete()).Methods("DELETE")
+
+	auth.HandleFunc("/orgs", s.handleOrganizationGetAll()).Methods("GET")
+	auth.HandleFunc("/orgs", s.handleOrganizationCreate()).Methods("POST")
+	auth.HandleFunc("/invitations/{token}/accept", s.handleInvitationAccept()).Methods("POST")
+
+	org := auth.PathPrefix("/orgs/{org_id}").Subrouter()
+	org.Use(s.authOrgMW)
+	org.HandleFunc("", s.orgRole(model.OrgRoleMember, s.handleOrganizationGet())).Methods("GET")
+	org.HandleFunc("", s.orgRole(model.OrgRoleAdmin, s.handleOrganizationUpdate())).Methods("PATCH")
+	org.HandleFunc("", s.orgRole(model.OrgRoleOwner, s.handleOrganizationDelete())).Methods("DELETE")
+	org.HandleFunc("/members", s.orgRole(model.OrgRoleMember, s.handleMemberGetAll())).Methods("GET")
+	org.HandleFunc("/members/{user_id}", s.orgRole(model.OrgRoleOwner, s.handleMemberUpdate())).Methods("PATCH")
+	org.HandleFunc("/members/{user_id}", s.orgRole(model.OrgRoleMember, s.handleMemberDelete())).Methods("DELETE")
+	org.HandleFunc("/invitations", s.orgRole(model.OrgRoleAdmin, s.handleInvitationGetAll())).Methods("GET")
+	org.HandleFunc("/invitations", s.orgRole(model.OrgRoleAdmin, s.handleInvitationCreate())).Methods("POST")
+	org.HandleFunc("/invitations/{invitation_id}", s.orgRole(model.OrgRoleAdmin, s.handleInvitationDelete())).Methods("DELETE")
+	org.HandleFunc("/projects", s.orgRole(model.OrgRoleMember, s.handleOrganizationProjectGetAll())).Methods("GET")
+	org.HandleFunc("/projects", s.orgRole(model.OrgRoleAdmin, s.handleOrganizationProjectCreate())).Methods("POST")
+
+	auth.HandleFunc("/workflow", s.handleWorkflowGet()).Methods("GET")
+	auth.HandleFunc("/workflow", s.handleWorkflowSave()).Methods("PUT")
+	auth.HandleFunc("/workflow", s.handleWorkflowDelete()).Methods("DELETE")
+	auth.HandleFunc("/board", s.handleBoardGet()).Methods("GET")
+
+	auth.HandleFunc("/timer", s.handleTimerGet()).Methods("GET")
+	auth.HandleFunc("/time", s.handleTimeReport()).Methods("GET")
+	auth.HandleFunc("/time/{id}", s.handleTimeEntryDelete()).Methods("DELETE")
+
+	auth.HandleFunc("/reports/burndown", s.handleBurndownReport()).Methods("GET")
+
+	auth.HandleFunc("/tags", s.handleTagGetAll()).Methods("GET")
+	auth.HandleFunc("/tags", s.handleTagCreate()).Methods("POST")
+	auth.HandleFunc("/tags/{id}", s.handleTagRename()).Methods("PATCH")
+	auth.HandleFunc("/tags/{id}", s.handleTagDelete()).Methods("DELETE")
 }
 
 func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
@@ -84,7 +229,34 @@ func (s *server) authUserMW(next http.Handler) http.Handler {
 			return
 		}
 
-		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKeyUser, userId)))
+		// A reset of the password logs out the sessions created before it, the
+		// sessions older than the versions have none and count as the first one
+		version, _ := session.Values["session_version"].(int)
+		user, err := s.store.User().FindById(userId.(int))
+		if err != nil || user.SessionVersion != version {
+			s.error(w, r, http.StatusUnauthorized, ErrNotAuthenticated)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), ctxKeyUser, userId)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, ctxKeyAccount, user)))
+	})
+}
+
+// verifiedMW turns away the users who didn't verify their email if it is required
+func (s *server) verifiedMW(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.requireVerified || unverifiedPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !r.Context().Value(ctxKeyAccount).(*model.User).Verified {
+			s.error(w, r, http.StatusForbidden, ErrEmailNotVerified)
+			return
+		}
+
+		next.ServeHTTP(w, r)
 	})
 }
 
@@ -113,6 +285,11 @@ func (s *server) handleUserCreate() http.HandlerFunc {
 			return
 		}
 
+		// The account is created even if the email can't be sent, the link can be sent again
+		if err := s.sendVerification(u); err != nil {
+			s.logger.Errorf("sending email verification to user %d: %v", u.ID, err)
+		}
+
 		u.Sanitize()
 		s.respond(w, r, http.StatusCreated, u)
 	}
@@ -150,6 +327,7 @@ func (s *server) handleUserLogin() http.HandlerFunc {
 		}
 
 		session.Values["user_id"] = user.ID
+		session.Values["session_version"] = user.SessionVersion
 		if err = s.sessionStore.Save(r, w, session); err != nil {
 			s.error(w, r, http.StatusInternalServerError, err)
 			return
@@ -169,6 +347,7 @@ func (s *server) handleUserLogout() http.HandlerFunc {
 
 		session.Options.MaxAge = -1
 		delete(session.Values, "user_id")
+		delete(session.Values, "session_version")
 		if err := session.Save(r, w); err != nil {
 			s.error(w, r, http.StatusInternalServerError, err)
 			return
@@ -179,38 +358,192 @@ func (s *server) handleUserLogout() http.HandlerFunc {
 
 func (s *server) handleWhoAmI() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		userId := r.Context().Value(ctxKeyUser).(int)
-		user, err := s.store.User().FindById(userId)
+		s.respond(w, r, http.StatusOK, r.Context().Value(ctxKeyAccount).(*model.User))
+	}
+}
+
+// handlePasswordForgot emails a password reset token to the user. It answers
+// the same whether or not the email belongs to a user, so that it can't be
+// used to find out who has an account.
+func (s *server) handlePasswordForgot() http.HandlerFunc {
+	type request struct {
+		Email string `json:"email"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		info := map[string]string{"info": "if the email belongs to an account, a password reset link was sent to it"}
+		user, err := s.store.User().FindByEmail(strings.ToLower(req.Email))
 		if err != nil {
-			s.error(w, r, http.StatusUnauthorized, ErrNotAuthenticated)
+			s.respond(w, r, http.StatusOK, info)
+			return
+		}
+
+		reset := &model.PasswordReset{UserID: user.ID}
+		if err := s.store.User().CreatePasswordReset(reset); err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
 			return
 		}
 
-		s.respond(w, r, http.StatusOK, user)
+		if err := s.mailer.Send(&mailer.Message{
+			To:      user.Email,
+			Subject: "Reset your password",
+			Body: fmt.Sprintf(
+				"Use this to set a new password within %d minutes:\n\n%s\n\n"+
+					"If you didn't ask to reset your password, ignore this email.",
+				int(model.PasswordResetTTL.Minutes()), s.emailLink("/reset-password", reset.Token),
+			),
+		}); err != nil {
+			s.logger.Errorf("sending password reset to user %d: %v", user.ID, err)
+		}
+
+		s.respond(w, r, http.StatusOK, info)
 	}
 }
 
-func (s *server) handleTaskAdd() http.HandlerFunc {
-	type Request struct {
-		Title       string `json:"title"`
-		Description string `json:"description"`
+func (s *server) handlePasswordReset() http.HandlerFunc {
+	type request struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if _, err := s.store.User().ResetPassword(req.Token, req.Password); err != nil {
+			if err == store.ErrInvalidResetToken {
+				s.error(w, r, http.StatusNotFound, err)
+				return
+			}
+			s.error(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, map[string]string{"info": "your password was reset, sign in with the new one"})
+	}
+}
+
+func (s *server) handleEmailVerify() http.HandlerFunc {
+	type request struct {
+		Token string `json:"token"`
 	}
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		req := &Request{}
+		req := &request{}
 		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
 			s.error(w, r, http.StatusBadRequest, err)
 			return
 		}
 
-		task := &model.Task{
-			UserID:       r.Context().Value(ctxKeyUser).(int),
-			Title:        req.Title,
-			Description:  req.Description,
-			Done:         false,
-			CreationDate: time.Now().Format("02/01/06"),
+		if _, err := s.store.User().Verify(req.Token); err != nil {
+			if err == store.ErrInvalidVerification {
+				s.error(w, r, http.StatusNotFound, err)
+				return
+			}
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, map[string]string{"info": "your email is verified"})
+	}
+}
+
+func (s *server) handleEmailVerifyResend() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(ctxKeyAccount).(*model.User)
+		if user.Verified {
+			s.error(w, r, http.StatusConflict, ErrAlreadyVerified)
+			return
+		}
+
+		if err := s.sendVerification(user); err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, map[string]string{"info": "a verification link was sent to your email"})
+	}
+}
+
+// sendVerification emails the user a new link to verify their email, the
+// links sent before stop working
+func (s *server) sendVerification(user *model.User) error {
+	v := &model.EmailVerification{UserID: user.ID}
+	if err := s.store.User().CreateVerification(v); err != nil {
+		return err
+	}
+
+	return s.mailer.Send(&mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf(
+			"Use this to verify your email within %d hours:\n\n%s\n\n"+
+				"If you didn't sign up, ignore this email.",
+			int(model.EmailVerificationTTL.Hours()), s.emailLink("/verify-email", v.Token),
+		),
+	})
+}
+
+// emailLink returns the link to the page of the app that takes the token, or
+// the token alone if the address of the app is not configured
+func (s *server) emailLink(page string, token string) string {
+	if s.appURL == "" {
+		return token
+	}
+
+	return s.appURL + page + "?token=" + url.QueryEscape(token)
+}
+
+// taskCreateRequest is the body of a new task
+type taskCreateRequest struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	DueAt       *time.Time     `json:"due_at"`
+	ProjectID   *int           `json:"project_id"`
+	ParentID    *int           `json:"parent_id"`
+	Recurrence  string         `json:"recurrence"`
+	Priority    model.Priority `json:"priority"`
+	Estimate    *int           `json:"estimate"`
+	AssigneeID  *int           `json:"assignee_id"`
+	Status      string         `json:"status"`
+}
+
+func (req *taskCreateRequest) task(userId int) *model.Task {
+	return &model.Task{
+		UserID:       userId,
+		Title:        req.Title,
+		Description:  req.Description,
+		Done:         false,
+		CreationDate: time.Now().Format("02/01/06"),
+		DueAt:        req.DueAt,
+		ProjectID:    req.ProjectID,
+		ParentID:     req.ParentID,
+		Recurrence:   req.Recurrence,
+		Priority:     req.Priority,
+		Estimate:     req.Estimate,
+		AssigneeID:   req.AssigneeID,
+		Status:       req.Status,
+	}
+}
+
+func (s *server) handleTaskAdd() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &taskCreateRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
 		}
 
+		task := req.task(r.Context().Value(ctxKeyUser).(int))
 		if err := s.store.Task().Create(task); err != nil {
 			s.error(w, r, http.StatusUnprocessableEntity, err)
 			return
@@ -241,26 +574,21 @@ func (s *server) handleTaskDelete() http.HandlerFunc {
 		}
 
 		s.respond(w, r, http.StatusOK, map[string]string{
-			"info": "you've successfully deleted a task",
+			"info": "you've moved the task to the trash",
 		})
 	}
 }
 
-func (s *server) handleTaskDone() http.HandlerFunc {
+func (s *server) handleTaskRestore() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userId := r.Context().Value(ctxKeyUser).(int)
-		taskIdString, ok := mux.Vars(r)["id"]
-		if !ok {
-			s.error(w, r, http.StatusBadRequest, nil)
-			return
-		}
-		taskId, err := strconv.Atoi(taskIdString)
+		taskId, err := taskIdFromRequest(r)
 		if err != nil {
 			s.error(w, r, http.StatusBadRequest, err)
 			return
 		}
 
-		if err := s.store.Task().Done(userId, taskId); err != nil {
+		if err := s.store.Task().Restore(userId, taskId); err != nil {
 			if err == store.ErrInvalidTaskId {
 				s.error(w, r, http.StatusNotFound, err)
 				return
@@ -270,31 +598,61 @@ func (s *server) handleTaskDone() http.HandlerFunc {
 			return
 		}
 
-		s.respond(w, r, http.StatusOK, map[string]string{
-			"info": "congrats! you've done a task",
-		})
+		task, err := s.store.Task().GetById(userId, taskId)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, task)
 	}
 }
 
-func (s *server) handleTaskGet() http.HandlerFunc {
+func (s *server) handleTaskMove() http.HandlerFunc {
+	type request struct {
+		Before *int `json:"before"`
+		After  *int `json:"after"`
+	}
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		userId := r.Context().Value(ctxKeyUser).(int)
-		taskIdString, ok := mux.Vars(r)["id"]
-		if !ok {
-			s.error(w, r, http.StatusBadRequest, nil)
+		taskId, err := taskIdFromRequest(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
 			return
 		}
 
-		taskId, err := strconv.Atoi(taskIdString)
-		if err != nil {
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
 			s.error(w, r, http.StatusBadRequest, err)
 			return
 		}
 
+		if (req.Before == nil) == (req.After == nil) {
+			s.error(w, r, http.StatusBadRequest, ErrInvalidPosition)
+			return
+		}
+
+		targetId, after := req.Before, false
+		if req.After != nil {
+			targetId, after = req.After, true
+		}
+
+		if err := s.store.Task().Move(userId, taskId, *targetId, after); err != nil {
+			switch err {
+			case store.ErrInvalidTaskId:
+				s.error(w, r, http.StatusNotFound, err)
+			case store.ErrInvalidMove:
+				s.error(w, r, http.StatusUnprocessableEntity, err)
+			default:
+				s.error(w, r, http.StatusInternalServerError, err)
+			}
+			return
+		}
+
 		task, err := s.store.Task().GetById(userId, taskId)
 		if err != nil {
-
-			s.error(w, r, http.StatusNotFound, err)
+			s.error(w, r, http.StatusInternalServerError, err)
 			return
 		}
 
@@ -302,16 +660,150 @@ func (s *server) handleTaskGet() http.HandlerFunc {
 	}
 }
 
-func (s *server) handleTaskGetDone() http.HandlerFunc {
+func (s *server) handleTaskBatch() http.HandlerFunc {
+	type operation struct {
+		Op    string             `json:"op"`
+		ID    int                `json:"id"`
+		Task  *taskCreateRequest `json:"task"`
+		Patch json.RawMessage    `json:"patch"`
+	}
+
+	type request struct {
+		Atomic     bool         `json:"atomic"`
+		Operations []*operation `json:"operations"`
+	}
+
+	type result struct {
+		Op     string      `json:"op"`
+		Status int         `json:"status"`
+		Task   *model.Task `json:"task,omitempty"`
+		Error  string      `json:"error,omitempty"`
+	}
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		userId := r.Context().Value(ctxKeyUser).(int)
-		done, err := strconv.ParseBool(r.URL.Query().Get("done"))
-		if err != nil {
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
 			s.error(w, r, http.StatusBadRequest, err)
 			return
 		}
 
-		tasks, err := s.store.Task().GetBool(userId, done)
+		if len(req.Operations) == 0 || len(req.Operations) > maxBatchOperations {
+			s.error(w, r, http.StatusBadRequest, ErrInvalidBatch)
+			return
+		}
+
+		ops := make([]*store.TaskOperation, len(req.Operations))
+		for i, o := range req.Operations {
+			op := &store.TaskOperation{Op: o.Op, TaskID: o.ID, Patch: o.Patch}
+			if o.Task != nil {
+				op.Task = o.Task.task(userId)
+			}
+			ops[i] = op
+		}
+
+		results, err := s.store.Task().Batch(userId, ops, req.Atomic)
+		if err != nil && !errors.Is(err, store.ErrBatchRolledBack) {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		resp := make([]*result, len(results))
+		for i, res := range results {
+			item := &result{Op: ops[i].Op, Status: http.StatusOK, Task: res.Task}
+			switch {
+			case res.Err == nil && ops[i].Op == store.BatchCreate:
+				item.Status = http.StatusCreated
+			case res.Err == nil:
+			case errors.Is(res.Err, store.ErrInvalidTaskId):
+				item.Status = http.StatusNotFound
+			case errors.Is(res.Err, store.ErrReadOnly):
+				item.Status = http.StatusForbidden
+			case errors.Is(res.Err, store.ErrInvalidOperation):
+				item.Status = http.StatusBadRequest
+			case errors.Is(res.Err, model.ErrInvalidTransition), errors.Is(res.Err, store.ErrTaskBlocked):
+				item.Status = http.StatusConflict
+			default:
+				item.Status = http.StatusUnprocessableEntity
+			}
+
+			if res.Err != nil {
+				item.Error = res.Err.Error()
+			}
+			resp[i] = item
+		}
+
+		code := http.StatusOK
+		if err != nil {
+			code = http.StatusUnprocessableEntity
+		}
+
+		s.respond(w, r, code, map[string]interface{}{
+			"committed": err == nil,
+			"results":   resp,
+		})
+	}
+}
+
+func (s *server) handleTaskCompleteAll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		n, blocked, err := s.store.Task().CompleteAll(userId)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		info := "you've completed all open tasks"
+		if len(blocked) > 0 {
+			info = "you've completed all open tasks but the blocked ones"
+		}
+
+		s.respond(w, r, http.StatusOK, map[string]interface{}{
+			"info":    info,
+			"count":   n,
+			"blocked": blocked,
+		})
+	}
+}
+
+func (s *server) handleTaskDeleteDone() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		n, err := s.store.Task().DeleteDone(userId)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, map[string]interface{}{
+			"info":  "you've moved completed tasks to the trash",
+			"count": n,
+		})
+	}
+}
+
+func (s *server) handleTaskSearch() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		values := r.URL.Query()
+		text := strings.TrimSpace(values.Get("q"))
+		if text == "" {
+			s.error(w, r, http.StatusBadRequest, ErrEmptySearch)
+			return
+		}
+
+		limit := defaultTaskLimit
+		if v := values.Get("limit"); v != "" {
+			var err error
+			limit, err = strconv.Atoi(v)
+			if err != nil || limit < 1 || limit > maxTaskLimit {
+				s.error(w, r, http.StatusBadRequest, ErrInvalidLimit)
+				return
+			}
+		}
+
+		matches, err := s.store.Task().Search(userId, text, limit)
 		if err != nil {
 			if err == store.ErrNoRecordsInTable {
 				s.error(w, r, http.StatusNotFound, err)
@@ -322,14 +814,38 @@ func (s *server) handleTaskGetDone() http.HandlerFunc {
 			return
 		}
 
-		s.respond(w, r, http.StatusOK, tasks)
+		s.respond(w, r, http.StatusOK, matches)
 	}
 }
 
-func (s *server) handleTaskGetAll() http.HandlerFunc {
+func (s *server) handleTaskGetHistory() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		taskId, err := taskIdFromRequest(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		events, err := s.store.Task().History(userId, taskId)
+		if err != nil {
+			if err == store.ErrInvalidTaskId || err == store.ErrNoRecordsInTable {
+				s.error(w, r, http.StatusNotFound, err)
+				return
+			}
+
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, events)
+	}
+}
+
+func (s *server) handleTrashGet() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userId := r.Context().Value(ctxKeyUser).(int)
-		tasks, err := s.store.Task().GetAll(userId)
+		tasks, err := s.store.Task().GetTrash(userId)
 		if err != nil {
 			if err == store.ErrNoRecordsInTable {
 				s.error(w, r, http.StatusNotFound, err)
@@ -344,6 +860,2230 @@ func (s *server) handleTaskGetAll() http.HandlerFunc {
 	}
 }
 
+func (s *server) handleTrashPurge() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		taskId, err := taskIdFromRequest(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := s.store.Task().Purge(userId, taskId); err != nil {
+			if err == store.ErrInvalidTaskId {
+				s.error(w, r, http.StatusNotFound, err)
+				return
+			}
+
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.cleanupAttachments()
+		s.respond(w, r, http.StatusOK, map[string]string{
+			"info": "you've permanently deleted a task",
+		})
+	}
+}
+
+func (s *server) handleTrashEmpty() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		if err := s.store.Task().EmptyTrash(userId); err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.cleanupAttachments()
+		s.respond(w, r, http.StatusOK, map[string]string{
+			"info": "you've emptied the trash",
+		})
+	}
+}
+
+func (s *server) handleTaskDone() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		taskIdString, ok := mux.Vars(r)["id"]
+		if !ok {
+			s.error(w, r, http.StatusBadRequest, nil)
+			return
+		}
+		taskId, err := strconv.Atoi(taskIdString)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := s.store.Task().Done(userId, taskId); err != nil {
+			if err == store.ErrInvalidTaskId {
+				s.error(w, r, http.StatusNotFound, err)
+				return
+			}
+
+			if err == store.ErrReadOnly {
+				s.error(w, r, http.StatusForbidden, err)
+				return
+			}
+
+			if err == store.ErrTaskBlocked {
+				s.error(w, r, http.StatusConflict, err)
+				return
+			}
+
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, map[string]string{
+			"info": "congrats! you've done a task",
+		})
+	}
+}
+
+func (s *server) handleTaskPatch() http.HandlerFunc {
+	done := s.handleTaskDone()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body []byte
+		if r.Body != nil {
+			b, err := io.ReadAll(r.Body)
+			if err != nil {
+				s.error(w, r, http.StatusBadRequest, err)
+				return
+			}
+
+			body = b
+		}
+
+		// A PATCH without a body keeps its original meaning of completing the task
+		if len(bytes.TrimSpace(body)) == 0 {
+			done(w, r)
+			return
+		}
+
+		userId := r.Context().Value(ctxKeyUser).(int)
+		taskId, err := taskIdFromRequest(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		task, err := s.store.Task().GetById(userId, taskId)
+		if err != nil {
+			s.error(w, r, http.StatusNotFound, err)
+			return
+		}
+
+		patched := *task
+		if err := patched.Patch(body); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		s.updateTask(w, r, &patched)
+	}
+}
+
+func (s *server) handleTaskReplace() http.HandlerFunc {
+	type request struct {
+		Title       string         `json:"title"`
+		Description string         `json:"description"`
+		Done        bool           `json:"done"`
+		DueAt       *time.Time     `json:"due_at"`
+		ProjectID   *int           `json:"project_id"`
+		ParentID    *int           `json:"parent_id"`
+		Recurrence  string         `json:"recurrence"`
+		Priority    model.Priority `json:"priority"`
+		Estimate    *int           `json:"estimate"`
+		AssigneeID  *int           `json:"assignee_id"`
+		Status      string         `json:"status"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		taskId, err := taskIdFromRequest(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		task, err := s.store.Task().GetById(userId, taskId)
+		if err != nil {
+			s.error(w, r, http.StatusNotFound, err)
+			return
+		}
+
+		replaced := *task
+		replaced.Title = req.Title
+		replaced.Description = req.Description
+		replaced.Done = req.Done
+		replaced.DueAt = req.DueAt
+		replaced.ProjectID = req.ProjectID
+		replaced.ParentID = req.ParentID
+		replaced.Recurrence = req.Recurrence
+		replaced.Priority = req.Priority
+		replaced.Estimate = req.Estimate
+		replaced.AssigneeID = req.AssigneeID
+		// Without a status the task keeps its own unless done changes it
+		if req.Status != "" {
+			replaced.Status = req.Status
+		}
+
+		s.updateTask(w, r, &replaced)
+	}
+}
+
+// updateTask stores an edited task and responds with its new state
+func (s *server) updateTask(w http.ResponseWriter, r *http.Request, task *model.Task) {
+	userId := r.Context().Value(ctxKeyUser).(int)
+	if err := s.store.Task().Update(userId, task); err != nil {
+		switch err {
+		case store.ErrInvalidTaskId:
+			s.error(w, r, http.StatusNotFound, err)
+		case store.ErrReadOnly:
+			s.error(w, r, http.StatusForbidden, err)
+		case model.ErrInvalidTransition, store.ErrTaskBlocked:
+			s.error(w, r, http.StatusConflict, err)
+		default:
+			s.error(w, r, http.StatusUnprocessableEntity, err)
+		}
+		return
+	}
+
+	s.respond(w, r, http.StatusOK, task)
+}
+
+func (s *server) handleTaskGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		taskIdString, ok := mux.Vars(r)["id"]
+		if !ok {
+			s.error(w, r, http.StatusBadRequest, nil)
+			return
+		}
+
+		taskId, err := strconv.Atoi(taskIdString)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		task, err := s.store.Task().GetById(userId, taskId)
+		if err != nil {
+
+			s.error(w, r, http.StatusNotFound, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, task)
+	}
+}
+
+func (s *server) handleTaskGetAll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query, err := taskQueryFromRequest(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		s.respondTaskPage(w, r, query)
+	}
+}
+
+func (s *server) handleTaskGetChildren() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		taskId, err := taskIdFromRequest(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if _, err := s.store.Task().GetById(userId, taskId); err != nil {
+			s.error(w, r, http.StatusNotFound, err)
+			return
+		}
+
+		query, err := taskQueryFromRequest(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		query.ParentID = &taskId
+		s.respondTaskPage(w, r, query)
+	}
+}
+
+func (s *server) handleTaskGetSeries() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		taskId, err := taskIdFromRequest(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		task, err := s.store.Task().GetById(userId, taskId)
+		if err != nil {
+			s.error(w, r, http.StatusNotFound, err)
+			return
+		}
+
+		query, err := taskQueryFromRequest(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		seriesId := task.Series()
+		query.SeriesID = &seriesId
+		s.respondTaskPage(w, r, query)
+	}
+}
+
+// respondTaskPage responds with a page of the current user's tasks matching the query
+func (s *server) respondTaskPage(w http.ResponseWriter, r *http.Request, query *store.TaskQuery) {
+	type response struct {
+		Tasks      []*model.Task `json:"tasks"`
+		NextCursor string        `json:"next_cursor,omitempty"`
+	}
+
+	userId := r.Context().Value(ctxKeyUser).(int)
+	tasks, cursor, err := s.store.Task().Find(userId, query)
+	if err != nil {
+		switch err {
+		case store.ErrInvalidCursor, store.ErrInvalidSort:
+			s.error(w, r, http.StatusBadRequest, err)
+		default:
+			s.error(w, r, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	s.respond(w, r, http.StatusOK, &response{Tasks: tasks, NextCursor: cursor})
+}
+
+func (s *server) handleTaskTagAttach() http.HandlerFunc {
+	return s.handleTaskTag(func(userId, taskId, tagId int) error {
+		return s.store.Tag().Attach(userId, taskId, tagId)
+	}, "tag attached")
+}
+
+func (s *server) handleTaskTagDetach() http.HandlerFunc {
+	return s.handleTaskTag(func(userId, taskId, tagId int) error {
+		return s.store.Tag().Detach(userId, taskId, tagId)
+	}, "tag detached")
+}
+
+// handleTaskTag parses the task and tag ids shared by attaching and detaching
+func (s *server) handleTaskTag(action func(int, int, int) error, info string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		taskId, err := taskIdFromRequest(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		tagId, err := idFromRequest(r, "tag_id", store.ErrInvalidTagId)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := action(userId, taskId, tagId); err != nil {
+			if err == store.ErrInvalidTaskId || err == store.ErrInvalidTagId {
+				s.error(w, r, http.StatusNotFound, err)
+				return
+			}
+
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, map[string]string{"info": info})
+	}
+}
+
+func (s *server) handleTaskBlockerAdd() http.HandlerFunc {
+	return s.handleTaskBlocker(func(userId, taskId, blockerId int) error {
+		return s.store.Task().AddBlocker(userId, taskId, blockerId)
+	}, "blocker added")
+}
+
+func (s *server) handleTaskBlockerRemove() http.HandlerFunc {
+	return s.handleTaskBlocker(func(userId, taskId, blockerId int) error {
+		return s.store.Task().RemoveBlocker(userId, taskId, blockerId)
+	}, "blocker removed")
+}
+
+// handleTaskBlocker parses the ids of the blocked task and of its blocker
+func (s *server) handleTaskBlocker(action func(int, int, int) error, info string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		taskId, err := taskIdFromRequest(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		blockerId, err := idFromRequest(r, "blocker_id", store.ErrInvalidTaskId)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := action(userId, taskId, blockerId); err != nil {
+			switch {
+			case err == store.ErrInvalidTaskId, err == store.ErrNoDependency:
+				s.error(w, r, http.StatusNotFound, err)
+			case err == store.ErrReadOnly:
+				s.error(w, r, http.StatusForbidden, err)
+			case err == store.ErrInvalidBlocker:
+				s.error(w, r, http.StatusUnprocessableEntity, err)
+			case errors.Is(err, store.ErrDependencyCycle):
+				s.error(w, r, http.StatusConflict, err)
+			default:
+				s.error(w, r, http.StatusInternalServerError, err)
+			}
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, map[string]string{"info": info})
+	}
+}
+
+func (s *server) handleCommentGetAll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		taskId, err := taskIdFromRequest(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		comments, err := s.store.Comment().GetByTask(userId, taskId)
+		if err != nil {
+			if err == store.ErrInvalidTaskId {
+				s.error(w, r, http.StatusNotFound, err)
+				return
+			}
+
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, comments)
+	}
+}
+
+func (s *server) handleCommentCreate() http.HandlerFunc {
+	type request struct {
+		Body string `json:"body"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		taskId, err := taskIdFromRequest(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		comment := &model.Comment{
+			TaskID: taskId,
+			UserID: r.Context().Value(ctxKeyUser).(int),
+			Body:   req.Body,
+		}
+
+		if err := s.store.Comment().Create(comment); err != nil {
+			if err == store.ErrInvalidTaskId {
+				s.error(w, r, http.StatusNotFound, err)
+				return
+			}
+
+			if err == store.ErrReadOnly {
+				s.error(w, r, http.StatusForbidden, err)
+				return
+			}
+
+			s.error(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusCreated, comment)
+	}
+}
+
+func (s *server) handleCommentUpdate() http.HandlerFunc {
+	type request struct {
+		Body string `json:"body"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		taskId, err := taskIdFromRequest(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		commentId, err := idFromRequest(r, "comment_id", store.ErrInvalidCommentId)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		comment := &model.Comment{
+			ID:     commentId,
+			TaskID: taskId,
+			UserID: r.Context().Value(ctxKeyUser).(int),
+			Body:   req.Body,
+		}
+
+		if err := s.store.Comment().Update(comment); err != nil {
+			s.commentError(w, r, err, http.StatusUnprocessableEntity)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, comment)
+	}
+}
+
+func (s *server) handleCommentDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		taskId, err := taskIdFromRequest(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		commentId, err := idFromRequest(r, "comment_id", store.ErrInvalidCommentId)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := s.store.Comment().Delete(userId, taskId, commentId); err != nil {
+			s.commentError(w, r, err, http.StatusInternalServerError)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, map[string]string{"info": "comment deleted"})
+	}
+}
+
+// commentError responds to a failed change of a comment, other errors get
+// the fallback code
+func (s *server) commentError(w http.ResponseWriter, r *http.Request, err error, fallback int) {
+	switch err {
+	case store.ErrInvalidTaskId, store.ErrInvalidCommentId:
+		s.error(w, r, http.StatusNotFound, err)
+	case store.ErrNotCommentAuthor:
+		s.error(w, r, http.StatusForbidden, err)
+	default:
+		s.error(w, r, fallback, err)
+	}
+}
+
+func (s *server) handleAttachmentGetAll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		taskId, err := taskIdFromRequest(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		attachments, err := s.store.Attachment().GetByTask(userId, taskId)
+		if err != nil {
+			if err == store.ErrInvalidTaskId {
+				s.error(w, r, http.StatusNotFound, err)
+				return
+			}
+
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, attachments)
+	}
+}
+
+// handleAttachmentCreate takes the file field of a multipart form. The content
+// type is sniffed from the file rather than taken from the client.
+func (s *server) handleAttachmentCreate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		taskId, err := taskIdFromRequest(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if _, err := s.store.Task().GetById(userId, taskId); err != nil {
+			if err == store.ErrInvalidTaskId {
+				s.error(w, r, http.StatusNotFound, err)
+				return
+			}
+
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, model.MaxAttachmentSize+multipartOverhead)
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				s.error(w, r, http.StatusRequestEntityTooLarge, model.ErrAttachmentTooLarge)
+				return
+			}
+
+			s.error(w, r, http.StatusBadRequest, ErrMissingFile)
+			return
+		}
+		defer file.Close()
+
+		if header.Size > model.MaxAttachmentSize {
+			s.error(w, r, http.StatusRequestEntityTooLarge, model.ErrAttachmentTooLarge)
+			return
+		}
+
+		contentType, err := sniffContentType(file)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		key, err := attachmentKey(taskId)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		attachment := &model.Attachment{
+			TaskID:      taskId,
+			UserID:      userId,
+			Name:        header.Filename,
+			ContentType: contentType,
+			Size:        header.Size,
+			Key:         key,
+		}
+
+		if err := attachment.Validate(); err != nil {
+			s.error(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		if err := s.blobs.Put(key, file, header.Size, contentType); err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		if err := s.store.Attachment().Create(attachment); err != nil {
+			if err := s.blobs.Delete(key); err != nil {
+				s.logger.Errorf("deleting blob %s: %v", key, err)
+			}
+
+			s.attachmentError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusCreated, attachment)
+	}
+}
+
+// handleAttachmentGet downloads the file of an attachment
+func (s *server) handleAttachmentGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		taskId, err := taskIdFromRequest(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		attachmentId, err := idFromRequest(r, "attachment_id", store.ErrInvalidAttachmentId)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		attachment, err := s.store.Attachment().GetById(userId, taskId, attachmentId)
+		if err != nil {
+			s.attachmentError(w, r, err)
+			return
+		}
+
+		content, err := s.blobs.Get(attachment.Key)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		defer content.Close()
+
+		w.Header().Set("Content-Type", attachment.ContentType)
+		w.Header().Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+			"filename": attachment.Name,
+		}))
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusOK)
+		if _, err := io.Copy(w, content); err != nil {
+			s.logger.Errorf("sending attachment %d: %v", attachment.ID, err)
+		}
+	}
+}
+
+func (s *server) handleAttachmentDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		taskId, err := taskIdFromRequest(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		attachmentId, err := idFromRequest(r, "attachment_id", store.ErrInvalidAttachmentId)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		attachment, err := s.store.Attachment().GetById(userId, taskId, attachmentId)
+		if err != nil {
+			s.attachmentError(w, r, err)
+			return
+		}
+
+		if err := s.store.Attachment().Delete(userId, taskId, attachmentId); err != nil {
+			s.attachmentError(w, r, err)
+			return
+		}
+
+		// The record is gone already, a blob which failed to be deleted is
+		// only wasted space
+		if err := s.blobs.Delete(attachment.Key); err != nil {
+			s.logger.Errorf("deleting blob %s: %v", attachment.Key, err)
+		}
+
+		s.respond(w, r, http.StatusOK, map[string]string{"info": "attachment deleted"})
+	}
+}
+
+func (s *server) attachmentError(w http.ResponseWriter, r *http.Request, err error) {
+	switch err {
+	case store.ErrInvalidTaskId, store.ErrInvalidAttachmentId:
+		s.error(w, r, http.StatusNotFound, err)
+	case store.ErrReadOnly:
+		s.error(w, r, http.StatusForbidden, err)
+	default:
+		s.error(w, r, http.StatusInternalServerError, err)
+	}
+}
+
+// cleanupAttachments deletes the files of attachments left behind by purged
+// tasks. Attachments whose file can't be deleted are kept for the next run.
+func (s *server) cleanupAttachments() {
+	attachments, err := s.store.Attachment().Orphans()
+	if err != nil {
+		s.logger.Errorf("listing orphaned attachments: %v", err)
+		return
+	}
+
+	for _, a := range attachments {
+		if err := s.blobs.Delete(a.Key); err != nil {
+			s.logger.Errorf("deleting blob %s: %v", a.Key, err)
+			continue
+		}
+
+		if err := s.store.Attachment().DeleteOrphan(a.ID); err != nil {
+			s.logger.Errorf("deleting attachment %d: %v", a.ID, err)
+		}
+	}
+}
+
+// sniffContentType detects the content type of the file from its first bytes
+// and rewinds it. Parameters like the charset of text are dropped.
+func sniffContentType(file io.ReadSeeker) (string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	contentType, _, err := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	if err != nil {
+		return "", err
+	}
+
+	return contentType, nil
+}
+
+// attachmentKey generates an unguessable blob key for a file of the task
+func attachmentKey(taskId int) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("tasks/%d/%s", taskId, hex.EncodeToString(b)), nil
+}
+
+func (s *server) handleTaskShareGetAll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		taskId, err := taskIdFromRequest(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		shares, err := s.store.Share().GetByTask(userId, taskId)
+		if err != nil {
+			if err == store.ErrInvalidTaskId {
+				s.error(w, r, http.StatusNotFound, err)
+				return
+			}
+
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, shares)
+	}
+}
+
+func (s *server) handleProjectShareGetAll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		projectId, err := idFromRequest(r, "id", store.ErrInvalidProjectId)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		shares, err := s.store.Share().GetByProject(userId, projectId)
+		if err != nil {
+			if err == store.ErrInvalidProjectId {
+				s.error(w, r, http.StatusNotFound, err)
+				return
+			}
+
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, shares)
+	}
+}
+
+func (s *server) handleTaskShareCreate() http.HandlerFunc {
+	return s.handleShareCreate(func(r *http.Request, share *model.Share) error {
+		taskId, err := taskIdFromRequest(r)
+		share.TaskID = &taskId
+		return err
+	})
+}
+
+func (s *server) handleProjectShareCreate() http.HandlerFunc {
+	return s.handleShareCreate(func(r *http.Request, share *model.Share) error {
+		projectId, err := idFromRequest(r, "id", store.ErrInvalidProjectId)
+		share.ProjectID = &projectId
+		return err
+	})
+}
+
+// handleShareCreate shares the task or the project target takes from the
+// request with the user having the given email. It answers the same whether
+// or not the email belongs to a user, so that it can't be used to find out
+// who has an account.
+func (s *server) handleShareCreate(target func(*http.Request, *model.Share) error) http.HandlerFunc {
+	type request struct {
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		share := &model.Share{OwnerID: r.Context().Value(ctxKeyUser).(int)}
+		if err := target(r, share); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		// Everything but the user is checked first
+		share.Role = req.Role
+		if err := share.ValidateRole(); err != nil {
+			s.error(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		if err := s.checkShareTarget(share); err != nil {
+			if err == store.ErrInvalidTaskId || err == store.ErrInvalidProjectId {
+				s.error(w, r, http.StatusNotFound, err)
+				return
+			}
+
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		info := map[string]string{"info": "if the email belongs to an account, it was shared with it"}
+		user, err := s.store.User().FindByEmail(strings.ToLower(req.Email))
+		if err != nil {
+			s.respond(w, r, http.StatusAccepted, info)
+			return
+		}
+
+		share.UserID, share.Email = user.ID, user.Email
+		if err := s.store.Share().Create(share); err != nil {
+			if err == store.ErrShareWithOwner {
+				s.error(w, r, http.StatusUnprocessableEntity, err)
+				return
+			}
+
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusAccepted, info)
+	}
+}
+
+// checkShareTarget makes sure the shared task or project belongs to the owner
+func (s *server) checkShareTarget(share *model.Share) error {
+	var err error
+	if share.TaskID != nil {
+		_, err = s.store.Share().GetByTask(share.OwnerID, *share.TaskID)
+	} else {
+		_, err = s.store.Share().GetByProject(share.OwnerID, *share.ProjectID)
+	}
+
+	return err
+}
+
+// handleSharedGet lists the tasks and projects other users shared with the user
+func (s *server) handleSharedGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		shares, err := s.store.Share().SharedWith(userId)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, shares)
+	}
+}
+
+// handleShareDelete revokes a share, the user it is shared with can leave it the same way
+func (s *server) handleShareDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		shareId, err := idFromRequest(r, "id", store.ErrInvalidShareId)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := s.store.Share().Delete(userId, shareId); err != nil {
+			if err == store.ErrInvalidShareId {
+				s.error(w, r, http.StatusNotFound, err)
+				return
+			}
+
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, map[string]string{"info": "share revoked"})
+	}
+}
+
+// authOrgMW resolves the organization of the request path, only its members
+// get through with the organization and their role in the context
+func (s *server) authOrgMW(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		orgId, err := idFromRequest(r, "org_id", store.ErrInvalidOrganizationId)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		org, err := s.store.Organization().GetById(userId, orgId)
+		if err != nil {
+			if err == store.ErrInvalidOrganizationId {
+				s.error(w, r, http.StatusNotFound, err)
+				return
+			}
+
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKeyOrganization, org)))
+	})
+}
+
+// orgRole lets through the members of the current organization whose role
+// has the permissions of the required one
+func (s *server) orgRole(required string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !r.Context().Value(ctxKeyOrganization).(*model.Organization).Allows(required) {
+			s.error(w, r, http.StatusForbidden, ErrRoleForbidden)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func (s *server) handleOrganizationCreate() http.HandlerFunc {
+	type request struct {
+		Name string `json:"name"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		org := &model.Organization{Name: req.Name}
+		if err := s.store.Organization().Create(org, r.Context().Value(ctxKeyUser).(int)); err != nil {
+			s.error(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusCreated, org)
+	}
+}
+
+func (s *server) handleOrganizationGetAll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		orgs, err := s.store.Organization().GetAll(r.Context().Value(ctxKeyUser).(int))
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, orgs)
+	}
+}
+
+func (s *server) handleOrganizationGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.respond(w, r, http.StatusOK, r.Context().Value(ctxKeyOrganization))
+	}
+}
+
+func (s *server) handleOrganizationUpdate() http.HandlerFunc {
+	type request struct {
+		Name string `json:"name"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		org := *r.Context().Value(ctxKeyOrganization).(*model.Organization)
+		org.Name = req.Name
+		if err := s.store.Organization().Update(&org); err != nil {
+			s.error(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, &org)
+	}
+}
+
+func (s *server) handleOrganizationDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		org := r.Context().Value(ctxKeyOrganization).(*model.Organization)
+		if err := s.store.Organization().Delete(userId, org.ID); err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, map[string]string{"info": "organization deleted"})
+	}
+}
+
+func (s *server) handleMemberGetAll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		org := r.Context().Value(ctxKeyOrganization).(*model.Organization)
+		members, err := s.store.Organization().GetMembers(org.ID)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, members)
+	}
+}
+
+func (s *server) handleMemberUpdate() http.HandlerFunc {
+	type request struct {
+		Role string `json:"role"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		org := r.Context().Value(ctxKeyOrganization).(*model.Organization)
+		memberId, err := idFromRequest(r, "user_id", store.ErrInvalidMember)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		member := &model.Member{OrganizationID: org.ID, UserID: memberId, Role: req.Role}
+		if err := s.store.Organization().SetRole(member); err != nil {
+			s.memberError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, member)
+	}
+}
+
+// handleMemberDelete removes a member from the organization. Everyone can
+// leave it, admins remove members and only the owner removes admins.
+func (s *server) handleMemberDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		org := r.Context().Value(ctxKeyOrganization).(*model.Organization)
+		memberId, err := idFromRequest(r, "user_id", store.ErrInvalidMember)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if memberId != userId {
+			members, err := s.store.Organization().GetMembers(org.ID)
+			if err != nil {
+				s.error(w, r, http.StatusInternalServerError, err)
+				return
+			}
+
+			required := model.OrgRoleAdmin
+			for _, m := range members {
+				if m.UserID == memberId && m.Role == model.OrgRoleAdmin {
+					required = model.OrgRoleOwner
+				}
+			}
+
+			if !org.Allows(required) {
+				s.error(w, r, http.StatusForbidden, ErrRoleForbidden)
+				return
+			}
+		}
+
+		if err := s.store.Organization().RemoveMember(userId, org.ID, memberId); err != nil {
+			s.memberError(w, r, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, map[string]string{"info": "member removed"})
+	}
+}
+
+// memberError responds with the status matching an error of a membership change
+func (s *server) memberError(w http.ResponseWriter, r *http.Request, err error) {
+	switch err {
+	case store.ErrInvalidMember:
+		s.error(w, r, http.StatusNotFound, err)
+	case store.ErrOrganizationOwner:
+		s.error(w, r, http.StatusForbidden, err)
+	default:
+		s.error(w, r, http.StatusUnprocessableEntity, err)
+	}
+}
+
+func (s *server) handleInvitationGetAll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		org := r.Context().Value(ctxKeyOrganization).(*model.Organization)
+		invitations, err := s.store.Organization().GetInvitations(org.ID)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, invitations)
+	}
+}
+
+func (s *server) handleInvitationCreate() http.HandlerFunc {
+	type request struct {
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		org := r.Context().Value(ctxKeyOrganization).(*model.Organization)
+		invitation := &model.Invitation{
+			OrganizationID: org.ID,
+			Email:          strings.ToLower(req.Email),
+			Role:           req.Role,
+			InvitedBy:      r.Context().Value(ctxKeyUser).(int),
+		}
+
+		if err := s.store.Organization().Invite(invitation); err != nil {
+			if err == store.ErrAlreadyMember {
+				s.error(w, r, http.StatusConflict, err)
+				return
+			}
+
+			s.error(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		// Only the invitee gets the token
+		if err := s.mailer.Send(&mailer.Message{
+			To:      invitation.Email,
+			Subject: fmt.Sprintf("Join %s", org.Name),
+			Body: fmt.Sprintf(
+				"You are invited to the organization %s. Sign in with this email to accept "+
+					"the invitation within %d days:\n\n%s",
+				org.Name, int(model.InvitationTTL.Hours()/24), s.emailLink("/accept-invitation", invitation.Token),
+			),
+		}); err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusCreated, invitation)
+	}
+}
+
+func (s *server) handleInvitationDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		org := r.Context().Value(ctxKeyOrganization).(*model.Organization)
+		invitationId, err := idFromRequest(r, "invitation_id", store.ErrInvalidInvitation)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := s.store.Organization().DeleteInvitation(org.ID, invitationId); err != nil {
+			if err == store.ErrInvalidInvitation {
+				s.error(w, r, http.StatusNotFound, err)
+				return
+			}
+
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, map[string]string{"info": "invitation revoked"})
+	}
+}
+
+func (s *server) handleInvitationAccept() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := r.Context().Value(ctxKeyAccount).(*model.User)
+		org, err := s.store.Organization().Accept(user, mux.Vars(r)["token"])
+		if err != nil {
+			switch err {
+			case store.ErrInvalidInvitation:
+				s.error(w, r, http.StatusNotFound, err)
+			case store.ErrAlreadyMember:
+				s.error(w, r, http.StatusConflict, err)
+			case store.ErrUnverifiedInvitee:
+				s.error(w, r, http.StatusForbidden, err)
+			default:
+				s.error(w, r, http.StatusInternalServerError, err)
+			}
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, org)
+	}
+}
+
+func (s *server) handleOrganizationProjectGetAll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		org := r.Context().Value(ctxKeyOrganization).(*model.Organization)
+		projects, err := s.store.Project().GetByOrganization(org.ID)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, projects)
+	}
+}
+
+func (s *server) handleOrganizationProjectCreate() http.HandlerFunc {
+	type request struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		project := &model.Project{
+			UserID:         r.Context().Value(ctxKeyUser).(int),
+			Name:           req.Name,
+			Description:    req.Description,
+			OrganizationID: &r.Context().Value(ctxKeyOrganization).(*model.Organization).ID,
+		}
+
+		if err := s.store.Project().Create(project); err != nil {
+			s.error(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusCreated, project)
+	}
+}
+
+func (s *server) handleTimerStart() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		taskId, err := taskIdFromRequest(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		entry, err := s.store.TimeEntry().Start(userId, taskId)
+		if err != nil {
+			switch err {
+			case store.ErrInvalidTaskId:
+				s.error(w, r, http.StatusNotFound, err)
+			case store.ErrReadOnly:
+				s.error(w, r, http.StatusForbidden, err)
+			case store.ErrTimerRunning:
+				s.error(w, r, http.StatusConflict, err)
+			default:
+				s.error(w, r, http.StatusInternalServerError, err)
+			}
+			return
+		}
+
+		s.respond(w, r, http.StatusCreated, entry)
+	}
+}
+
+func (s *server) handleTimerStop() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		taskId, err := taskIdFromRequest(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		entry, err := s.store.TimeEntry().Stop(userId, taskId)
+		if err != nil {
+			if err == store.ErrNoTimerRunning {
+				s.error(w, r, http.StatusConflict, err)
+				return
+			}
+
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, entry)
+	}
+}
+
+func (s *server) handleTimerGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		entry, err := s.store.TimeEntry().Running(userId)
+		if err != nil {
+			if err == store.ErrNoRecordsInTable {
+				s.error(w, r, http.StatusNotFound, err)
+				return
+			}
+
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, entry)
+	}
+}
+
+func (s *server) handleTaskTimeGet() http.HandlerFunc {
+	type response struct {
+		Entries []*model.TimeEntry `json:"entries"`
+		Total   int64              `json:"total"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		taskId, err := taskIdFromRequest(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		entries, err := s.store.TimeEntry().GetByTask(userId, taskId)
+		if err != nil {
+			if err == store.ErrInvalidTaskId {
+				s.error(w, r, http.StatusNotFound, err)
+				return
+			}
+
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		resp := &response{Entries: entries}
+		for _, e := range entries {
+			resp.Total += e.Duration
+		}
+
+		s.respond(w, r, http.StatusOK, resp)
+	}
+}
+
+func (s *server) handleTimeEntryCreate() http.HandlerFunc {
+	type request struct {
+		StartedAt time.Time  `json:"started_at"`
+		StoppedAt *time.Time `json:"stopped_at"`
+		Note      string     `json:"note"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		taskId, err := taskIdFromRequest(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		entry := &model.TimeEntry{
+			UserID:    r.Context().Value(ctxKeyUser).(int),
+			TaskID:    taskId,
+			StartedAt: req.StartedAt,
+			StoppedAt: req.StoppedAt,
+			Note:      req.Note,
+		}
+
+		if err := s.store.TimeEntry().Create(entry); err != nil {
+			if err == store.ErrInvalidTaskId {
+				s.error(w, r, http.StatusNotFound, err)
+				return
+			}
+
+			if err == store.ErrReadOnly {
+				s.error(w, r, http.StatusForbidden, err)
+				return
+			}
+
+			s.error(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusCreated, entry)
+	}
+}
+
+func (s *server) handleTimeEntryDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		entryId, err := idFromRequest(r, "id", ErrInvalidEntryId)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := s.store.TimeEntry().Delete(userId, entryId); err != nil {
+			if err == store.ErrNoRecordsInTable {
+				s.error(w, r, http.StatusNotFound, err)
+				return
+			}
+
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, map[string]string{"info": "time entry deleted"})
+	}
+}
+
+// handleTimeReport sums up the time logged over a period, the last week by default
+func (s *server) handleTimeReport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		values := r.URL.Query()
+		now := time.Now()
+
+		to, err := parseTime(values.Get("to"))
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+		if to == nil {
+			to = &now
+		}
+
+		from, err := parseTime(values.Get("from"))
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+		if from == nil {
+			start := to.Add(-defaultReportPeriod)
+			from = &start
+		}
+
+		if !from.Before(*to) {
+			s.error(w, r, http.StatusBadRequest, ErrInvalidPeriod)
+			return
+		}
+
+		entries, err := s.store.TimeEntry().Find(userId, *from, *to)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, model.NewTimeReport(entries, *from, *to, now))
+	}
+}
+
+// handleBurndownReport reports the remaining and completed estimate of the
+// tasks of a project, of a tag or of all tasks day by day
+func (s *server) handleBurndownReport() http.HandlerFunc {
+	type response struct {
+		ProjectID *int                   `json:"project_id,omitempty"`
+		Tag       string                 `json:"tag,omitempty"`
+		Points    []*model.BurndownPoint `json:"points"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		values := r.URL.Query()
+		now := time.Now()
+
+		to, err := parseTime(values.Get("to"))
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+		if to == nil {
+			to = &now
+		}
+
+		from, err := parseTime(values.Get("from"))
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+		if from == nil {
+			start := to.AddDate(0, 0, 1-defaultBurndownDays)
+			from = &start
+		}
+
+		if to.Before(*from) {
+			s.error(w, r, http.StatusBadRequest, ErrInvalidPeriod)
+			return
+		}
+
+		if to.Sub(*from) >= maxBurndownDays*24*time.Hour {
+			s.error(w, r, http.StatusBadRequest, ErrPeriodTooLong)
+			return
+		}
+
+		scope := &store.ReportScope{}
+		if v := values.Get("project_id"); v != "" {
+			projectId, err := strconv.Atoi(v)
+			if err != nil {
+				s.error(w, r, http.StatusBadRequest, store.ErrInvalidProjectId)
+				return
+			}
+
+			if _, err := s.store.Project().GetById(userId, projectId); err != nil {
+				if err == store.ErrInvalidProjectId {
+					s.error(w, r, http.StatusNotFound, err)
+					return
+				}
+
+				s.error(w, r, http.StatusInternalServerError, err)
+				return
+			}
+
+			scope.ProjectID = &projectId
+		}
+
+		tag := &model.Tag{Name: values.Get("tag")}
+		tag.Normalize()
+		scope.Tag = tag.Name
+
+		tasks, events, err := s.store.Task().ReportHistory(userId, scope)
+		if err != nil {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, &response{
+			ProjectID: scope.ProjectID,
+			Tag:       scope.Tag,
+			Points:    model.Burndown(tasks, events, *from, *to, now),
+		})
+	}
+}
+
+func (s *server) handleTagCreate() http.HandlerFunc {
+	type request struct {
+		Name string `json:"name"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		tag := &model.Tag{
+			UserID: r.Context().Value(ctxKeyUser).(int),
+			Name:   req.Name,
+		}
+
+		if err := s.store.Tag().Create(tag); err != nil {
+			if err == store.ErrTagExists {
+				s.error(w, r, http.StatusConflict, err)
+				return
+			}
+
+			s.error(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusCreated, tag)
+	}
+}
+
+func (s *server) handleTagGetAll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		tags, err := s.store.Tag().GetAll(userId)
+		if err != nil {
+			if err == store.ErrNoRecordsInTable {
+				s.error(w, r, http.StatusNotFound, err)
+				return
+			}
+
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, tags)
+	}
+}
+
+func (s *server) handleTagRename() http.HandlerFunc {
+	type request struct {
+		Name string `json:"name"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		tagId, err := idFromRequest(r, "id", store.ErrInvalidTagId)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		tag, err := s.store.Tag().Rename(userId, tagId, req.Name)
+		if err != nil {
+			switch err {
+			case store.ErrInvalidTagId:
+				s.error(w, r, http.StatusNotFound, err)
+			case store.ErrTagExists:
+				s.error(w, r, http.StatusConflict, err)
+			default:
+				s.error(w, r, http.StatusUnprocessableEntity, err)
+			}
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, tag)
+	}
+}
+
+func (s *server) handleTagDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		tagId, err := idFromRequest(r, "id", store.ErrInvalidTagId)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := s.store.Tag().Delete(userId, tagId); err != nil {
+			if err == store.ErrInvalidTagId {
+				s.error(w, r, http.StatusNotFound, err)
+				return
+			}
+
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, map[string]string{
+			"info": "you've successfully deleted a tag",
+		})
+	}
+}
+
+func (s *server) handleProjectCreate() http.HandlerFunc {
+	type request struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		project := &model.Project{
+			UserID:      r.Context().Value(ctxKeyUser).(int),
+			Name:        req.Name,
+			Description: req.Description,
+		}
+
+		if err := s.store.Project().Create(project); err != nil {
+			s.error(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusCreated, project)
+	}
+}
+
+func (s *server) handleProjectGetAll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		projects, err := s.store.Project().GetAll(userId)
+		if err != nil {
+			if err == store.ErrNoRecordsInTable {
+				s.error(w, r, http.StatusNotFound, err)
+				return
+			}
+
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, projects)
+	}
+}
+
+func (s *server) handleProjectGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		projectId, err := idFromRequest(r, "id", store.ErrInvalidProjectId)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		project, err := s.store.Project().GetById(userId, projectId)
+		if err != nil {
+			s.error(w, r, http.StatusNotFound, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, project)
+	}
+}
+
+func (s *server) handleWorkflowGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		projectId, err := projectIdFromRequest(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		workflow, err := s.store.Workflow().Get(userId, projectId)
+		if err != nil {
+			if err == store.ErrInvalidProjectId {
+				s.error(w, r, http.StatusNotFound, err)
+				return
+			}
+
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, workflow)
+	}
+}
+
+func (s *server) handleWorkflowSave() http.HandlerFunc {
+	type request struct {
+		Statuses    []*model.Status     `json:"statuses"`
+		Transitions map[string][]string `json:"transitions"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		projectId, err := projectIdFromRequest(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		workflow := &model.Workflow{
+			UserID:      userId,
+			ProjectID:   projectId,
+			Statuses:    req.Statuses,
+			Transitions: req.Transitions,
+		}
+		if err := s.store.Workflow().Save(workflow); err != nil {
+			if err == store.ErrInvalidProjectId {
+				s.error(w, r, http.StatusNotFound, err)
+				return
+			}
+
+			s.error(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, workflow)
+	}
+}
+
+func (s *server) handleWorkflowDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		projectId, err := projectIdFromRequest(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		if err := s.store.Workflow().Delete(userId, projectId); err != nil {
+			if err == store.ErrNoRecordsInTable {
+				s.error(w, r, http.StatusNotFound, err)
+				return
+			}
+
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, map[string]string{
+			"info": "you've reset the workflow",
+		})
+	}
+}
+
+func (s *server) handleBoardGet() http.HandlerFunc {
+	type column struct {
+		Status string        `json:"status"`
+		Done   bool          `json:"done"`
+		Tasks  []*model.Task `json:"tasks"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		projectId, err := projectIdFromRequest(r)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		workflow, err := s.store.Workflow().Get(userId, projectId)
+		if err != nil {
+			if err == store.ErrInvalidProjectId {
+				s.error(w, r, http.StatusNotFound, err)
+				return
+			}
+
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		tasks, _, err := s.store.Task().Find(userId, &store.TaskQuery{ProjectID: projectId, Sort: store.SortRank})
+		if err != nil && err != store.ErrNoRecordsInTable {
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		columns := make([]*column, len(workflow.Statuses))
+		index := map[string]*column{}
+		for i, status := range workflow.Statuses {
+			columns[i] = &column{Status: status.Name, Done: status.Done, Tasks: []*model.Task{}}
+			index[status.Name] = columns[i]
+		}
+
+		// The board of the user leaves out tasks of projects with their own workflow
+		follows := map[int]bool{}
+		for _, task := range tasks {
+			if projectId == nil && task.ProjectID != nil {
+				ok, seen := follows[*task.ProjectID]
+				if !seen {
+					own, err := s.store.Workflow().Get(userId, task.ProjectID)
+					if err != nil {
+						s.error(w, r, http.StatusInternalServerError, err)
+						return
+					}
+
+					ok = own.ProjectID == nil
+					follows[*task.ProjectID] = ok
+				}
+
+				if !ok {
+					continue
+				}
+			}
+
+			c := index[workflow.StatusOf(task)]
+			c.Tasks = append(c.Tasks, task)
+		}
+
+		s.respond(w, r, http.StatusOK, map[string]interface{}{
+			"columns":     columns,
+			"transitions": workflow.Transitions,
+		})
+	}
+}
+
+func (s *server) handleProjectUpdate() http.HandlerFunc {
+	type request struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		projectId, err := idFromRequest(r, "id", store.ErrInvalidProjectId)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		req := &request{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		project, err := s.store.Project().GetById(userId, projectId)
+		if err != nil {
+			s.error(w, r, http.StatusNotFound, err)
+			return
+		}
+
+		updated := *project
+		if req.Name != nil {
+			updated.Name = *req.Name
+		}
+
+		if req.Description != nil {
+			updated.Description = *req.Description
+		}
+
+		if err := s.store.Project().Update(&updated); err != nil {
+			s.error(w, r, http.StatusUnprocessableEntity, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, &updated)
+	}
+}
+
+// handleProjectDelete deletes a project. Its tasks are left without a project
+// unless they are moved into another one with move_to or deleted with delete_tasks=true.
+func (s *server) handleProjectDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userId := r.Context().Value(ctxKeyUser).(int)
+		projectId, err := idFromRequest(r, "id", store.ErrInvalidProjectId)
+		if err != nil {
+			s.error(w, r, http.StatusBadRequest, err)
+			return
+		}
+
+		var deleteTasks bool
+		if v := r.URL.Query().Get("delete_tasks"); v != "" {
+			if deleteTasks, err = strconv.ParseBool(v); err != nil {
+				s.error(w, r, http.StatusBadRequest, err)
+				return
+			}
+		}
+
+		var target *int
+		if v := r.URL.Query().Get("move_to"); v != "" {
+			targetId, err := strconv.Atoi(v)
+			if err != nil || deleteTasks {
+				s.error(w, r, http.StatusBadRequest, ErrInvalidMoveTo)
+				return
+			}
+
+			target = &targetId
+		}
+
+		if deleteTasks {
+			err = s.store.Project().DeleteWithTasks(userId, projectId)
+		} else {
+			err = s.store.Project().Delete(userId, projectId, target)
+		}
+
+		if err != nil {
+			if err == store.ErrInvalidProjectId {
+				s.error(w, r, http.StatusNotFound, err)
+				return
+			}
+
+			s.error(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		s.respond(w, r, http.StatusOK, map[string]string{
+			"info": "you've successfully deleted a project",
+		})
+	}
+}
+
+// taskQueryFromRequest builds a task listing query from the URL query parameters
+func taskQueryFromRequest(r *http.Request) (*store.TaskQuery, error) {
+	values := r.URL.Query()
+	query := &store.TaskQuery{
+		Sort:   values.Get("sort"),
+		Limit:  defaultTaskLimit,
+		Cursor: values.Get("cursor"),
+	}
+
+	if v := values.Get("done"); v != "" {
+		done, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, err
+		}
+
+		query.Done = &done
+	}
+
+	if v := values.Get("overdue"); v != "" {
+		overdue, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, err
+		}
+
+		if overdue {
+			now := time.Now()
+			query.OverdueAt = &now
+		}
+	}
+
+	var err error
+	if query.DueAfter, err = parseTime(values.Get("due_after")); err != nil {
+		return nil, err
+	}
+
+	if query.DueBefore, err = parseTime(values.Get("due_before")); err != nil {
+		return nil, err
+	}
+
+	if v := values.Get("project_id"); v != "" {
+		projectId, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, store.ErrInvalidProjectId
+		}
+
+		query.ProjectID = &projectId
+	}
+
+	if v := values.Get("assignee"); v != "" {
+		assigneeId, err := strconv.Atoi(v)
+		if v == "me" {
+			assigneeId, err = r.Context().Value(ctxKeyUser).(int), nil
+		}
+		if err != nil {
+			return nil, ErrInvalidAssignee
+		}
+
+		query.AssigneeID = &assigneeId
+	}
+
+	for _, status := range values["status"] {
+		query.Statuses = append(query.Statuses, strings.ToLower(status))
+	}
+
+	seen := map[string]bool{}
+	for _, name := range values["tag"] {
+		tag := &model.Tag{Name: name}
+		tag.Normalize()
+		if tag.Name != "" && !seen[tag.Name] {
+			seen[tag.Name] = true
+			query.Tags = append(query.Tags, tag.Name)
+		}
+	}
+
+	for _, v := range values["priority"] {
+		priority := model.Priority(strings.ToLower(v))
+		if priority == "" || priority.Level() < 0 {
+			return nil, ErrInvalidPriority
+		}
+
+		query.Priorities = append(query.Priorities, priority)
+	}
+
+	if v := values.Get("filter"); v != "" {
+		expr, err := filter.Parse(v)
+		if err != nil {
+			return nil, err
+		}
+
+		query.Filter = expr
+	}
+
+	switch values.Get("tag_mode") {
+	case "", "any":
+	case "all":
+		query.AllTags = true
+	default:
+		return nil, ErrInvalidTagMode
+	}
+
+	switch values.Get("order") {
+	case "", "asc":
+	case "desc":
+		query.Desc = true
+	default:
+		return nil, ErrInvalidOrder
+	}
+
+	if v := values.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxTaskLimit {
+			return nil, ErrInvalidLimit
+		}
+
+		query.Limit = limit
+	}
+
+	return query, nil
+}
+
+// taskIdFromRequest extracts the task id from the request path
+func taskIdFromRequest(r *http.Request) (int, error) {
+	return idFromRequest(r, "id", store.ErrInvalidTaskId)
+}
+
+// idFromRequest extracts a numeric path variable, a malformed one is reported as errInvalid
+func idFromRequest(r *http.Request, name string, errInvalid error) (int, error) {
+	id, err := strconv.Atoi(mux.Vars(r)[name])
+	if err != nil {
+		return 0, errInvalid
+	}
+
+	return id, nil
+}
+
+// projectIdFromRequest extracts the optional project id from the request
+// path, nil stands for the routes of the user outside of projects
+func projectIdFromRequest(r *http.Request) (*int, error) {
+	if _, ok := mux.Vars(r)["id"]; !ok {
+		return nil, nil
+	}
+
+	projectId, err := idFromRequest(r, "id", store.ErrInvalidProjectId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &projectId, nil
+}
+
+// parseTime parses an optional query parameter holding either
+// an RFC 3339 timestamp or a plain date. An empty value yields nil.
+func parseTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
+	}
+
+	return nil, ErrInvalidDate
+}
+
 // error wrapper for respond function
 func (s *server) error(w http.ResponseWriter, r *http.Request, code int, err error) {
 	s.respond(w, r, code, map[string]string{"error": err.Error()})
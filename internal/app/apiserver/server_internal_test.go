@@ -2,21 +2,27 @@ package apiserver
 
 import (
 	"bytes"
-	"context"
 	"encoding/json"
 	"fmt"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"regexp"
 	"testing"
+	"time"
 
 	"github.com/gorilla/sessions"
+	"github.com/pyuldashev912/todoapp/internal/app/blobstore"
+	"github.com/pyuldashev912/todoapp/internal/app/blobstore/filestore"
+	"github.com/pyuldashev912/todoapp/internal/app/mailer/memmailer"
 	"github.com/pyuldashev912/todoapp/internal/app/model"
 	"github.com/pyuldashev912/todoapp/internal/app/store/teststore"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestServer_handleUserCreate(t *testing.T) {
-	s := newServer(teststore.New(), nil)
+	s := newServer(teststore.New(), nil, nil, memmailer.New())
 
 	testCases := []struct {
 		name         string
@@ -65,7 +71,7 @@ func TestServer_handleUserLogin(t *testing.T) {
 	user := model.TestUser(t)
 	store.User().Create(user)
 
-	srv := newServer(store, sessions.NewCookieStore([]byte("secret")))
+	srv := newServer(store, sessions.NewCookieStore([]byte("secret")), nil, nil)
 	testCases := []struct {
 		name         string
 		payload      interface{}
@@ -117,6 +123,12 @@ func TestServer_authUserMW(t *testing.T) {
 	store := teststore.New()
 	u := model.TestUser(t)
 	store.User().Create(u)
+	reset := model.TestUser(t)
+	reset.Email = "reset@user.com"
+	store.User().Create(reset)
+	p := &model.PasswordReset{UserID: reset.ID}
+	store.User().CreatePasswordReset(p)
+	store.User().ResetPassword(p.Token, "NewPassword")
 
 	testCases := []struct {
 		name         string
@@ -130,6 +142,36 @@ func TestServer_authUserMW(t *testing.T) {
 			},
 			expectedCode: http.StatusOK,
 		},
+		{
+			name: "current session version",
+			cookieValue: map[interface{}]interface{}{
+				"user_id":         u.ID,
+				"session_version": 0,
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name: "session before a password reset",
+			cookieValue: map[interface{}]interface{}{
+				"user_id":         u.ID,
+				"session_version": -1,
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name: "session without a version before a password reset",
+			cookieValue: map[interface{}]interface{}{
+				"user_id": reset.ID,
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name: "unknown user",
+			cookieValue: map[interface{}]interface{}{
+				"user_id": 100,
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
 		{
 			name:         "not authenticated",
 			cookieValue:  nil,
@@ -138,7 +180,7 @@ func TestServer_authUserMW(t *testing.T) {
 	}
 
 	cookieStore, secureCookie := TestSession(t)
-	s := newServer(store, cookieStore)
+	s := newServer(store, cookieStore, nil, nil)
 	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
@@ -173,7 +215,7 @@ func TestServer_handleUserLogout(t *testing.T) {
 	}
 
 	cookieStore, secureCookie := TestSession(t)
-	s := newServer(store, cookieStore)
+	s := newServer(store, cookieStore, nil, nil)
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -210,12 +252,12 @@ func TestServer_handleWhoAmI(t *testing.T) {
 		},
 	}
 
-	srv := newServer(store, nil)
+	srv := testServer(t, store)
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			rec := httptest.NewRecorder()
 			req, _ := http.NewRequest(http.MethodGet, "/users/me", nil)
-			req = req.WithContext(context.WithValue(req.Context(), ctxKeyUser, tc.user_id))
+			authenticate(t, req, tc.user_id)
 			srv.ServeHTTP(rec, req)
 			assert.Equal(t, tc.expectedCode, rec.Result().StatusCode)
 		})
@@ -224,8 +266,9 @@ func TestServer_handleWhoAmI(t *testing.T) {
 
 func TestServer_handleTaskCreate(t *testing.T) {
 	store := teststore.New()
+	store.User().Create(model.TestUser(t))
 	task := model.TestTask(t)
-	srv := newServer(store, nil)
+	srv := testServer(t, store)
 
 	testCases := []struct {
 		name         string
@@ -268,7 +311,7 @@ func TestServer_handleTaskCreate(t *testing.T) {
 			buf := &bytes.Buffer{}
 			json.NewEncoder(buf).Encode(tc.payload)
 			req, _ := http.NewRequest(http.MethodPost, "/users/tasks", buf)
-			req = req.WithContext(context.WithValue(req.Context(), ctxKeyUser, tc.user_id))
+			authenticate(t, req, tc.user_id)
 			srv.ServeHTTP(rec, req)
 			assert.Equal(t, tc.expectedCode, rec.Result().StatusCode)
 		})
@@ -277,9 +320,10 @@ func TestServer_handleTaskCreate(t *testing.T) {
 
 func TestServer_handleTaskDelete(t *testing.T) {
 	store := teststore.New()
+	store.User().Create(model.TestUser(t))
 	task := model.TestTask(t)
 	store.Task().Create(task)
-	srv := newServer(store, nil)
+	srv := testServer(t, store)
 
 	testCases := []struct {
 		name         string
@@ -311,7 +355,7 @@ func TestServer_handleTaskDelete(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			rec := httptest.NewRecorder()
 			req, _ := http.NewRequest(http.MethodDelete, tc.queryString, nil)
-			req = req.WithContext(context.WithValue(req.Context(), ctxKeyUser, tc.user_id))
+			authenticate(t, req, tc.user_id)
 			srv.ServeHTTP(rec, req)
 			assert.Equal(t, tc.expectedCode, rec.Result().StatusCode)
 		})
@@ -320,9 +364,10 @@ func TestServer_handleTaskDelete(t *testing.T) {
 
 func TestServer_handleTaskDone(t *testing.T) {
 	store := teststore.New()
+	store.User().Create(model.TestUser(t))
 	task := model.TestTask(t)
 	store.Task().Create(task)
-	srv := newServer(store, nil)
+	srv := testServer(t, store)
 
 	testCases := []struct {
 		name         string
@@ -354,7 +399,7 @@ func TestServer_handleTaskDone(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			rec := httptest.NewRecorder()
 			req, _ := http.NewRequest(http.MethodPatch, tc.queryString, nil)
-			req = req.WithContext(context.WithValue(req.Context(), ctxKeyUser, tc.user_id))
+			authenticate(t, req, tc.user_id)
 			srv.ServeHTTP(rec, req)
 			assert.Equal(t, tc.expectedCode, rec.Result().StatusCode)
 		})
@@ -363,9 +408,10 @@ func TestServer_handleTaskDone(t *testing.T) {
 
 func TestServer_handleTaskGet(t *testing.T) {
 	store := teststore.New()
+	store.User().Create(model.TestUser(t))
 	task := model.TestTask(t)
 	store.Task().Create(task)
-	srv := newServer(store, nil)
+	srv := testServer(t, store)
 
 	testCases := []struct {
 		name         string
@@ -397,18 +443,19 @@ func TestServer_handleTaskGet(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			rec := httptest.NewRecorder()
 			req, _ := http.NewRequest(http.MethodGet, tc.queryString, nil)
-			req = req.WithContext(context.WithValue(req.Context(), ctxKeyUser, tc.userId))
+			authenticate(t, req, tc.userId)
 			srv.ServeHTTP(rec, req)
 			assert.Equal(t, tc.expectedCode, rec.Result().StatusCode)
 		})
 	}
 }
 
-func TestServer_handleTaskGetDone(t *testing.T) {
+func TestServer_handleTaskGetAllDone(t *testing.T) {
 	store := teststore.New()
+	store.User().Create(model.TestUser(t))
 	task := model.TestTask(t)
 	store.Task().Create(task)
-	srv := newServer(store, nil)
+	srv := testServer(t, store)
 
 	testCases := []struct {
 		name         string
@@ -423,10 +470,10 @@ func TestServer_handleTaskGetDone(t *testing.T) {
 			expectedCode: http.StatusOK,
 		},
 		{
-			name:         "not found task",
+			name:         "no matching tasks",
 			userId:       task.UserID,
 			queryString:  "/users/tasks?done=true",
-			expectedCode: http.StatusNotFound,
+			expectedCode: http.StatusOK,
 		},
 		{
 			name:         "invalid argument",
@@ -440,7 +487,7 @@ func TestServer_handleTaskGetDone(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			rec := httptest.NewRecorder()
 			req, _ := http.NewRequest(http.MethodGet, tc.queryString, nil)
-			req = req.WithContext(context.WithValue(req.Context(), ctxKeyUser, tc.userId))
+			authenticate(t, req, tc.userId)
 			srv.ServeHTTP(rec, req)
 			assert.Equal(t, tc.expectedCode, rec.Result().StatusCode)
 		})
@@ -449,8 +496,9 @@ func TestServer_handleTaskGetDone(t *testing.T) {
 
 func TestServer_handleTaskGetAll(t *testing.T) {
 	store := teststore.New()
+	store.User().Create(model.TestUser(t))
 	task := model.TestTask(t)
-	srv := newServer(store, nil)
+	srv := testServer(t, store)
 
 	testCases := []struct {
 		name         string
@@ -461,7 +509,7 @@ func TestServer_handleTaskGetAll(t *testing.T) {
 		{
 			name:         "no tasks in storage",
 			userId:       task.UserID,
-			expectedCode: http.StatusNotFound,
+			expectedCode: http.StatusOK,
 		},
 		{
 			name:         "valid",
@@ -478,9 +526,2855 @@ func TestServer_handleTaskGetAll(t *testing.T) {
 				store.Task().Create(task)
 			}
 			req, _ := http.NewRequest(http.MethodGet, "/users/tasks", nil)
-			req = req.WithContext(context.WithValue(req.Context(), ctxKeyUser, tc.userId))
+			authenticate(t, req, tc.userId)
+			srv.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Result().StatusCode)
+			if !tc.create {
+				assert.JSONEq(t, `{"tasks": []}`, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestServer_handleTaskGetAllDue(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	task := model.TestTask(t)
+	dueAt := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	task.DueAt = &dueAt
+	store.Task().Create(task)
+	srv := testServer(t, store)
+
+	testCases := []struct {
+		name         string
+		userId       interface{}
+		queryString  string
+		expectedCode int
+	}{
+		{
+			name:         "due before",
+			userId:       task.UserID,
+			queryString:  "/users/tasks?due_before=2026-04-01",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "due range",
+			userId:       task.UserID,
+			queryString:  "/users/tasks?due_after=2026-03-01T00:00:00Z&due_before=2026-04-01",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "no matching tasks",
+			userId:       task.UserID,
+			queryString:  "/users/tasks?due_after=2026-04-01",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "invalid date",
+			userId:       task.UserID,
+			queryString:  "/users/tasks?due_before=tomorrow",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, tc.queryString, nil)
+			authenticate(t, req, tc.userId)
+			srv.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Result().StatusCode)
+		})
+	}
+}
+
+func TestServer_handleTaskGetAllOverdue(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	task := model.TestTask(t)
+	srv := testServer(t, store)
+
+	testCases := []struct {
+		name         string
+		userId       interface{}
+		queryString  string
+		dueAt        time.Time
+		expectedCode int
+	}{
+		{
+			name:         "no overdue tasks",
+			userId:       task.UserID,
+			queryString:  "/users/tasks?overdue=true",
+			dueAt:        time.Now().Add(time.Hour),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "overdue task",
+			userId:       task.UserID,
+			queryString:  "/users/tasks?overdue=true",
+			dueAt:        time.Now().Add(-time.Hour),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "invalid argument",
+			userId:       task.UserID,
+			queryString:  "/users/tasks?overdue=maybe",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if !tc.dueAt.IsZero() {
+				task := model.TestTask(t)
+				task.DueAt = &tc.dueAt
+				store.Task().Create(task)
+			}
+
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, tc.queryString, nil)
+			authenticate(t, req, tc.userId)
+			srv.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Result().StatusCode)
+		})
+	}
+}
+
+// testServer returns a server whose session store accepts cookies made by authenticate
+func testServer(t *testing.T, st *teststore.Store) *server {
+	t.Helper()
+
+	cookieStore, _ := TestSession(t)
+	return newServer(st, cookieStore, filestore.New(t.TempDir()), memmailer.New())
+}
+
+// authenticate attaches a session cookie that logs the request in as userId
+func authenticate(t *testing.T, req *http.Request, userId interface{}) {
+	t.Helper()
+
+	_, secureCookie := TestSession(t)
+	cookieStr, _ := secureCookie.Encode(sessionName, map[interface{}]interface{}{
+		"user_id": userId,
+	})
+	req.Header.Set("Cookie", fmt.Sprintf("%s=%s", sessionName, cookieStr))
+}
+
+func TestServer_handleTaskPatch(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	task := model.TestTask(t)
+	store.Task().Create(task)
+	srv := testServer(t, store)
+
+	testCases := []struct {
+		name         string
+		userId       interface{}
+		queryString  string
+		payload      string
+		expectedCode int
+	}{
+		{
+			name:         "empty body completes",
+			userId:       task.UserID,
+			queryString:  "/users/tasks/1",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "rename and uncomplete",
+			userId:       task.UserID,
+			queryString:  "/users/tasks/1",
+			payload:      `{"title": "Renamed", "done": false}`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "invalid title",
+			userId:       task.UserID,
+			queryString:  "/users/tasks/1",
+			payload:      `{"title": null}`,
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "unknown field",
+			userId:       task.UserID,
+			queryString:  "/users/tasks/1",
+			payload:      `{"user_id": 2}`,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "not existing id",
+			userId:       task.UserID,
+			queryString:  "/users/tasks/564",
+			payload:      `{"done": true}`,
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodPatch, tc.queryString, bytes.NewBufferString(tc.payload))
+			authenticate(t, req, tc.userId)
+			srv.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Result().StatusCode)
+		})
+	}
+
+	res, _ := store.Task().GetById(task.UserID, task.ID)
+	assert.Equal(t, "Renamed", res.Title)
+	assert.False(t, res.Done)
+}
+
+func TestServer_handleTaskReplace(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	task := model.TestTask(t)
+	store.Task().Create(task)
+	srv := testServer(t, store)
+
+	testCases := []struct {
+		name         string
+		userId       interface{}
+		queryString  string
+		payload      interface{}
+		expectedCode int
+	}{
+		{
+			name:        "valid",
+			userId:      task.UserID,
+			queryString: "/users/tasks/1",
+			payload: map[string]interface{}{
+				"title":       "Replaced",
+				"description": "New text",
+				"done":        true,
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:        "missing description",
+			userId:      task.UserID,
+			queryString: "/users/tasks/1",
+			payload: map[string]interface{}{
+				"title": "Replaced",
+			},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "invalid payload",
+			userId:       task.UserID,
+			queryString:  "/users/tasks/1",
+			payload:      "some text",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:        "not existing id",
+			userId:      task.UserID,
+			queryString: "/users/tasks/564",
+			payload: map[string]interface{}{
+				"title":       "Replaced",
+				"description": "New text",
+			},
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			buf := &bytes.Buffer{}
+			json.NewEncoder(buf).Encode(tc.payload)
+			req, _ := http.NewRequest(http.MethodPut, tc.queryString, buf)
+			authenticate(t, req, tc.userId)
 			srv.ServeHTTP(rec, req)
 			assert.Equal(t, tc.expectedCode, rec.Result().StatusCode)
 		})
 	}
 }
+
+func TestServer_handleTaskGetAllPage(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	for _, title := range []string{"c", "a", "b"} {
+		task := model.TestTask(t)
+		task.Title = title
+		store.Task().Create(task)
+	}
+	srv := testServer(t, store)
+
+	type page struct {
+		Tasks      []*model.Task `json:"tasks"`
+		NextCursor string        `json:"next_cursor"`
+	}
+
+	get := func(t *testing.T, queryString string) (int, *page) {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, queryString, nil)
+		authenticate(t, req, 1)
+		srv.ServeHTTP(rec, req)
+		p := &page{}
+		json.NewDecoder(rec.Body).Decode(p)
+		return rec.Code, p
+	}
+
+	code, first := get(t, "/users/tasks?sort=title&limit=2")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, first.Tasks, 2)
+	assert.Equal(t, "a", first.Tasks[0].Title)
+	assert.NotEmpty(t, first.NextCursor)
+
+	code, second := get(t, "/users/tasks?sort=title&limit=2&cursor="+first.NextCursor)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, second.Tasks, 1)
+	assert.Equal(t, "c", second.Tasks[0].Title)
+	assert.Empty(t, second.NextCursor)
+
+	code, desc := get(t, "/users/tasks?sort=title&order=desc")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "c", desc.Tasks[0].Title)
+
+	// The next page is empty once its tasks are gone
+	assert.NoError(t, store.Task().Delete(1, second.Tasks[0].ID))
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/users/tasks?sort=title&limit=2&cursor="+first.NextCursor, nil)
+	authenticate(t, req, 1)
+	srv.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"tasks": []}`, rec.Body.String())
+
+	testCases := []struct {
+		name         string
+		queryString  string
+		expectedCode int
+	}{
+		{
+			name:         "cursor of another sort",
+			queryString:  "/users/tasks?sort=created&cursor=" + first.NextCursor,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid cursor",
+			queryString:  "/users/tasks?cursor=abc",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid sort",
+			queryString:  "/users/tasks?sort=color",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid order",
+			queryString:  "/users/tasks?order=up",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid limit",
+			queryString:  "/users/tasks?limit=1000",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid priority",
+			queryString:  "/users/tasks?priority=critical",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, _ := get(t, tc.queryString)
+			assert.Equal(t, tc.expectedCode, code)
+		})
+	}
+}
+
+func TestServer_handleTagCreate(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	srv := testServer(t, store)
+
+	testCases := []struct {
+		name         string
+		payload      interface{}
+		expectedCode int
+	}{
+		{
+			name:         "valid",
+			payload:      map[string]string{"name": "Bug"},
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "existing name",
+			payload:      map[string]string{"name": "bug"},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "empty name",
+			payload:      map[string]string{"name": " "},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "invalid payload",
+			payload:      "some text",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			buf := &bytes.Buffer{}
+			json.NewEncoder(buf).Encode(tc.payload)
+			req, _ := http.NewRequest(http.MethodPost, "/users/tags", buf)
+			authenticate(t, req, 1)
+			srv.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Result().StatusCode)
+		})
+	}
+}
+
+func TestServer_handleTagRename(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	tag := model.TestTag(t)
+	store.Tag().Create(tag)
+	store.Tag().Create(&model.Tag{UserID: tag.UserID, Name: "home"})
+	srv := testServer(t, store)
+
+	testCases := []struct {
+		name         string
+		queryString  string
+		payload      interface{}
+		expectedCode int
+	}{
+		{
+			name:         "valid",
+			queryString:  "/users/tags/1",
+			payload:      map[string]string{"name": "critical"},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "existing name",
+			queryString:  "/users/tags/1",
+			payload:      map[string]string{"name": "home"},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "invalid id",
+			queryString:  "/users/tags/abc",
+			payload:      map[string]string{"name": "other"},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "not existing id",
+			queryString:  "/users/tags/10",
+			payload:      map[string]string{"name": "other"},
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			buf := &bytes.Buffer{}
+			json.NewEncoder(buf).Encode(tc.payload)
+			req, _ := http.NewRequest(http.MethodPatch, tc.queryString, buf)
+			authenticate(t, req, tag.UserID)
+			srv.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Result().StatusCode)
+		})
+	}
+}
+
+func TestServer_handleTagDelete(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	tag := model.TestTag(t)
+	store.Tag().Create(tag)
+	srv := testServer(t, store)
+
+	testCases := []struct {
+		name         string
+		queryString  string
+		expectedCode int
+	}{
+		{
+			name:         "valid id",
+			queryString:  "/users/tags/1",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "not existing id",
+			queryString:  "/users/tags/1",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodDelete, tc.queryString, nil)
+			authenticate(t, req, tag.UserID)
+			srv.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Result().StatusCode)
+		})
+	}
+}
+
+func TestServer_handleTaskTagAttach(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	task := model.TestTask(t)
+	store.Task().Create(task)
+	tag := model.TestTag(t)
+	store.Tag().Create(tag)
+	srv := testServer(t, store)
+
+	testCases := []struct {
+		name         string
+		method       string
+		queryString  string
+		expectedCode int
+	}{
+		{
+			name:         "attach",
+			method:       http.MethodPut,
+			queryString:  "/users/tasks/1/tags/1",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "filter by tag",
+			method:       http.MethodGet,
+			queryString:  "/users/tasks?tag=Urgent",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "not existing tag",
+			method:       http.MethodPut,
+			queryString:  "/users/tasks/1/tags/7",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "invalid tag id",
+			method:       http.MethodPut,
+			queryString:  "/users/tasks/1/tags/abc",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "detach",
+			method:       http.MethodDelete,
+			queryString:  "/users/tasks/1/tags/1",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "filter by detached tag",
+			method:       http.MethodGet,
+			queryString:  "/users/tasks?tag=urgent&tag_mode=all",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "invalid tag mode",
+			method:       http.MethodGet,
+			queryString:  "/users/tasks?tag=urgent&tag_mode=some",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest(tc.method, tc.queryString, nil)
+			authenticate(t, req, task.UserID)
+			srv.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Result().StatusCode)
+		})
+	}
+}
+
+func TestServer_handleProjectCreate(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	srv := testServer(t, store)
+
+	testCases := []struct {
+		name         string
+		payload      interface{}
+		expectedCode int
+	}{
+		{
+			name: "valid",
+			payload: map[string]string{
+				"name":        "Groceries",
+				"description": "Weekly shopping list",
+			},
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name: "empty name",
+			payload: map[string]string{
+				"description": "Weekly shopping list",
+			},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "invalid payload",
+			payload:      "some text",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			buf := &bytes.Buffer{}
+			json.NewEncoder(buf).Encode(tc.payload)
+			req, _ := http.NewRequest(http.MethodPost, "/users/projects", buf)
+			authenticate(t, req, 1)
+			srv.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Result().StatusCode)
+		})
+	}
+}
+
+func TestServer_handleProjectUpdate(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	p := model.TestProject(t)
+	store.Project().Create(p)
+	srv := testServer(t, store)
+
+	testCases := []struct {
+		name         string
+		queryString  string
+		payload      interface{}
+		expectedCode int
+	}{
+		{
+			name:         "rename",
+			queryString:  "/users/projects/1",
+			payload:      map[string]string{"name": "Sprint"},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "empty name",
+			queryString:  "/users/projects/1",
+			payload:      map[string]string{"name": ""},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "not existing id",
+			queryString:  "/users/projects/10",
+			payload:      map[string]string{"name": "Sprint"},
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			buf := &bytes.Buffer{}
+			json.NewEncoder(buf).Encode(tc.payload)
+			req, _ := http.NewRequest(http.MethodPatch, tc.queryString, buf)
+			authenticate(t, req, p.UserID)
+			srv.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Result().StatusCode)
+		})
+	}
+
+	res, _ := store.Project().GetById(p.UserID, p.ID)
+	assert.Equal(t, "Sprint", res.Name)
+	assert.Equal(t, "Weekly shopping list", res.Description)
+}
+
+func TestServer_handleProjectTasks(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	p := model.TestProject(t)
+	store.Project().Create(p)
+	srv := testServer(t, store)
+
+	testCases := []struct {
+		name         string
+		method       string
+		queryString  string
+		payload      interface{}
+		expectedCode int
+	}{
+		{
+			name:        "create task in project",
+			method:      http.MethodPost,
+			queryString: "/users/tasks",
+			payload: map[string]interface{}{
+				"title":       "Milk",
+				"description": "Two bottles",
+				"project_id":  p.ID,
+			},
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:        "create task in foreign project",
+			method:      http.MethodPost,
+			queryString: "/users/tasks",
+			payload: map[string]interface{}{
+				"title":       "Milk",
+				"description": "Two bottles",
+				"project_id":  10,
+			},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "tasks of project",
+			method:       http.MethodGet,
+			queryString:  "/users/tasks?project_id=1",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "tasks of other project",
+			method:       http.MethodGet,
+			queryString:  "/users/tasks?project_id=2",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "invalid move_to",
+			method:       http.MethodDelete,
+			queryString:  "/users/projects/1?move_to=2&delete_tasks=true",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "move to not existing project",
+			method:       http.MethodDelete,
+			queryString:  "/users/projects/1?move_to=2",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "delete with tasks",
+			method:       http.MethodDelete,
+			queryString:  "/users/projects/1?delete_tasks=true",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "tasks are deleted",
+			method:       http.MethodGet,
+			queryString:  "/users/tasks",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "project is deleted",
+			method:       http.MethodGet,
+			queryString:  "/users/projects/1",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			buf := &bytes.Buffer{}
+			if tc.payload != nil {
+				json.NewEncoder(buf).Encode(tc.payload)
+			}
+			req, _ := http.NewRequest(tc.method, tc.queryString, buf)
+			authenticate(t, req, p.UserID)
+			srv.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Result().StatusCode)
+		})
+	}
+}
+
+func TestServer_handleTaskGetChildren(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	parent := model.TestTask(t)
+	store.Task().Create(parent)
+	srv := testServer(t, store)
+
+	testCases := []struct {
+		name         string
+		method       string
+		queryString  string
+		payload      interface{}
+		expectedCode int
+	}{
+		{
+			name:         "no children",
+			method:       http.MethodGet,
+			queryString:  "/users/tasks/1/children",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:        "create subtask",
+			method:      http.MethodPost,
+			queryString: "/users/tasks",
+			payload: map[string]interface{}{
+				"title":       "Write handlers",
+				"description": "Subtask",
+				"parent_id":   parent.ID,
+			},
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "children",
+			method:       http.MethodGet,
+			queryString:  "/users/tasks/1/children",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "parent under its child",
+			method:       http.MethodPatch,
+			queryString:  "/users/tasks/1",
+			payload:      map[string]interface{}{"parent_id": 2},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "not existing task",
+			method:       http.MethodGet,
+			queryString:  "/users/tasks/10/children",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "invalid id",
+			method:       http.MethodGet,
+			queryString:  "/users/tasks/abc/children",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			buf := &bytes.Buffer{}
+			if tc.payload != nil {
+				json.NewEncoder(buf).Encode(tc.payload)
+			}
+			req, _ := http.NewRequest(tc.method, tc.queryString, buf)
+			authenticate(t, req, parent.UserID)
+			srv.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Result().StatusCode)
+		})
+	}
+
+	res, _ := store.Task().GetById(parent.UserID, parent.ID)
+	assert.Equal(t, &model.Progress{Done: 0, Total: 1}, res.Progress)
+}
+
+func TestServer_handleTaskGetSeries(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	srv := testServer(t, store)
+
+	testCases := []struct {
+		name         string
+		method       string
+		queryString  string
+		payload      interface{}
+		expectedCode int
+	}{
+		{
+			name:        "recurring without due date",
+			method:      http.MethodPost,
+			queryString: "/users/tasks",
+			payload: map[string]interface{}{
+				"title":       "Standup",
+				"description": "Daily meeting",
+				"recurrence":  "daily",
+			},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:        "invalid recurrence",
+			method:      http.MethodPost,
+			queryString: "/users/tasks",
+			payload: map[string]interface{}{
+				"title":       "Standup",
+				"description": "Daily meeting",
+				"due_at":      "2026-01-05T09:00:00Z",
+				"recurrence":  "FREQ=HOURLY",
+			},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:        "create recurring",
+			method:      http.MethodPost,
+			queryString: "/users/tasks",
+			payload: map[string]interface{}{
+				"title":       "Standup",
+				"description": "Daily meeting",
+				"due_at":      "2026-01-05T09:00:00Z",
+				"recurrence":  "FREQ=WEEKLY;BYDAY=MO,WE,FR",
+			},
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "complete occurrence",
+			method:       http.MethodPatch,
+			queryString:  "/users/tasks/1",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "series of first occurrence",
+			method:       http.MethodGet,
+			queryString:  "/users/tasks/1/series",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "series of next occurrence",
+			method:       http.MethodGet,
+			queryString:  "/users/tasks/2/series",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "not existing task",
+			method:       http.MethodGet,
+			queryString:  "/users/tasks/10/series",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			buf := &bytes.Buffer{}
+			if tc.payload != nil {
+				json.NewEncoder(buf).Encode(tc.payload)
+			}
+			req, _ := http.NewRequest(tc.method, tc.queryString, buf)
+			authenticate(t, req, 1)
+			srv.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Result().StatusCode)
+		})
+	}
+
+	next, err := store.Task().GetById(1, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 1, 7, 9, 0, 0, 0, time.UTC), *next.DueAt)
+	assert.Equal(t, 1, *next.SeriesID)
+}
+
+func TestServer_handleTaskPriority(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	srv := testServer(t, store)
+
+	send := func(t *testing.T, method, queryString string, payload interface{}) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		buf := &bytes.Buffer{}
+		if payload != nil {
+			json.NewEncoder(buf).Encode(payload)
+		}
+		req, _ := http.NewRequest(method, queryString, buf)
+		authenticate(t, req, 1)
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, priority := range []string{"", "high", "low"} {
+		rec := send(t, http.MethodPost, "/users/tasks", map[string]string{
+			"title":       "Task",
+			"description": "Priority " + priority,
+			"priority":    priority,
+		})
+		assert.Equal(t, http.StatusCreated, rec.Code)
+	}
+
+	rec := send(t, http.MethodPost, "/users/tasks", map[string]string{
+		"title":       "Task",
+		"description": "Unknown priority",
+		"priority":    "critical",
+	})
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	rec = send(t, http.MethodPatch, "/users/tasks/1", map[string]string{"priority": "urgent"})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	type page struct {
+		Tasks []*model.Task `json:"tasks"`
+	}
+
+	rec = send(t, http.MethodGet, "/users/tasks?sort=priority", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	p := &page{}
+	json.NewDecoder(rec.Body).Decode(p)
+	assert.Len(t, p.Tasks, 3)
+	assert.Equal(t, model.PriorityUrgent, p.Tasks[0].Priority)
+	assert.Equal(t, model.PriorityHigh, p.Tasks[1].Priority)
+	assert.Equal(t, model.PriorityLow, p.Tasks[2].Priority)
+
+	rec = send(t, http.MethodGet, "/users/tasks?priority=high&priority=LOW", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	p = &page{}
+	json.NewDecoder(rec.Body).Decode(p)
+	assert.Len(t, p.Tasks, 2)
+}
+
+func TestServer_handleTrash(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	for i := 0; i < 2; i++ {
+		store.Task().Create(model.TestTask(t))
+	}
+	srv := testServer(t, store)
+
+	testCases := []struct {
+		name         string
+		method       string
+		queryString  string
+		expectedCode int
+	}{
+		{
+			name:         "empty trash",
+			method:       http.MethodGet,
+			queryString:  "/users/trash",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "delete",
+			method:       http.MethodDelete,
+			queryString:  "/users/tasks/1",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "deleted task is hidden",
+			method:       http.MethodGet,
+			queryString:  "/users/tasks/1",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "trash",
+			method:       http.MethodGet,
+			queryString:  "/users/trash",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "restore",
+			method:       http.MethodPost,
+			queryString:  "/users/tasks/1/restore",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "restore task not in trash",
+			method:       http.MethodPost,
+			queryString:  "/users/tasks/2/restore",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "purge task not in trash",
+			method:       http.MethodDelete,
+			queryString:  "/users/trash/1",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "delete again",
+			method:       http.MethodDelete,
+			queryString:  "/users/tasks/1",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "purge",
+			method:       http.MethodDelete,
+			queryString:  "/users/trash/1",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "purged task can't be restored",
+			method:       http.MethodPost,
+			queryString:  "/users/tasks/1/restore",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "delete other",
+			method:       http.MethodDelete,
+			queryString:  "/users/tasks/2",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "empty",
+			method:       http.MethodDelete,
+			queryString:  "/users/trash",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "emptied trash",
+			method:       http.MethodGet,
+			queryString:  "/users/trash",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest(tc.method, tc.queryString, nil)
+			authenticate(t, req, 1)
+			srv.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+		})
+	}
+}
+
+func TestServer_purgeTrash(t *testing.T) {
+	store := teststore.New()
+	task := model.TestTask(t)
+	store.Task().Create(task)
+	store.Task().Delete(task.UserID, task.ID)
+	srv := testServer(t, store)
+
+	tick := make(chan time.Time)
+	close(tick)
+
+	srv.purgeTrash(time.Hour, tick)
+	_, err := store.Task().GetTrash(task.UserID)
+	assert.NoError(t, err)
+
+	srv.purgeTrash(-time.Hour, tick)
+	_, err = store.Task().GetTrash(task.UserID)
+	assert.Error(t, err)
+}
+
+func TestServer_handleTaskGetHistory(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	other := model.TestUser(t)
+	other.Email = "other@user.com"
+	store.User().Create(other)
+	store.Task().Create(model.TestTask(t))
+	store.Task().Done(1, 1)
+	srv := testServer(t, store)
+
+	testCases := []struct {
+		name         string
+		queryString  string
+		userId       int
+		expectedCode int
+	}{
+		{
+			name:         "valid",
+			queryString:  "/users/tasks/1/history",
+			userId:       1,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "task of another user",
+			queryString:  "/users/tasks/1/history",
+			userId:       2,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "invalid id",
+			queryString:  "/users/tasks/abc/history",
+			userId:       1,
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, tc.queryString, nil)
+			authenticate(t, req, tc.userId)
+			srv.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/users/tasks/1/history", nil)
+	authenticate(t, req, 1)
+	srv.ServeHTTP(rec, req)
+	var events []*model.TaskEvent
+	json.NewDecoder(rec.Body).Decode(&events)
+	assert.Len(t, events, 2)
+	assert.Equal(t, model.ActionDone, events[1].Action)
+}
+
+func TestServer_handleTaskSearch(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	task := model.TestTask(t)
+	task.Title = "Buy milk"
+	store.Task().Create(task)
+	srv := testServer(t, store)
+
+	testCases := []struct {
+		name         string
+		queryString  string
+		expectedCode int
+	}{
+		{
+			name:         "found",
+			queryString:  "/users/tasks/search?q=milk",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "not found",
+			queryString:  "/users/tasks/search?q=bread",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "missing query",
+			queryString:  "/users/tasks/search?q=+",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid limit",
+			queryString:  "/users/tasks/search?q=milk&limit=0",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, tc.queryString, nil)
+			authenticate(t, req, 1)
+			srv.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+		})
+	}
+}
+
+func TestServer_handleTaskGetAllFilter(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	task := model.TestTask(t)
+	task.Title = "Weekly report"
+	store.Task().Create(task)
+	srv := testServer(t, store)
+
+	testCases := []struct {
+		name         string
+		filter       string
+		expectedCode int
+		expectedBody string
+	}{
+		{
+			name:         "matching",
+			filter:       `done:false AND title~"report"`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "not matching",
+			filter:       "done:true",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "invalid",
+			filter:       "done:false AND color:red",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: `position 16 near \"color:red\"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/users/tasks?filter="+url.QueryEscape(tc.filter), nil)
+			authenticate(t, req, 1)
+			srv.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+			assert.Contains(t, rec.Body.String(), tc.expectedBody)
+		})
+	}
+}
+
+func TestServer_handleTaskBatch(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	task := model.TestTask(t)
+	store.Task().Create(task)
+	srv := testServer(t, store)
+
+	type item struct {
+		Status int `json:"status"`
+	}
+	type response struct {
+		Committed bool    `json:"committed"`
+		Results   []*item `json:"results"`
+	}
+
+	testCases := []struct {
+		name             string
+		payload          interface{}
+		expectedCode     int
+		expectedStatuses []int
+	}{
+		{
+			name: "partial",
+			payload: map[string]interface{}{
+				"operations": []map[string]interface{}{
+					{"op": "create", "task": map[string]string{"title": "New task", "description": "Details"}},
+					{"op": "update", "id": task.ID, "patch": map[string]string{"title": "Renamed"}},
+					{"op": "complete", "id": 100},
+					{"op": "create", "task": map[string]string{"title": ""}},
+					{"op": "archive", "id": task.ID},
+				},
+			},
+			expectedCode:     http.StatusOK,
+			expectedStatuses: []int{201, 200, 404, 422, 400},
+		},
+		{
+			name: "atomic rolled back",
+			payload: map[string]interface{}{
+				"atomic": true,
+				"operations": []map[string]interface{}{
+					{"op": "complete", "id": task.ID},
+					{"op": "delete", "id": 100},
+				},
+			},
+			expectedCode:     http.StatusUnprocessableEntity,
+			expectedStatuses: []int{200, 404},
+		},
+		{
+			name:         "empty batch",
+			payload:      map[string]interface{}{"operations": []interface{}{}},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid payload",
+			payload:      "invalid",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			buf := &bytes.Buffer{}
+			json.NewEncoder(buf).Encode(tc.payload)
+			req, _ := http.NewRequest(http.MethodPost, "/users/tasks/batch", buf)
+			authenticate(t, req, 1)
+			srv.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+			if tc.expectedStatuses == nil {
+				return
+			}
+
+			resp := &response{}
+			json.NewDecoder(rec.Body).Decode(resp)
+			assert.Equal(t, tc.expectedCode == http.StatusOK, resp.Committed)
+			var statuses []int
+			for _, res := range resp.Results {
+				statuses = append(statuses, res.Status)
+			}
+			assert.Equal(t, tc.expectedStatuses, statuses)
+		})
+	}
+
+	res, _ := store.Task().GetById(task.UserID, task.ID)
+	assert.Equal(t, "Renamed", res.Title)
+	assert.False(t, res.Done)
+}
+
+func TestServer_handleTaskBatchShortcuts(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	store.Task().Create(model.TestTask(t))
+	store.Task().Create(model.TestTask(t))
+	srv := testServer(t, store)
+
+	testCases := []struct {
+		name          string
+		path          string
+		expectedCount int
+	}{
+		{
+			name:          "complete all",
+			path:          "/users/tasks/batch/complete-all",
+			expectedCount: 2,
+		},
+		{
+			name:          "delete done",
+			path:          "/users/tasks/batch/delete-done",
+			expectedCount: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodPost, tc.path, nil)
+			authenticate(t, req, 1)
+			srv.ServeHTTP(rec, req)
+			assert.Equal(t, http.StatusOK, rec.Code)
+			resp := map[string]interface{}{}
+			json.NewDecoder(rec.Body).Decode(&resp)
+			assert.Equal(t, float64(tc.expectedCount), resp["count"])
+		})
+	}
+}
+
+func TestServer_handleTaskCompleteAllBlocked(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	task := model.TestTask(t)
+	store.Task().Create(task)
+	blocked := model.TestTask(t)
+	store.Task().Create(blocked)
+	other := model.TestTask(t)
+	other.UserID = 2
+	store.Task().Create(other)
+	share := model.TestShare(t)
+	share.OwnerID, share.UserID, share.TaskID = 2, 1, &other.ID
+	store.Share().Create(share)
+	store.Task().AddBlocker(1, blocked.ID, other.ID)
+	srv := testServer(t, store)
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/users/tasks/batch/complete-all", nil)
+	authenticate(t, req, 1)
+	srv.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	resp := map[string]interface{}{}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	assert.Equal(t, float64(1), resp["count"])
+	assert.Equal(t, []interface{}{float64(blocked.ID)}, resp["blocked"])
+
+	res, _ := store.Task().GetById(1, task.ID)
+	assert.True(t, res.Done)
+	res, _ = store.Task().GetById(1, blocked.ID)
+	assert.False(t, res.Done)
+}
+
+func TestServer_handleTaskMove(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	first, second := model.TestTask(t), model.TestTask(t)
+	store.Task().Create(first)
+	store.Task().Create(second)
+	srv := testServer(t, store)
+
+	testCases := []struct {
+		name         string
+		path         string
+		payload      interface{}
+		expectedCode int
+	}{
+		{
+			name:         "before",
+			path:         fmt.Sprintf("/users/tasks/%d/move", second.ID),
+			payload:      map[string]int{"before": first.ID},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "after",
+			path:         fmt.Sprintf("/users/tasks/%d/move", second.ID),
+			payload:      map[string]int{"after": first.ID},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "both positions",
+			path:         fmt.Sprintf("/users/tasks/%d/move", second.ID),
+			payload:      map[string]int{"before": first.ID, "after": first.ID},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "no position",
+			path:         fmt.Sprintf("/users/tasks/%d/move", second.ID),
+			payload:      map[string]int{},
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "itself",
+			path:         fmt.Sprintf("/users/tasks/%d/move", second.ID),
+			payload:      map[string]int{"after": second.ID},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "unknown target",
+			path:         fmt.Sprintf("/users/tasks/%d/move", second.ID),
+			payload:      map[string]int{"after": 100},
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			buf := &bytes.Buffer{}
+			json.NewEncoder(buf).Encode(tc.payload)
+			req, _ := http.NewRequest(http.MethodPost, tc.path, buf)
+			authenticate(t, req, 1)
+			srv.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+		})
+	}
+
+	tasks, _ := store.Task().GetAll(1)
+	assert.Equal(t, []int{first.ID, second.ID}, []int{tasks[0].ID, tasks[1].ID})
+}
+
+func TestServer_handleWorkflowSave(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	p := model.TestProject(t)
+	store.Project().Create(p)
+	srv := testServer(t, store)
+
+	testCases := []struct {
+		name         string
+		path         string
+		payload      interface{}
+		expectedCode int
+	}{
+		{
+			name:         "user workflow",
+			path:         "/users/workflow",
+			payload:      model.TestWorkflow(t),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "project workflow",
+			path:         fmt.Sprintf("/users/projects/%d/workflow", p.ID),
+			payload:      model.DefaultWorkflow(),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "unknown project",
+			path:         "/users/projects/100/workflow",
+			payload:      model.DefaultWorkflow(),
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name: "invalid workflow",
+			path: "/users/workflow",
+			payload: map[string]interface{}{
+				"statuses": []map[string]interface{}{{"name": "todo"}},
+			},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			buf := &bytes.Buffer{}
+			json.NewEncoder(buf).Encode(tc.payload)
+			req, _ := http.NewRequest(http.MethodPut, tc.path, buf)
+			authenticate(t, req, 1)
+			srv.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+		})
+	}
+
+	w, _ := store.Workflow().Get(1, nil)
+	assert.Equal(t, "backlog", w.Initial())
+	w, _ = store.Workflow().Get(1, &p.ID)
+	assert.Equal(t, "todo", w.Initial())
+}
+
+func TestServer_handleBoardGet(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	p := model.TestProject(t)
+	store.Project().Create(p)
+	w := model.TestWorkflow(t)
+	w.ProjectID = &p.ID
+	store.Workflow().Save(w)
+	open, done, inProject := model.TestTask(t), model.TestTask(t), model.TestTask(t)
+	store.Task().Create(open)
+	store.Task().Create(done)
+	store.Task().Done(done.UserID, done.ID)
+	inProject.ProjectID = &p.ID
+	store.Task().Create(inProject)
+	srv := testServer(t, store)
+
+	type column struct {
+		Status string        `json:"status"`
+		Tasks  []*model.Task `json:"tasks"`
+	}
+
+	testCases := []struct {
+		name         string
+		path         string
+		expectedCode int
+		expected     map[string]int
+	}{
+		{
+			name:         "user board",
+			path:         "/users/board",
+			expectedCode: http.StatusOK,
+			expected:     map[string]int{"todo": 1, "in_progress": 0, "blocked": 0, "done": 1},
+		},
+		{
+			name:         "project board",
+			path:         fmt.Sprintf("/users/projects/%d/board", p.ID),
+			expectedCode: http.StatusOK,
+			expected:     map[string]int{"backlog": 1, "review": 0, "shipped": 0},
+		},
+		{
+			name:         "unknown project",
+			path:         "/users/projects/100/board",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, tc.path, nil)
+			authenticate(t, req, 1)
+			srv.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+			if tc.expected == nil {
+				return
+			}
+
+			resp := &struct {
+				Columns []*column `json:"columns"`
+			}{}
+			json.NewDecoder(rec.Body).Decode(resp)
+			found := map[string]int{}
+			for _, c := range resp.Columns {
+				found[c.Status] = len(c.Tasks)
+			}
+			assert.Equal(t, tc.expected, found)
+		})
+	}
+}
+
+func TestServer_handleTaskPatchStatus(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	task := model.TestTask(t)
+	store.Task().Create(task)
+	srv := testServer(t, store)
+
+	testCases := []struct {
+		name         string
+		payload      string
+		expectedCode int
+	}{
+		{
+			name:         "allowed",
+			payload:      `{"status": "blocked"}`,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "not allowed",
+			payload:      `{"status": "done"}`,
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "unknown",
+			payload:      `{"status": "review"}`,
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodPatch, fmt.Sprintf("/users/tasks/%d", task.ID), bytes.NewBufferString(tc.payload))
+			authenticate(t, req, 1)
+			srv.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+		})
+	}
+}
+
+func TestServer_handleTaskBlocker(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	blocker, task := model.TestTask(t), model.TestTask(t)
+	store.Task().Create(blocker)
+	store.Task().Create(task)
+	srv := testServer(t, store)
+
+	testCases := []struct {
+		name         string
+		method       string
+		path         string
+		expectedCode int
+	}{
+		{
+			name:         "add",
+			method:       http.MethodPut,
+			path:         fmt.Sprintf("/users/tasks/%d/blockers/%d", task.ID, blocker.ID),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "cycle",
+			method:       http.MethodPut,
+			path:         fmt.Sprintf("/users/tasks/%d/blockers/%d", blocker.ID, task.ID),
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "unknown blocker",
+			method:       http.MethodPut,
+			path:         fmt.Sprintf("/users/tasks/%d/blockers/100", task.ID),
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "invalid blocker id",
+			method:       http.MethodPut,
+			path:         fmt.Sprintf("/users/tasks/%d/blockers/first", task.ID),
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "done while blocked",
+			method:       http.MethodPatch,
+			path:         fmt.Sprintf("/users/tasks/%d", task.ID),
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "remove",
+			method:       http.MethodDelete,
+			path:         fmt.Sprintf("/users/tasks/%d/blockers/%d", task.ID, blocker.ID),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "remove missing",
+			method:       http.MethodDelete,
+			path:         fmt.Sprintf("/users/tasks/%d/blockers/%d", task.ID, blocker.ID),
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "done",
+			method:       http.MethodPatch,
+			path:         fmt.Sprintf("/users/tasks/%d", task.ID),
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest(tc.method, tc.path, nil)
+			authenticate(t, req, 1)
+			srv.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+		})
+	}
+}
+
+func TestServer_handleTimer(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	first, second := model.TestTask(t), model.TestTask(t)
+	store.Task().Create(first)
+	store.Task().Create(second)
+	srv := testServer(t, store)
+
+	testCases := []struct {
+		name         string
+		method       string
+		path         string
+		payload      interface{}
+		expectedCode int
+	}{
+		{
+			name:         "start",
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/users/tasks/%d/timer/start", first.ID),
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "start another",
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/users/tasks/%d/timer/start", second.ID),
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "running",
+			method:       http.MethodGet,
+			path:         "/users/timer",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "stop another",
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/users/tasks/%d/timer/stop", second.ID),
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "stop",
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/users/tasks/%d/timer/stop", first.ID),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "nothing running",
+			method:       http.MethodGet,
+			path:         "/users/timer",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "unknown task",
+			method:       http.MethodPost,
+			path:         "/users/tasks/100/timer/start",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:   "manual entry",
+			method: http.MethodPost,
+			path:   fmt.Sprintf("/users/tasks/%d/time", second.ID),
+			payload: map[string]string{
+				"started_at": "2026-03-02T09:00:00Z",
+				"stopped_at": "2026-03-02T10:30:00Z",
+			},
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:   "manual entry stopped before started",
+			method: http.MethodPost,
+			path:   fmt.Sprintf("/users/tasks/%d/time", second.ID),
+			payload: map[string]string{
+				"started_at": "2026-03-02T09:00:00Z",
+				"stopped_at": "2026-03-02T08:00:00Z",
+			},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "report",
+			method:       http.MethodGet,
+			path:         "/users/time?from=2026-03-02&to=2026-03-03",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "report with an empty period",
+			method:       http.MethodGet,
+			path:         "/users/time?from=2026-03-03&to=2026-03-02",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			buf := &bytes.Buffer{}
+			if tc.payload != nil {
+				json.NewEncoder(buf).Encode(tc.payload)
+			}
+			req, _ := http.NewRequest(tc.method, tc.path, buf)
+			authenticate(t, req, 1)
+			srv.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/users/tasks/%d/time", second.ID), nil)
+	authenticate(t, req, 1)
+	srv.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	resp := &struct {
+		Entries []*model.TimeEntry `json:"entries"`
+		Total   int64              `json:"total"`
+	}{}
+	json.NewDecoder(rec.Body).Decode(resp)
+	assert.Len(t, resp.Entries, 1)
+	assert.Equal(t, int64(90*60), resp.Total)
+
+	rec = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/users/time?from=2026-03-02&to=2026-03-03", nil)
+	authenticate(t, req, 1)
+	srv.ServeHTTP(rec, req)
+
+	report := &model.TimeReport{}
+	json.NewDecoder(rec.Body).Decode(report)
+	assert.Equal(t, []*model.TaskTotal{{TaskID: second.ID, Duration: 90 * 60}}, report.Tasks)
+}
+
+func TestServer_handleBurndownReport(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	estimate := 5
+	open, done := model.TestTask(t), model.TestTask(t)
+	open.Estimate, done.Estimate = &estimate, &estimate
+	store.Task().Create(open)
+	store.Task().Create(done)
+	store.Task().Done(1, done.ID)
+	srv := testServer(t, store)
+
+	today := time.Now().UTC().Format("2006-01-02")
+	testCases := []struct {
+		name         string
+		query        string
+		expectedCode int
+	}{
+		{
+			name:         "default period",
+			query:        "",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "today",
+			query:        "?from=" + today + "&to=" + today,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "empty period",
+			query:        "?from=2026-03-02&to=2026-03-01",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "too long",
+			query:        "?from=2024-03-02&to=2026-03-01",
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "unknown project",
+			query:        "?project_id=100",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/users/reports/burndown"+tc.query, nil)
+			authenticate(t, req, 1)
+			srv.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/users/reports/burndown", nil)
+	authenticate(t, req, 1)
+	srv.ServeHTTP(rec, req)
+
+	resp := &struct {
+		Points []*model.BurndownPoint `json:"points"`
+	}{}
+	json.NewDecoder(rec.Body).Decode(resp)
+	assert.Len(t, resp.Points, 14)
+
+	last := resp.Points[len(resp.Points)-1]
+	assert.Equal(t, today, last.Date)
+	assert.Equal(t, 5, *last.Remaining)
+	assert.Equal(t, 5, *last.Completed)
+}
+
+func TestServer_handleComments(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	task := model.TestTask(t)
+	store.Task().Create(task)
+	srv := testServer(t, store)
+
+	testCases := []struct {
+		name         string
+		method       string
+		path         string
+		payload      interface{}
+		expectedCode int
+	}{
+		{
+			name:         "create",
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/users/tasks/%d/comments", task.ID),
+			payload:      map[string]string{"body": "# Notes\n\n- first"},
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "empty body",
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/users/tasks/%d/comments", task.ID),
+			payload:      map[string]string{"body": ""},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "unknown task",
+			method:       http.MethodPost,
+			path:         "/users/tasks/100/comments",
+			payload:      map[string]string{"body": "Hello"},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "list",
+			method:       http.MethodGet,
+			path:         fmt.Sprintf("/users/tasks/%d/comments", task.ID),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "edit",
+			method:       http.MethodPatch,
+			path:         fmt.Sprintf("/users/tasks/%d/comments/1", task.ID),
+			payload:      map[string]string{"body": "Edited"},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "edit unknown",
+			method:       http.MethodPatch,
+			path:         fmt.Sprintf("/users/tasks/%d/comments/100", task.ID),
+			payload:      map[string]string{"body": "Edited"},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "invalid comment id",
+			method:       http.MethodDelete,
+			path:         fmt.Sprintf("/users/tasks/%d/comments/first", task.ID),
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "delete",
+			method:       http.MethodDelete,
+			path:         fmt.Sprintf("/users/tasks/%d/comments/1", task.ID),
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			buf := &bytes.Buffer{}
+			if tc.payload != nil {
+				json.NewEncoder(buf).Encode(tc.payload)
+			}
+			req, _ := http.NewRequest(tc.method, tc.path, buf)
+			authenticate(t, req, 1)
+			srv.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+		})
+	}
+}
+
+// multipartFile builds an upload form with the content in its file field
+func multipartFile(t *testing.T, field string, name string, content []byte) (*bytes.Buffer, string) {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	form := multipart.NewWriter(buf)
+	part, err := form.CreateFormFile(field, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(content)
+	form.Close()
+
+	return buf, form.FormDataContentType()
+}
+
+func TestServer_handleAttachmentCreate(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	task := model.TestTask(t)
+	store.Task().Create(task)
+	srv := testServer(t, store)
+
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 100)...)
+	testCases := []struct {
+		name         string
+		path         string
+		field        string
+		content      []byte
+		expectedCode int
+	}{
+		{
+			name:         "image",
+			path:         fmt.Sprintf("/users/tasks/%d/attachments", task.ID),
+			field:        "file",
+			content:      png,
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "plain text",
+			path:         fmt.Sprintf("/users/tasks/%d/attachments", task.ID),
+			field:        "file",
+			content:      []byte("steps to reproduce"),
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "executable",
+			path:         fmt.Sprintf("/users/tasks/%d/attachments", task.ID),
+			field:        "file",
+			content:      []byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00\x00\x00\xff\xff"),
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "empty file",
+			path:         fmt.Sprintf("/users/tasks/%d/attachments", task.ID),
+			field:        "file",
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "too large",
+			path:         fmt.Sprintf("/users/tasks/%d/attachments", task.ID),
+			field:        "file",
+			content:      bytes.Repeat([]byte("a"), model.MaxAttachmentSize+1),
+			expectedCode: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:         "missing file",
+			path:         fmt.Sprintf("/users/tasks/%d/attachments", task.ID),
+			field:        "document",
+			content:      png,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "unknown task",
+			path:         "/users/tasks/100/attachments",
+			field:        "file",
+			content:      png,
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			body, contentType := multipartFile(t, tc.field, "upload", tc.content)
+			req, _ := http.NewRequest(http.MethodPost, tc.path, body)
+			req.Header.Set("Content-Type", contentType)
+			authenticate(t, req, 1)
+			srv.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+		})
+	}
+
+	attachments, _ := store.Attachment().GetByTask(1, task.ID)
+	if assert.Len(t, attachments, 2) {
+		assert.Equal(t, "image/png", attachments[0].ContentType)
+		assert.Equal(t, "text/plain", attachments[1].ContentType)
+	}
+}
+
+func TestServer_handleAttachmentGet(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	other := model.TestUser(t)
+	other.Email = "other@user.com"
+	store.User().Create(other)
+	task := model.TestTask(t)
+	store.Task().Create(task)
+	srv := testServer(t, store)
+
+	rec := httptest.NewRecorder()
+	body, contentType := multipartFile(t, "file", "notes.txt", []byte("steps to reproduce"))
+	req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/users/tasks/%d/attachments", task.ID), body)
+	req.Header.Set("Content-Type", contentType)
+	authenticate(t, req, 1)
+	srv.ServeHTTP(rec, req)
+	attachment := &model.Attachment{}
+	json.NewDecoder(rec.Body).Decode(attachment)
+	attachment, _ = store.Attachment().GetById(1, task.ID, attachment.ID)
+
+	rec = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/users/tasks/%d/attachments/%d", task.ID, attachment.ID), nil)
+	authenticate(t, req, 1)
+	srv.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/plain", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename=notes.txt`, rec.Header().Get("Content-Disposition"))
+	assert.Equal(t, "steps to reproduce", rec.Body.String())
+
+	// Another user can't download it
+	rec = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, fmt.Sprintf("/users/tasks/%d/attachments/%d", task.ID, attachment.ID), nil)
+	authenticate(t, req, 2)
+	srv.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodDelete, fmt.Sprintf("/users/tasks/%d/attachments/%d", task.ID, attachment.ID), nil)
+	authenticate(t, req, 1)
+	srv.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	stored, _ := store.Attachment().GetByTask(1, task.ID)
+	assert.Empty(t, stored)
+	_, err := srv.blobs.Get(attachment.Key)
+	assert.Equal(t, blobstore.ErrNotFound, err)
+}
+
+func TestServer_handleTrashPurge_Attachments(t *testing.T) {
+	store := teststore.New()
+	store.User().Create(model.TestUser(t))
+	task := model.TestTask(t)
+	store.Task().Create(task)
+	srv := testServer(t, store)
+
+	rec := httptest.NewRecorder()
+	body, contentType := multipartFile(t, "file", "notes.txt", []byte("steps to reproduce"))
+	req, _ := http.NewRequest(http.MethodPost, fmt.Sprintf("/users/tasks/%d/attachments", task.ID), body)
+	req.Header.Set("Content-Type", contentType)
+	authenticate(t, req, 1)
+	srv.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	attachments, _ := store.Attachment().GetByTask(1, task.ID)
+	key := attachments[0].Key
+
+	store.Task().Delete(1, task.ID)
+	rec = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodDelete, fmt.Sprintf("/users/trash/%d", task.ID), nil)
+	authenticate(t, req, 1)
+	srv.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	_, err := srv.blobs.Get(key)
+	assert.Equal(t, blobstore.ErrNotFound, err)
+	orphans, _ := store.Attachment().Orphans()
+	assert.Empty(t, orphans)
+}
+
+func TestServer_handleShares(t *testing.T) {
+	store := teststore.New()
+	owner := model.TestUser(t)
+	store.User().Create(owner)
+	friend := model.TestUser(t)
+	friend.Email = "friend@user.com"
+	store.User().Create(friend)
+	task := model.TestTask(t)
+	task.UserID = owner.ID
+	store.Task().Create(task)
+	srv := testServer(t, store)
+
+	testCases := []struct {
+		name         string
+		userId       int
+		method       string
+		path         string
+		payload      interface{}
+		expectedCode int
+	}{
+		{
+			name:         "share as viewer",
+			userId:       owner.ID,
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/users/tasks/%d/shares", task.ID),
+			payload:      map[string]string{"email": "Friend@User.com", "role": "viewer"},
+			expectedCode: http.StatusAccepted,
+		},
+		{
+			name:         "unknown email",
+			userId:       owner.ID,
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/users/tasks/%d/shares", task.ID),
+			payload:      map[string]string{"email": "nobody@user.com", "role": "viewer"},
+			expectedCode: http.StatusAccepted,
+		},
+		{
+			name:         "unknown email and role",
+			userId:       owner.ID,
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/users/tasks/%d/shares", task.ID),
+			payload:      map[string]string{"email": "nobody@user.com", "role": "admin"},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "unknown role",
+			userId:       owner.ID,
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/users/tasks/%d/shares", task.ID),
+			payload:      map[string]string{"email": friend.Email, "role": "admin"},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "share with self",
+			userId:       owner.ID,
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/users/tasks/%d/shares", task.ID),
+			payload:      map[string]string{"email": owner.Email, "role": "viewer"},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "reshare as non owner",
+			userId:       friend.ID,
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/users/tasks/%d/shares", task.ID),
+			payload:      map[string]string{"email": owner.Email, "role": "editor"},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "share with an unknown email as non owner",
+			userId:       friend.ID,
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/users/tasks/%d/shares", task.ID),
+			payload:      map[string]string{"email": "nobody@user.com", "role": "editor"},
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "viewer reads",
+			userId:       friend.ID,
+			method:       http.MethodGet,
+			path:         fmt.Sprintf("/users/tasks/%d", task.ID),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "viewer edits",
+			userId:       friend.ID,
+			method:       http.MethodPatch,
+			path:         fmt.Sprintf("/users/tasks/%d", task.ID),
+			payload:      map[string]string{"title": "Edited"},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "viewer comments",
+			userId:       friend.ID,
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/users/tasks/%d/comments", task.ID),
+			payload:      map[string]string{"body": "Hello"},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "share as editor",
+			userId:       owner.ID,
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/users/tasks/%d/shares", task.ID),
+			payload:      map[string]string{"email": friend.Email, "role": "editor"},
+			expectedCode: http.StatusAccepted,
+		},
+		{
+			name:         "editor edits",
+			userId:       friend.ID,
+			method:       http.MethodPatch,
+			path:         fmt.Sprintf("/users/tasks/%d", task.ID),
+			payload:      map[string]string{"title": "Edited"},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "editor comments",
+			userId:       friend.ID,
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/users/tasks/%d/comments", task.ID),
+			payload:      map[string]string{"body": "Hello"},
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "owner edits the comment of the editor",
+			userId:       owner.ID,
+			method:       http.MethodPatch,
+			path:         fmt.Sprintf("/users/tasks/%d/comments/1", task.ID),
+			payload:      map[string]string{"body": "Edited"},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "editor deletes",
+			userId:       friend.ID,
+			method:       http.MethodDelete,
+			path:         fmt.Sprintf("/users/tasks/%d", task.ID),
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "list shares",
+			userId:       owner.ID,
+			method:       http.MethodGet,
+			path:         fmt.Sprintf("/users/tasks/%d/shares", task.ID),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "shared with me",
+			userId:       friend.ID,
+			method:       http.MethodGet,
+			path:         "/users/shared",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "revoke",
+			userId:       owner.ID,
+			method:       http.MethodDelete,
+			path:         "/users/shares/1",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "revoked",
+			userId:       friend.ID,
+			method:       http.MethodGet,
+			path:         fmt.Sprintf("/users/tasks/%d", task.ID),
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			buf := &bytes.Buffer{}
+			if tc.payload != nil {
+				json.NewEncoder(buf).Encode(tc.payload)
+			}
+			req, _ := http.NewRequest(tc.method, tc.path, buf)
+			authenticate(t, req, tc.userId)
+			srv.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+		})
+	}
+}
+
+func TestServer_handleTaskAssignee(t *testing.T) {
+	store := teststore.New()
+	owner := model.TestUser(t)
+	store.User().Create(owner)
+	member := model.TestUser(t)
+	member.Email = "member@user.com"
+	store.User().Create(member)
+	project := model.TestProject(t)
+	project.UserID = owner.ID
+	store.Project().Create(project)
+	task := model.TestTask(t)
+	task.UserID, task.ProjectID = owner.ID, &project.ID
+	store.Task().Create(task)
+	srv := testServer(t, store)
+
+	testCases := []struct {
+		name         string
+		userId       int
+		method       string
+		path         string
+		payload      interface{}
+		expectedCode int
+	}{
+		{
+			name:         "assign a non member",
+			userId:       owner.ID,
+			method:       http.MethodPatch,
+			path:         fmt.Sprintf("/users/tasks/%d", task.ID),
+			payload:      map[string]int{"assignee_id": member.ID},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "share the project",
+			userId:       owner.ID,
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/users/projects/%d/shares", project.ID),
+			payload:      map[string]string{"email": member.Email, "role": "editor"},
+			expectedCode: http.StatusAccepted,
+		},
+		{
+			name:         "assign a member",
+			userId:       owner.ID,
+			method:       http.MethodPatch,
+			path:         fmt.Sprintf("/users/tasks/%d", task.ID),
+			payload:      map[string]int{"assignee_id": member.ID},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "invalid assignee filter",
+			userId:       member.ID,
+			method:       http.MethodGet,
+			path:         "/users/tasks?assignee=someone",
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			buf := &bytes.Buffer{}
+			if tc.payload != nil {
+				json.NewEncoder(buf).Encode(tc.payload)
+			}
+			req, _ := http.NewRequest(tc.method, tc.path, buf)
+			authenticate(t, req, tc.userId)
+			srv.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/users/tasks?assignee=me", nil)
+	authenticate(t, req, member.ID)
+	srv.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	page := struct {
+		Tasks []*model.Task `json:"tasks"`
+	}{}
+	json.NewDecoder(rec.Body).Decode(&page)
+	if assert.Len(t, page.Tasks, 1) {
+		assert.Equal(t, member.ID, *page.Tasks[0].AssigneeID)
+	}
+
+	// Leaving the project takes the member off its tasks
+	assert.NoError(t, store.Share().Delete(member.ID, 1))
+	task, _ = store.Task().GetById(owner.ID, task.ID)
+	assert.Nil(t, task.AssigneeID)
+}
+
+func TestServer_handleOrganizations(t *testing.T) {
+	store := teststore.New()
+	owner := model.TestUser(t)
+	store.User().Create(owner)
+	member := model.TestUser(t)
+	member.Email, member.Verified = "member@user.com", true
+	store.User().Create(member)
+	outsider := model.TestUser(t)
+	outsider.Email = "outsider@user.com"
+	store.User().Create(outsider)
+	org := model.TestOrganization(t)
+	store.Organization().Create(org, owner.ID)
+	invitation := model.TestInvitation(t)
+	invitation.OrganizationID, invitation.Email = org.ID, member.Email
+	store.Organization().Invite(invitation)
+	srv := testServer(t, store)
+
+	orgPath := fmt.Sprintf("/users/orgs/%d", org.ID)
+	testCases := []struct {
+		name         string
+		userId       int
+		method       string
+		path         string
+		payload      interface{}
+		expectedCode int
+	}{
+		{
+			name:         "create",
+			userId:       owner.ID,
+			method:       http.MethodPost,
+			path:         "/users/orgs",
+			payload:      map[string]string{"name": "Sales"},
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "create without name",
+			userId:       owner.ID,
+			method:       http.MethodPost,
+			path:         "/users/orgs",
+			payload:      map[string]string{"name": ""},
+			expectedCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "outsider reads",
+			userId:       outsider.ID,
+			method:       http.MethodGet,
+			path:         orgPath,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "accept an invitation of another email",
+			userId:       outsider.ID,
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/users/invitations/%s/accept", invitation.Token),
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "accept",
+			userId:       member.ID,
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/users/invitations/%s/accept", invitation.Token),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "accept again",
+			userId:       member.ID,
+			method:       http.MethodPost,
+			path:         fmt.Sprintf("/users/invitations/%s/accept", invitation.Token),
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "member reads",
+			userId:       member.ID,
+			method:       http.MethodGet,
+			path:         orgPath,
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "member lists members",
+			userId:       member.ID,
+			method:       http.MethodGet,
+			path:         orgPath + "/members",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "member invites",
+			userId:       member.ID,
+			method:       http.MethodPost,
+			path:         orgPath + "/invitations",
+			payload:      map[string]string{"email": outsider.Email, "role": "member"},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "member creates a project",
+			userId:       member.ID,
+			method:       http.MethodPost,
+			path:         orgPath + "/projects",
+			payload:      map[string]string{"name": "Roadmap"},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "owner promotes",
+			userId:       owner.ID,
+			method:       http.MethodPatch,
+			path:         fmt.Sprintf("%s/members/%d", orgPath, member.ID),
+			payload:      map[string]string{"role": "admin"},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "admin creates a project",
+			userId:       member.ID,
+			method:       http.MethodPost,
+			path:         orgPath + "/projects",
+			payload:      map[string]string{"name": "Roadmap"},
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "admin lists projects",
+			userId:       member.ID,
+			method:       http.MethodGet,
+			path:         orgPath + "/projects",
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "admin invites",
+			userId:       member.ID,
+			method:       http.MethodPost,
+			path:         orgPath + "/invitations",
+			payload:      map[string]string{"email": outsider.Email, "role": "member"},
+			expectedCode: http.StatusCreated,
+		},
+		{
+			name:         "invite a member",
+			userId:       member.ID,
+			method:       http.MethodPost,
+			path:         orgPath + "/invitations",
+			payload:      map[string]string{"email": owner.Email, "role": "member"},
+			expectedCode: http.StatusConflict,
+		},
+		{
+			name:         "admin renames",
+			userId:       member.ID,
+			method:       http.MethodPatch,
+			path:         orgPath,
+			payload:      map[string]string{"name": "Platform"},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "admin deletes",
+			userId:       member.ID,
+			method:       http.MethodDelete,
+			path:         orgPath,
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "admin changes roles",
+			userId:       member.ID,
+			method:       http.MethodPatch,
+			path:         fmt.Sprintf("%s/members/%d", orgPath, owner.ID),
+			payload:      map[string]string{"role": "member"},
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "owner leaves",
+			userId:       owner.ID,
+			method:       http.MethodDelete,
+			path:         fmt.Sprintf("%s/members/%d", orgPath, owner.ID),
+			expectedCode: http.StatusForbidden,
+		},
+		{
+			name:         "admin leaves",
+			userId:       member.ID,
+			method:       http.MethodDelete,
+			path:         fmt.Sprintf("%s/members/%d", orgPath, member.ID),
+			expectedCode: http.StatusOK,
+		},
+		{
+			name:         "left",
+			userId:       member.ID,
+			method:       http.MethodGet,
+			path:         orgPath,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "owner deletes",
+			userId:       owner.ID,
+			method:       http.MethodDelete,
+			path:         orgPath,
+			expectedCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			buf := &bytes.Buffer{}
+			if tc.payload != nil {
+				json.NewEncoder(buf).Encode(tc.payload)
+			}
+			req, _ := http.NewRequest(tc.method, tc.path, buf)
+			authenticate(t, req, tc.userId)
+			srv.ServeHTTP(rec, req)
+			assert.Equal(t, tc.expectedCode, rec.Code)
+		})
+	}
+}
+
+func TestServer_handlePasswordReset(t *testing.T) {
+	store := teststore.New()
+	user := model.TestUser(t)
+	password := user.Password
+	store.User().Create(user)
+	srv := testServer(t, store)
+	mails := srv.mailer.(*memmailer.Mailer)
+
+	post := func(path string, payload interface{}) *http.Response {
+		rec := httptest.NewRecorder()
+		buf := &bytes.Buffer{}
+		json.NewEncoder(buf).Encode(payload)
+		req, _ := http.NewRequest(http.MethodPost, path, buf)
+		srv.ServeHTTP(rec, req)
+		return rec.Result()
+	}
+	me := func(cookies []*http.Cookie) int {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/users/me", nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		srv.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	login := post("/sign-in", map[string]string{"email": user.Email, "password": password})
+	assert.Equal(t, http.StatusOK, login.StatusCode)
+	cookies := login.Cookies()
+	assert.Equal(t, http.StatusOK, me(cookies))
+
+	assert.Equal(t, http.StatusOK, post("/password/forgot", map[string]string{"email": "nobody@user.com"}).StatusCode)
+	assert.Empty(t, mails.Messages())
+
+	assert.Equal(t, http.StatusOK, post("/password/forgot", map[string]string{"email": "USER@user.com"}).StatusCode)
+	msg := mails.Last(user.Email)
+	if !assert.NotNil(t, msg) {
+		return
+	}
+	token := regexp.MustCompile("[0-9a-f]{64}").FindString(msg.Body)
+	assert.NotEmpty(t, token)
+
+	assert.Equal(t, http.StatusNotFound, post("/password/reset", map[string]string{"token": "invalid", "password": "NewPassword"}).StatusCode)
+	assert.Equal(t, http.StatusUnprocessableEntity, post("/password/reset", map[string]string{"token": token, "password": "123"}).StatusCode)
+	assert.Equal(t, http.StatusOK, post("/password/reset", map[string]string{"token": token, "password": "NewPassword"}).StatusCode)
+	assert.Equal(t, http.StatusNotFound, post("/password/reset", map[string]string{"token": token, "password": "OtherPassword"}).StatusCode)
+
+	assert.Equal(t, http.StatusUnauthorized, me(cookies))
+	assert.Equal(t, http.StatusUnauthorized, post("/sign-in", map[string]string{"email": user.Email, "password": password}).StatusCode)
+	login = post("/sign-in", map[string]string{"email": user.Email, "password": "NewPassword"})
+	assert.Equal(t, http.StatusOK, login.StatusCode)
+	assert.Equal(t, http.StatusOK, me(login.Cookies()))
+}
+
+func TestServer_handleInvitationEmail(t *testing.T) {
+	store := teststore.New()
+	owner := model.TestUser(t)
+	store.User().Create(owner)
+	member := model.TestUser(t)
+	member.Email = "member@user.com"
+	store.User().Create(member)
+	org := model.TestOrganization(t)
+	store.Organization().Create(org, owner.ID)
+	srv := testServer(t, store)
+	mails := srv.mailer.(*memmailer.Mailer)
+
+	do := func(method string, path string, userId int, payload interface{}) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		buf := &bytes.Buffer{}
+		json.NewEncoder(buf).Encode(payload)
+		req, _ := http.NewRequest(method, path, buf)
+		authenticate(t, req, userId)
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// The token goes to the invitee only
+	rec := do(http.MethodPost, fmt.Sprintf("/users/orgs/%d/invitations", org.ID), owner.ID,
+		map[string]string{"email": "Member@User.com", "role": "member"})
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "token")
+	msg := mails.Last(member.Email)
+	if !assert.NotNil(t, msg) {
+		return
+	}
+	token := regexp.MustCompile("[0-9a-f]{64}").FindString(msg.Body)
+	assert.NotEmpty(t, token)
+
+	accept := fmt.Sprintf("/users/invitations/%s/accept", token)
+	assert.Equal(t, http.StatusForbidden, do(http.MethodPost, accept, member.ID, nil).Code)
+
+	v := &model.EmailVerification{UserID: member.ID}
+	store.User().CreateVerification(v)
+	store.User().Verify(v.Token)
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, accept, member.ID, nil).Code)
+}
+
+func TestServer_handleEmailVerify(t *testing.T) {
+	store := teststore.New()
+	srv := testServer(t, store)
+	srv.requireVerified = true
+	mails := srv.mailer.(*memmailer.Mailer)
+	tokenPattern := regexp.MustCompile("[0-9a-f]{64}")
+
+	do := func(method string, path string, userId int, payload interface{}) int {
+		rec := httptest.NewRecorder()
+		buf := &bytes.Buffer{}
+		json.NewEncoder(buf).Encode(payload)
+		req, _ := http.NewRequest(method, path, buf)
+		if userId != 0 {
+			authenticate(t, req, userId)
+		}
+		srv.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusCreated, do(http.MethodPost, "/sign-up", 0, map[string]string{
+		"name": "Parviz", "email": "user@user.com", "password": "Password",
+	}))
+	user, _ := store.User().FindByEmail("user@user.com")
+	assert.False(t, user.Verified)
+	msg := mails.Last(user.Email)
+	if !assert.NotNil(t, msg) {
+		return
+	}
+	first := tokenPattern.FindString(msg.Body)
+
+	assert.Equal(t, http.StatusForbidden, do(http.MethodGet, "/users/orgs", user.ID, nil))
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/users/me", user.ID, nil))
+
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/users/verify-email/resend", user.ID, nil))
+	assert.Len(t, mails.Messages(), 2)
+	token := tokenPattern.FindString(mails.Last(user.Email).Body)
+	assert.NotEqual(t, first, token)
+
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPost, "/verify-email", 0, map[string]string{"token": first}))
+	assert.Equal(t, http.StatusOK, do(http.MethodPost, "/verify-email", 0, map[string]string{"token": token}))
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPost, "/verify-email", 0, map[string]string{"token": token}))
+
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/users/orgs", user.ID, nil))
+	assert.Equal(t, http.StatusConflict, do(http.MethodPost, "/users/verify-email/resend", user.ID, nil))
+}
+
+func TestServer_handleOrganizationTasks(t *testing.T) {
+	store := teststore.New()
+	owner := model.TestUser(t)
+	store.User().Create(owner)
+	member := model.TestUser(t)
+	member.Email, member.Verified = "member@user.com", true
+	store.User().Create(member)
+	outsider := model.TestUser(t)
+	outsider.Email = "outsider@user.com"
+	store.User().Create(outsider)
+	org := model.TestOrganization(t)
+	store.Organization().Create(org, owner.ID)
+	invitation := model.TestInvitation(t)
+	invitation.OrganizationID, invitation.Email = org.ID, member.Email
+	store.Organization().Invite(invitation)
+	store.Organization().Accept(member, invitation.Token)
+	project := model.TestProject(t)
+	project.UserID, project.OrganizationID = owner.ID, &org.ID
+	store.Project().Create(project)
+	own := model.TestTask(t)
+	own.UserID = member.ID
+	store.Task().Create(own)
+	srv := testServer(t, store)
+
+	do := func(method string, path string, userId int, payload interface{}) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		buf := &bytes.Buffer{}
+		json.NewEncoder(buf).Encode(payload)
+		req, _ := http.NewRequest(method, path, buf)
+		authenticate(t, req, userId)
+		srv.ServeHTTP(rec, req)
+		return rec
+	}
+
+	payload := map[string]interface{}{"title": "Release notes", "description": "For 2.0", "project_id": project.ID}
+	rec := do(http.MethodPost, "/users/tasks", member.ID, payload)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	task := &model.Task{}
+	json.NewDecoder(rec.Body).Decode(task)
+
+	// The other members work on it
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, fmt.Sprintf("/users/tasks/%d", task.ID), owner.ID, nil).Code)
+
+	// A member moves their task into the project
+	rec = do(http.MethodPatch, fmt.Sprintf("/users/tasks/%d", own.ID), member.ID, map[string]interface{}{"project_id": project.ID})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, do(http.MethodPost, "/users/tasks", outsider.ID, payload).Code)
+}
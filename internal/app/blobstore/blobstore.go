@@ -0,0 +1,20 @@
+package blobstore
+
+import (
+	"errors"
+	"io"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore keeps file contents under keys made of path segments joined
+// with "/", like "tasks/12/3f9a"
+type BlobStore interface {
+	// Put stores size bytes read from r under the key, replacing any blob
+	// stored there before
+	Put(key string, r io.Reader, size int64, contentType string) error
+	// Get opens the blob stored under the key, the caller closes it
+	Get(key string) (io.ReadCloser, error)
+	// Delete removes the blob, deleting a missing blob is not an error
+	Delete(key string) error
+}
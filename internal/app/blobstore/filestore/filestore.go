@@ -0,0 +1,98 @@
+package filestore
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pyuldashev912/todoapp/internal/app/blobstore"
+)
+
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// Store keeps blobs as files under a root directory
+type Store struct {
+	root string
+}
+
+// New returns a store keeping blobs under the root directory
+func New(root string) *Store {
+	return &Store{
+		root: root,
+	}
+}
+
+// Put writes the blob to a temporary file first, so a failed upload never
+// leaves a partial blob behind
+func (s *Store) Put(key string, r io.Reader, size int64, contentType string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	n, err := io.Copy(f, r)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if n != size {
+		return io.ErrUnexpectedEOF
+	}
+
+	return os.Rename(f.Name(), name)
+}
+
+func (s *Store) Get(key string) (io.ReadCloser, error) {
+	name, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, blobstore.ErrNotFound
+	}
+
+	return f, err
+}
+
+func (s *Store) Delete(key string) error {
+	name, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// path maps the key to a file under the root, keys can't point outside of it
+func (s *Store) path(key string) (string, error) {
+	if key == "" || path.IsAbs(key) || path.Clean(key) != key || strings.HasPrefix(key, "..") ||
+		strings.HasPrefix(path.Base(key), ".") {
+		return "", ErrInvalidKey
+	}
+
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,42 @@
+package filestore_test
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/pyuldashev912/todoapp/internal/app/blobstore"
+	"github.com/pyuldashev912/todoapp/internal/app/blobstore/filestore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+	s := filestore.New(t.TempDir())
+
+	assert.NoError(t, s.Put("tasks/1/a", strings.NewReader("hello"), 5, "text/plain"))
+	r, err := s.Get("tasks/1/a")
+	assert.NoError(t, err)
+	data, _ := io.ReadAll(r)
+	r.Close()
+	assert.Equal(t, "hello", string(data))
+
+	// A short upload keeps the previous blob
+	assert.Error(t, s.Put("tasks/1/a", strings.NewReader("bye"), 5, "text/plain"))
+	r, _ = s.Get("tasks/1/a")
+	data, _ = io.ReadAll(r)
+	r.Close()
+	assert.Equal(t, "hello", string(data))
+
+	assert.NoError(t, s.Delete("tasks/1/a"))
+	assert.NoError(t, s.Delete("tasks/1/a"))
+	_, err = s.Get("tasks/1/a")
+	assert.EqualError(t, err, blobstore.ErrNotFound.Error())
+}
+
+func TestStore_InvalidKey(t *testing.T) {
+	s := filestore.New(t.TempDir())
+
+	for _, key := range []string{"", "/etc/passwd", "../secret", "tasks/../../secret", "tasks//a", "tasks/.upload-1"} {
+		assert.EqualError(t, s.Put(key, strings.NewReader("x"), 1, "text/plain"), filestore.ErrInvalidKey.Error(), key)
+	}
+}
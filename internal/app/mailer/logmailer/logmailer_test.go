@@ -0,0 +1,21 @@
+package logmailer_test
+
+import (
+	"testing"
+
+	"github.com/pyuldashev912/todoapp/internal/app/mailer"
+	"github.com/pyuldashev912/todoapp/internal/app/mailer/logmailer"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMailer_Send(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	m := logmailer.New(logger)
+	assert.NoError(t, m.Send(&mailer.Message{To: "user@user.com", Subject: "Reset your password", Body: "secret-token"}))
+
+	if assert.Len(t, hook.AllEntries(), 1) {
+		assert.Contains(t, hook.LastEntry().Message, "user@user.com")
+		assert.NotContains(t, hook.LastEntry().Message, "secret-token")
+	}
+}
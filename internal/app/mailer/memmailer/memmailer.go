@@ -0,0 +1,50 @@
+package memmailer
+
+import (
+	"sync"
+
+	"github.com/pyuldashev912/todoapp/internal/app/mailer"
+)
+
+// Mailer keeps the sent emails in memory instead of delivering them, for
+// tests to read them back
+type Mailer struct {
+	mu       sync.Mutex
+	messages []*mailer.Message
+}
+
+// New returns a mailer without any sent emails
+func New() *Mailer {
+	return &Mailer{}
+}
+
+func (m *Mailer) Send(msg *mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	sent := *msg
+	m.messages = append(m.messages, &sent)
+	return nil
+}
+
+// Messages returns the emails sent so far, the oldest first
+func (m *Mailer) Messages() []*mailer.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]*mailer.Message(nil), m.messages...)
+}
+
+// Last returns the last email sent to the address, nil if there is none
+func (m *Mailer) Last(to string) *mailer.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if m.messages[i].To == to {
+			return m.messages[i]
+		}
+	}
+
+	return nil
+}
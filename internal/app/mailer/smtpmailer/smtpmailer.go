@@ -0,0 +1,64 @@
+package smtpmailer
+
+import (
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/pyuldashev912/todoapp/internal/app/mailer"
+)
+
+// Config locates an SMTP server and the sender of the emails
+type Config struct {
+	Host string
+	Port string
+	// Username and Password authenticate with PLAIN auth when Username is set
+	Username string
+	Password string
+	From     string
+}
+
+// Mailer delivers emails through an SMTP server
+type Mailer struct {
+	config Config
+	now    func() time.Time
+	// send is smtp.SendMail, replaced in tests
+	send func(addr string, auth smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// New returns a mailer sending through the configured server
+func New(config Config) *Mailer {
+	return &Mailer{
+		config: config,
+		now:    time.Now,
+		send:   smtp.SendMail,
+	}
+}
+
+func (m *Mailer) Send(msg *mailer.Message) error {
+	var auth smtp.Auth
+	if m.config.Username != "" {
+		auth = smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+	}
+
+	addr := net.JoinHostPort(m.config.Host, m.config.Port)
+	return m.send(addr, auth, m.config.From, []string{msg.To}, m.format(msg))
+}
+
+// format renders the message as a plain text email of RFC 5322
+func (m *Mailer) format(msg *mailer.Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", m.config.From)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", m.now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+
+	return []byte(b.String())
+}
@@ -0,0 +1,42 @@
+package smtpmailer
+
+import (
+	"net/smtp"
+	"testing"
+	"time"
+
+	"github.com/pyuldashev912/todoapp/internal/app/mailer"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMailer_Send(t *testing.T) {
+	var addr, from string
+	var to []string
+	var auth smtp.Auth
+	var data []byte
+
+	m := New(Config{Host: "smtp.example.com", Port: "587", Username: "todoapp", Password: "secret", From: "todoapp@example.com"})
+	m.now = func() time.Time { return time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC) }
+	m.send = func(a string, au smtp.Auth, f string, t []string, msg []byte) error {
+		addr, auth, from, to, data = a, au, f, t, msg
+		return nil
+	}
+
+	assert.NoError(t, m.Send(&mailer.Message{To: "user@example.org", Subject: "Reset your password", Body: "line 1\nline 2"}))
+	assert.Equal(t, "smtp.example.com:587", addr)
+	assert.NotNil(t, auth)
+	assert.Equal(t, "todoapp@example.com", from)
+	assert.Equal(t, []string{"user@example.org"}, to)
+	assert.Equal(t, "From: todoapp@example.com\r\n"+
+		"To: user@example.org\r\n"+
+		"Subject: Reset your password\r\n"+
+		"Date: Fri, 02 Jan 2026 15:04:05 +0000\r\n"+
+		"MIME-Version: 1.0\r\n"+
+		"Content-Type: text/plain; charset=utf-8\r\n"+
+		"\r\n"+
+		"line 1\r\nline 2", string(data))
+
+	m.config.Username = ""
+	assert.NoError(t, m.Send(&mailer.Message{To: "user@example.org"}))
+	assert.Nil(t, auth)
+}
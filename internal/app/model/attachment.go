@@ -0,0 +1,49 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+var ErrAttachmentTooLarge = errors.New("file too large, expected at most 10 MiB")
+
+// MaxAttachmentSize caps the size of attached files, 10 MiB
+const MaxAttachmentSize = 10 << 20
+
+// attachmentTypes are the accepted content types, screenshots, PDFs and plain text
+var attachmentTypes = []interface{}{
+	"image/png",
+	"image/jpeg",
+	"image/gif",
+	"image/webp",
+	"application/pdf",
+	"text/plain",
+}
+
+// Attachment is a file attached to a task. The content is kept in a blob
+// store under Key.
+type Attachment struct {
+	ID          int       `json:"id"`
+	TaskID      int       `json:"task_id"`
+	UserID      int       `json:"-"`
+	Name        string    `json:"name"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Key         string    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Validate validates Attachment fields
+func (a *Attachment) Validate() error {
+	return validation.ValidateStruct(
+		a,
+		validation.Field(&a.Name, validation.Required, validation.Length(1, 255)),
+		validation.Field(&a.ContentType, validation.Required, validation.In(attachmentTypes...).Error(
+			"must be a PNG, JPEG, GIF or WebP image, a PDF or plain text",
+		)),
+		validation.Field(&a.Size, validation.Required, validation.Max(int64(MaxAttachmentSize))),
+		validation.Field(&a.Key, validation.Required),
+	)
+}
@@ -0,0 +1,95 @@
+package model_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttachment_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		a       func() *model.Attachment
+		isValid bool
+	}{
+		{
+			name: "valid",
+			a: func() *model.Attachment {
+				return model.TestAttachment(t)
+			},
+			isValid: true,
+		},
+		{
+			name: "pdf",
+			a: func() *model.Attachment {
+				a := model.TestAttachment(t)
+				a.ContentType = "application/pdf"
+
+				return a
+			},
+			isValid: true,
+		},
+		{
+			name: "empty name",
+			a: func() *model.Attachment {
+				a := model.TestAttachment(t)
+				a.Name = ""
+
+				return a
+			},
+			isValid: false,
+		},
+		{
+			name: "long name",
+			a: func() *model.Attachment {
+				a := model.TestAttachment(t)
+				a.Name = strings.Repeat("a", 256)
+
+				return a
+			},
+			isValid: false,
+		},
+		{
+			name: "executable",
+			a: func() *model.Attachment {
+				a := model.TestAttachment(t)
+				a.ContentType = "application/octet-stream"
+
+				return a
+			},
+			isValid: false,
+		},
+		{
+			name: "empty file",
+			a: func() *model.Attachment {
+				a := model.TestAttachment(t)
+				a.Size = 0
+
+				return a
+			},
+			isValid: false,
+		},
+		{
+			name: "too large",
+			a: func() *model.Attachment {
+				a := model.TestAttachment(t)
+				a.Size = model.MaxAttachmentSize + 1
+
+				return a
+			},
+			isValid: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.isValid {
+				assert.NoError(t, tc.a().Validate())
+			} else {
+				assert.Error(t, tc.a().Validate())
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package model
+
+import (
+	"sort"
+	"time"
+)
+
+// BurndownPoint holds the estimates of the reported tasks at the end of a
+// day. Days after today only have the ideal line.
+type BurndownPoint struct {
+	Date      string  `json:"date"`
+	Remaining *int    `json:"remaining"`
+	Completed *int    `json:"completed"`
+	Ideal     float64 `json:"ideal"`
+}
+
+// taskState is what a burndown needs to know about a task at some moment
+type taskState struct {
+	exists   bool
+	deleted  bool
+	done     bool
+	estimate int
+}
+
+// Burndown computes the remaining and the completed estimate at the end of
+// every day from from to to, which are UTC dates. The history is replayed
+// backwards from the current state of the tasks, so tasks created before
+// the history was kept count from their creation. Tasks in the trash count
+// as neither.
+func Burndown(tasks []*Task, events []*TaskEvent, from, to, now time.Time) []*BurndownPoint {
+	start := from.UTC().Truncate(24 * time.Hour)
+	var points []*BurndownPoint
+	var ends []time.Time
+	for day := start; !day.After(to); day = day.Add(24 * time.Hour) {
+		points = append(points, &BurndownPoint{Date: day.Format("2006-01-02")})
+		ends = append(ends, day.Add(24*time.Hour))
+	}
+
+	if len(points) == 0 {
+		return points
+	}
+
+	byTask := map[int][]*TaskEvent{}
+	for _, e := range events {
+		byTask[e.TaskID] = append(byTask[e.TaskID], e)
+	}
+
+	remaining := make([]int, len(points))
+	completed := make([]int, len(points))
+	for _, task := range tasks {
+		history := byTask[task.ID]
+		sort.SliceStable(history, func(i, j int) bool {
+			return history[i].CreatedAt.After(history[j].CreatedAt)
+		})
+
+		state := &taskState{exists: true, deleted: task.DeletedAt != nil, done: task.Done}
+		if task.Estimate != nil {
+			state.estimate = *task.Estimate
+		}
+
+		next := 0
+		for i := len(points) - 1; i >= 0; i-- {
+			for next < len(history) && history[next].CreatedAt.After(ends[i]) {
+				state.undo(history[next])
+				next++
+			}
+
+			if !state.exists || state.deleted || task.CreatedAt.After(ends[i]) {
+				continue
+			}
+
+			if state.done {
+				completed[i] += state.estimate
+			} else {
+				remaining[i] += state.estimate
+			}
+		}
+	}
+
+	for i, p := range points {
+		if ends[i].Add(-24 * time.Hour).After(now) {
+			continue
+		}
+
+		p.Remaining, p.Completed = &remaining[i], &completed[i]
+	}
+
+	// The ideal line goes from the remaining estimate of the first day to
+	// nothing left on the last one
+	if first := points[0].Remaining; first != nil {
+		for i, p := range points {
+			p.Ideal = float64(*first)
+			if len(points) > 1 {
+				p.Ideal = float64(*first) * float64(len(points)-1-i) / float64(len(points)-1)
+			}
+		}
+	}
+
+	return points
+}
+
+// undo brings the state back to the one before the event
+func (s *taskState) undo(e *TaskEvent) {
+	switch e.Action {
+	case ActionCreate:
+		s.exists = false
+	case ActionDelete:
+		s.deleted = false
+	case ActionRestore:
+		s.deleted = true
+	}
+
+	if change, ok := e.Changes["done"]; ok {
+		if done, ok := change.From.(bool); ok {
+			s.done = done
+		}
+	}
+
+	if change, ok := e.Changes["estimate"]; ok {
+		s.estimate = estimateValue(change.From)
+	}
+}
+
+// estimateValue reads an estimate from the history, where it is a number
+// decoded from JSON or nil for a task without one
+func estimateValue(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case float64:
+		return int(n)
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,66 @@
+package model_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBurndown(t *testing.T) {
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	at := func(hours int) time.Time {
+		return day.Add(time.Duration(hours) * time.Hour)
+	}
+	estimate := func(n int) *int {
+		return &n
+	}
+
+	tasks := []*model.Task{
+		// Created before the period, completed on the second day
+		{ID: 1, Done: true, Estimate: estimate(5), CreatedAt: at(-48)},
+		// Created on the second day and re-estimated on the third
+		{ID: 2, Estimate: estimate(8), CreatedAt: at(30)},
+		// Deleted on the third day
+		{ID: 3, Estimate: estimate(2), CreatedAt: at(-48), DeletedAt: timePtr(at(50))},
+	}
+	events := []*model.TaskEvent{
+		{TaskID: 1, Action: model.ActionCreate, CreatedAt: at(-48),
+			Changes: map[string]model.Change{"estimate": {From: nil, To: float64(5)}}},
+		{TaskID: 1, Action: model.ActionDone, CreatedAt: at(40),
+			Changes: map[string]model.Change{"done": {From: false, To: true}}},
+		{TaskID: 2, Action: model.ActionCreate, CreatedAt: at(30),
+			Changes: map[string]model.Change{"estimate": {From: nil, To: 3}}},
+		{TaskID: 2, Action: model.ActionUpdate, CreatedAt: at(55),
+			Changes: map[string]model.Change{"estimate": {From: 3, To: 8}}},
+		{TaskID: 3, Action: model.ActionDelete, CreatedAt: at(50)},
+	}
+
+	points := model.Burndown(tasks, events, day, at(72), at(60))
+	assert.Len(t, points, 4)
+
+	expected := []struct {
+		date      string
+		remaining int
+		completed int
+	}{
+		{"2026-03-02", 7, 0},
+		{"2026-03-03", 5, 5},
+		{"2026-03-04", 8, 5},
+	}
+	for i, e := range expected {
+		assert.Equal(t, e.date, points[i].Date)
+		assert.Equal(t, e.remaining, *points[i].Remaining)
+		assert.Equal(t, e.completed, *points[i].Completed)
+	}
+
+	// The future only has the ideal line
+	assert.Nil(t, points[3].Remaining)
+	assert.Equal(t, 7.0, points[0].Ideal)
+	assert.Equal(t, 0.0, points[3].Ideal)
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// maxCommentLength caps the length of comment bodies
+const maxCommentLength = 10000
+
+// Comment is a note left on a task. The body is Markdown kept as written,
+// rendering it is up to clients.
+type Comment struct {
+	ID        int        `json:"id"`
+	TaskID    int        `json:"task_id"`
+	UserID    int        `json:"user_id"`
+	Body      string     `json:"body"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// Validate validates Comment fields
+func (c *Comment) Validate() error {
+	return validation.ValidateStruct(
+		c,
+		validation.Field(&c.Body, validation.Required, validation.Length(1, maxCommentLength)),
+	)
+}
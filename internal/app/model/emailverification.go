@@ -0,0 +1,31 @@
+package model
+
+import "time"
+
+// EmailVerificationTTL is how long an email verification link can be used
+const EmailVerificationTTL = 24 * time.Hour
+
+// EmailVerification confirms that a user owns the email they signed up
+// with. Only the hash of its token is stored.
+type EmailVerification struct {
+	ID     int
+	UserID int
+	// Token is only known right after the verification is created, it is
+	// emailed to the user
+	Token     string
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// BeforeCreate gives the verification a new token valid for EmailVerificationTTL
+func (v *EmailVerification) BeforeCreate(now time.Time) error {
+	token, err := NewToken()
+	if err != nil {
+		return err
+	}
+
+	v.Token, v.TokenHash = token, HashToken(token)
+	v.ExpiresAt = now.Add(EmailVerificationTTL)
+	return nil
+}
@@ -0,0 +1,18 @@
+package model_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmailVerification_BeforeCreate(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	v := &model.EmailVerification{UserID: 1}
+	assert.NoError(t, v.BeforeCreate(now))
+	assert.Len(t, v.Token, 64)
+	assert.Equal(t, model.HashToken(v.Token), v.TokenHash)
+	assert.Equal(t, now.Add(model.EmailVerificationTTL), v.ExpiresAt)
+}
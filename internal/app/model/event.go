@@ -0,0 +1,81 @@
+package model
+
+import (
+	"reflect"
+	"time"
+)
+
+// Actions recorded in the task history
+const (
+	ActionCreate  = "create"
+	ActionUpdate  = "update"
+	ActionDone    = "done"
+	ActionDelete  = "delete"
+	ActionRestore = "restore"
+)
+
+// TaskEvent is a change of a task made by a user
+type TaskEvent struct {
+	ID        int               `json:"id"`
+	TaskID    int               `json:"task_id"`
+	UserID    int               `json:"user_id"`
+	Action    string            `json:"action"`
+	Changes   map[string]Change `json:"changes,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// Change holds the old and the new value of a task field
+type Change struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// taskFields are the editable task fields tracked in the history, the values
+// are the ones seen in JSON so that a change reads the same way as the task
+var taskFields = []struct {
+	name  string
+	value func(*Task) interface{}
+}{
+	{"title", func(t *Task) interface{} { return t.Title }},
+	{"description", func(t *Task) interface{} { return t.Description }},
+	{"done", func(t *Task) interface{} { return t.Done }},
+	{"status", func(t *Task) interface{} { return t.Status }},
+	{"due_at", func(t *Task) interface{} {
+		if t.DueAt == nil {
+			return nil
+		}
+		return t.DueAt.UTC().Format(time.RFC3339Nano)
+	}},
+	{"project_id", func(t *Task) interface{} { return intOrNil(t.ProjectID) }},
+	{"parent_id", func(t *Task) interface{} { return intOrNil(t.ParentID) }},
+	{"recurrence", func(t *Task) interface{} { return t.Recurrence }},
+	{"priority", func(t *Task) interface{} { return PriorityOfLevel(t.Priority.Level()) }},
+	{"estimate", func(t *Task) interface{} { return intOrNil(t.Estimate) }},
+	{"assignee_id", func(t *Task) interface{} { return intOrNil(t.AssigneeID) }},
+}
+
+// DiffTasks returns the changed editable fields between two states of a task.
+// A nil old state diffs the task against an empty one.
+func DiffTasks(old, new *Task) map[string]Change {
+	if old == nil {
+		old = &Task{}
+	}
+
+	changes := map[string]Change{}
+	for _, field := range taskFields {
+		from, to := field.value(old), field.value(new)
+		if !reflect.DeepEqual(from, to) {
+			changes[field.name] = Change{From: from, To: to}
+		}
+	}
+
+	return changes
+}
+
+func intOrNil(i *int) interface{} {
+	if i == nil {
+		return nil
+	}
+
+	return *i
+}
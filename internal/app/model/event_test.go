@@ -0,0 +1,36 @@
+package model_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffTasks(t *testing.T) {
+	old := model.TestTask(t)
+	old.Priority = model.PriorityNone
+
+	// An empty priority is none
+	task := *old
+	task.Priority = ""
+	assert.Empty(t, model.DiffTasks(old, &task))
+
+	projectId := 3
+	dueAt := time.Date(2026, 1, 5, 9, 0, 0, 0, time.FixedZone("UTC+5", 5*60*60))
+	task.Title = "Fixed title"
+	task.ProjectID = &projectId
+	task.DueAt = &dueAt
+	task.AssigneeID = &old.UserID
+	assert.Equal(t, map[string]model.Change{
+		"title":       {From: old.Title, To: "Fixed title"},
+		"project_id":  {From: nil, To: 3},
+		"due_at":      {From: nil, To: "2026-01-05T04:00:00Z"},
+		"assignee_id": {From: nil, To: old.UserID},
+	}, model.DiffTasks(old, &task))
+
+	created := model.DiffTasks(nil, old)
+	assert.Equal(t, model.Change{From: "", To: old.Title}, created["title"])
+	assert.NotContains(t, created, "done")
+}
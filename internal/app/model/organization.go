@@ -0,0 +1,110 @@
+package model
+
+import (
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/go-ozzo/ozzo-validation/is"
+)
+
+const (
+	// OrgRoleMember sees the organization with its members and projects and
+	// works on the tasks of the projects
+	OrgRoleMember = "member"
+	// OrgRoleAdmin also invites and removes members and creates projects
+	OrgRoleAdmin = "admin"
+	// OrgRoleOwner also changes the roles and deletes the organization
+	OrgRoleOwner = "owner"
+)
+
+// InvitationTTL is how long an invitation can be accepted
+const InvitationTTL = 7 * 24 * time.Hour
+
+// orgRoleLevels orders the roles, a role has the permissions of the lower ones
+var orgRoleLevels = map[string]int{
+	OrgRoleMember: 1,
+	OrgRoleAdmin:  2,
+	OrgRoleOwner:  3,
+}
+
+// Organization groups users working on shared projects
+type Organization struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	// Role is the one the current user has in the organization
+	Role      string    `json:"role,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate validates Organization fields
+func (o *Organization) Validate() error {
+	return validation.ValidateStruct(
+		o,
+		validation.Field(&o.Name, validation.Required, validation.Length(1, 100)),
+	)
+}
+
+// Allows tells whether the role of the current user has the permissions of the required one
+func (o *Organization) Allows(required string) bool {
+	return orgRoleLevels[o.Role] >= orgRoleLevels[required]
+}
+
+// Member is a user of an organization
+type Member struct {
+	OrganizationID int       `json:"organization_id"`
+	UserID         int       `json:"user_id"`
+	Email          string    `json:"email"`
+	Role           string    `json:"role"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Validate validates the role given to a member, an organization only gets
+// its owner when it is created
+func (m *Member) Validate() error {
+	return validation.ValidateStruct(
+		m,
+		validation.Field(&m.UserID, validation.Required),
+		validation.Field(&m.Role, validation.Required, validation.In(OrgRoleMember, OrgRoleAdmin).Error(
+			"must be member or admin",
+		)),
+	)
+}
+
+// Invitation lets the user with the email join an organization. Only the
+// hash of its token is stored.
+type Invitation struct {
+	ID             int    `json:"id"`
+	OrganizationID int    `json:"organization_id"`
+	Email          string `json:"email"`
+	Role           string `json:"role"`
+	// Token is only known right after the invitation is created, it is
+	// emailed to the invitee
+	Token     string    `json:"-"`
+	TokenHash string    `json:"-"`
+	InvitedBy int       `json:"invited_by"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Validate validates Invitation fields
+func (i *Invitation) Validate() error {
+	return validation.ValidateStruct(
+		i,
+		validation.Field(&i.Email, validation.Required, is.Email),
+		validation.Field(&i.Role, validation.Required, validation.In(OrgRoleMember, OrgRoleAdmin).Error(
+			"must be member or admin",
+		)),
+	)
+}
+
+// BeforeCreate gives the invitation a new token valid for InvitationTTL
+func (i *Invitation) BeforeCreate(now time.Time) error {
+	token, err := NewToken()
+	if err != nil {
+		return err
+	}
+
+	i.Token, i.TokenHash = token, HashToken(token)
+	i.ExpiresAt = now.Add(InvitationTTL)
+	return nil
+}
@@ -0,0 +1,85 @@
+package model_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrganization_Allows(t *testing.T) {
+	org := model.TestOrganization(t)
+	assert.False(t, org.Allows(model.OrgRoleMember))
+
+	org.Role = model.OrgRoleAdmin
+	assert.True(t, org.Allows(model.OrgRoleMember))
+	assert.True(t, org.Allows(model.OrgRoleAdmin))
+	assert.False(t, org.Allows(model.OrgRoleOwner))
+}
+
+func TestInvitation_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		i       func() *model.Invitation
+		isValid bool
+	}{
+		{
+			name: "valid",
+			i: func() *model.Invitation {
+				return model.TestInvitation(t)
+			},
+			isValid: true,
+		},
+		{
+			name: "admin",
+			i: func() *model.Invitation {
+				i := model.TestInvitation(t)
+				i.Role = model.OrgRoleAdmin
+
+				return i
+			},
+			isValid: true,
+		},
+		{
+			name: "owner",
+			i: func() *model.Invitation {
+				i := model.TestInvitation(t)
+				i.Role = model.OrgRoleOwner
+
+				return i
+			},
+			isValid: false,
+		},
+		{
+			name: "invalid email",
+			i: func() *model.Invitation {
+				i := model.TestInvitation(t)
+				i.Email = "member"
+
+				return i
+			},
+			isValid: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.isValid {
+				assert.NoError(t, tc.i().Validate())
+			} else {
+				assert.Error(t, tc.i().Validate())
+			}
+		})
+	}
+}
+
+func TestInvitation_BeforeCreate(t *testing.T) {
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	i := model.TestInvitation(t)
+	assert.NoError(t, i.BeforeCreate(now))
+	assert.Len(t, i.Token, 64)
+	assert.Equal(t, model.HashToken(i.Token), i.TokenHash)
+	assert.NotEqual(t, i.Token, i.TokenHash)
+	assert.Equal(t, now.Add(model.InvitationTTL), i.ExpiresAt)
+}
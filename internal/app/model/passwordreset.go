@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// PasswordResetTTL is how long a password reset token can be used
+const PasswordResetTTL = time.Hour
+
+// PasswordReset lets a user who forgot their password set a new one. Only
+// the hash of its token is stored and the token is used once.
+type PasswordReset struct {
+	ID     int
+	UserID int
+	// Token is only known right after the reset is created, it is emailed to the user
+	Token     string
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// BeforeCreate gives the reset a new token valid for PasswordResetTTL
+func (p *PasswordReset) BeforeCreate(now time.Time) error {
+	token, err := NewToken()
+	if err != nil {
+		return err
+	}
+
+	p.Token, p.TokenHash = token, HashToken(token)
+	p.ExpiresAt = now.Add(PasswordResetTTL)
+	return nil
+}
@@ -0,0 +1,22 @@
+package model_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordReset_BeforeCreate(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	p := &model.PasswordReset{UserID: 1}
+	assert.NoError(t, p.BeforeCreate(now))
+	assert.Len(t, p.Token, 64)
+	assert.Equal(t, model.HashToken(p.Token), p.TokenHash)
+	assert.Equal(t, now.Add(model.PasswordResetTTL), p.ExpiresAt)
+
+	other := &model.PasswordReset{UserID: 1}
+	assert.NoError(t, other.BeforeCreate(now))
+	assert.NotEqual(t, p.Token, other.Token)
+}
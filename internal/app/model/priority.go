@@ -0,0 +1,41 @@
+package model
+
+// Priority tells how important a task is
+type Priority string
+
+// Priorities from the least to the most important
+const (
+	PriorityNone   Priority = "none"
+	PriorityLow    Priority = "low"
+	PriorityMedium Priority = "medium"
+	PriorityHigh   Priority = "high"
+	PriorityUrgent Priority = "urgent"
+)
+
+// priorities are indexed by their level
+var priorities = []Priority{PriorityNone, PriorityLow, PriorityMedium, PriorityHigh, PriorityUrgent}
+
+// Level returns the position of the priority from 0 for none to 4 for urgent.
+// An empty priority is none, an unknown one is -1.
+func (p Priority) Level() int {
+	if p == "" {
+		return 0
+	}
+
+	for level, priority := range priorities {
+		if priority == p {
+			return level
+		}
+	}
+
+	return -1
+}
+
+// PriorityOfLevel is the inverse of Priority.Level
+func PriorityOfLevel(level int) Priority {
+	if level < 0 || level >= len(priorities) {
+		return ""
+	}
+
+	return priorities[level]
+}
@@ -0,0 +1,24 @@
+package model
+
+import (
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+type Project struct {
+	ID             int       `json:"id"`
+	UserID         int       `json:"-"`
+	Name           string    `json:"name"`
+	Description    string    `json:"description"`
+	OrganizationID *int      `json:"organization_id,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Validate validates Project fields
+func (p *Project) Validate() error {
+	return validation.ValidateStruct(
+		p,
+		validation.Field(&p.Name, validation.Required, validation.Length(1, 100)),
+	)
+}
@@ -0,0 +1,65 @@
+package model_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProject_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		p       func() *model.Project
+		isValid bool
+	}{
+		{
+			name: "valid",
+			p: func() *model.Project {
+				return model.TestProject(t)
+			},
+			isValid: true,
+		},
+		{
+			name: "empty description",
+			p: func() *model.Project {
+				p := model.TestProject(t)
+				p.Description = ""
+
+				return p
+			},
+			isValid: true,
+		},
+		{
+			name: "empty name",
+			p: func() *model.Project {
+				p := model.TestProject(t)
+				p.Name = ""
+
+				return p
+			},
+			isValid: false,
+		},
+		{
+			name: "long name",
+			p: func() *model.Project {
+				p := model.TestProject(t)
+				p.Name = strings.Repeat("a", 101)
+
+				return p
+			},
+			isValid: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.isValid {
+				assert.NoError(t, tc.p().Validate())
+			} else {
+				assert.Error(t, tc.p().Validate())
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"errors"
+	"strings"
+)
+
+// Ranks are strings of base 36 digits ordered byte by byte. A rank can
+// always be squeezed between two others, so moving a task only changes the
+// rank of the moved task.
+const (
+	rankDigits = "0123456789abcdefghijklmnopqrstuvwxyz"
+	rankBase   = len(rankDigits)
+	// rankWidth is the width of ranks given to appended tasks
+	rankWidth = 6
+)
+
+var ErrNoRankBetween = errors.New("no rank between the given ranks")
+
+// RankAfter returns a rank following prev for a task appended to the end
+// of the list. An empty prev stands for an empty list.
+func RankAfter(prev string) string {
+	// Appending increments a fixed width number instead of halving the gap
+	// to the end, which would make ranks grow with every appended task
+	digits := []byte(prev + strings.Repeat("0", rankWidth))[:rankWidth]
+	for i := rankWidth - 1; i >= 0; i-- {
+		d := strings.IndexByte(rankDigits, digits[i])
+		if d >= 0 && d < rankBase-1 {
+			digits[i] = rankDigits[d+1]
+			return string(digits)
+		}
+		digits[i] = '0'
+	}
+
+	// Every rank of the fixed width is taken, fall back to longer ones
+	rank, _ := RankBetween(prev, "")
+	return rank
+}
+
+// RankBetween returns a rank ordered after prev and before next. An empty
+// prev stands for the start of the list and an empty next for its end.
+func RankBetween(prev, next string) (string, error) {
+	if next != "" && prev >= next {
+		return "", ErrNoRankBetween
+	}
+
+	var rank []byte
+	bounded := next != ""
+	for i := 0; ; i++ {
+		lo := 0
+		if i < len(prev) {
+			lo = strings.IndexByte(rankDigits, prev[i])
+		}
+
+		hi := rankBase
+		if bounded {
+			if i >= len(next) {
+				return "", ErrNoRankBetween
+			}
+			hi = strings.IndexByte(rankDigits, next[i])
+		}
+
+		if lo < 0 || hi < 0 {
+			return "", ErrNoRankBetween
+		}
+
+		switch {
+		case hi-lo > 1:
+			return string(append(rank, rankDigits[(lo+hi)/2])), nil
+		case hi-lo == 1:
+			// Any rank starting with this digit is before next
+			bounded = false
+		}
+
+		rank = append(rank, rankDigits[lo])
+	}
+}
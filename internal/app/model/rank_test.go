@@ -0,0 +1,72 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRankAfter(t *testing.T) {
+	testCases := []struct {
+		prev     string
+		expected string
+	}{
+		{"", "000001"},
+		{"000001", "000002"},
+		{"00000z", "000010"},
+		{"000001i", "000002"},
+		{"zzzzzz", "zzzzzzi"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.prev, func(t *testing.T) {
+			rank := model.RankAfter(tc.prev)
+			assert.Equal(t, tc.expected, rank)
+			assert.True(t, rank > tc.prev)
+		})
+	}
+}
+
+func TestRankBetween(t *testing.T) {
+	testCases := []struct {
+		name    string
+		prev    string
+		next    string
+		isValid bool
+	}{
+		{"start of the list", "", "000001", true},
+		{"end of the list", "000001", "", true},
+		{"wide gap", "000001", "000009", true},
+		{"adjacent", "000001", "000002", true},
+		{"prefix", "00000z", "000010", true},
+		{"equal", "000001", "000001", false},
+		{"reversed", "000002", "000001", false},
+		{"nothing before", "", "0", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rank, err := model.RankBetween(tc.prev, tc.next)
+			if !tc.isValid {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.True(t, rank > tc.prev)
+			if tc.next != "" {
+				assert.True(t, rank < tc.next)
+			}
+		})
+	}
+
+	// Repeatedly inserting at the same place keeps the order
+	prev, next := "000001", "000002"
+	for i := 0; i < 100; i++ {
+		rank, err := model.RankBetween(prev, next)
+		assert.NoError(t, err)
+		assert.True(t, prev < rank && rank < next)
+		next = rank
+	}
+}
@@ -0,0 +1,194 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence frequencies
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+)
+
+var ErrInvalidRecurrence = errors.New("invalid recurrence rule")
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// Recurrence is the supported subset of an RFC 5545 RRULE:
+// FREQ=DAILY|WEEKLY|MONTHLY with INTERVAL, BYDAY (weekly), BYMONTHDAY (monthly), COUNT and UNTIL.
+type Recurrence struct {
+	Freq     string
+	Interval int
+	ByDay    []time.Weekday
+	MonthDay int
+	Count    int
+	Until    *time.Time
+}
+
+// ParseRecurrence parses an RRULE such as "FREQ=WEEKLY;BYDAY=MO,FR".
+// The shorthands "daily", "weekly" and "monthly" are accepted as well.
+func ParseRecurrence(rule string) (*Recurrence, error) {
+	rule = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(rule)), "RRULE:")
+	switch rule {
+	case FreqDaily, FreqWeekly, FreqMonthly:
+		return &Recurrence{Freq: rule, Interval: 1}, nil
+	}
+
+	r := &Recurrence{Interval: 1}
+	for _, part := range strings.Split(rule, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: %q is not KEY=VALUE", ErrInvalidRecurrence, part)
+		}
+
+		var err error
+		switch key {
+		case "FREQ":
+			r.Freq = value
+		case "INTERVAL":
+			r.Interval, err = positive(value)
+		case "COUNT":
+			r.Count, err = positive(value)
+		case "BYMONTHDAY":
+			r.MonthDay, err = positive(value)
+			if err == nil && r.MonthDay > 31 {
+				err = errors.New("day out of range")
+			}
+		case "BYDAY":
+			for _, name := range strings.Split(value, ",") {
+				day, ok := weekdays[name]
+				if !ok {
+					err = fmt.Errorf("unknown weekday %q", name)
+					break
+				}
+
+				r.ByDay = append(r.ByDay, day)
+			}
+		case "UNTIL":
+			var until time.Time
+			if until, err = time.Parse("20060102T150405Z", value); err != nil {
+				until, err = time.Parse("20060102", value)
+			}
+
+			r.Until = &until
+		default:
+			err = errors.New("unsupported key")
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrInvalidRecurrence, key, err)
+		}
+	}
+
+	switch {
+	case r.Freq != FreqDaily && r.Freq != FreqWeekly && r.Freq != FreqMonthly:
+		return nil, fmt.Errorf("%w: FREQ must be DAILY, WEEKLY or MONTHLY", ErrInvalidRecurrence)
+	case len(r.ByDay) > 0 && r.Freq != FreqWeekly:
+		return nil, fmt.Errorf("%w: BYDAY is only supported with FREQ=WEEKLY", ErrInvalidRecurrence)
+	case r.MonthDay > 0 && r.Freq != FreqMonthly:
+		return nil, fmt.Errorf("%w: BYMONTHDAY is only supported with FREQ=MONTHLY", ErrInvalidRecurrence)
+	case r.Count > 0 && r.Until != nil:
+		return nil, fmt.Errorf("%w: COUNT and UNTIL are mutually exclusive", ErrInvalidRecurrence)
+	}
+
+	sort.Slice(r.ByDay, func(i, j int) bool {
+		return mondayIndex(r.ByDay[i]) < mondayIndex(r.ByDay[j])
+	})
+
+	return r, nil
+}
+
+// String returns the rule in RRULE form
+func (r *Recurrence) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+
+	if len(r.ByDay) > 0 {
+		names := make([]string, 0, len(r.ByDay))
+		for _, day := range r.ByDay {
+			names = append(names, strings.ToUpper(day.String()[:2]))
+		}
+
+		parts = append(parts, "BYDAY="+strings.Join(names, ","))
+	}
+
+	if r.MonthDay > 0 {
+		parts = append(parts, "BYMONTHDAY="+strconv.Itoa(r.MonthDay))
+	}
+
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+
+	return strings.Join(parts, ";")
+}
+
+// Next returns the first occurrence after prev. It keeps the time of day of prev.
+// Monthly occurrences on days missing in a month fall on its last day.
+func (r *Recurrence) Next(prev time.Time) time.Time {
+	switch r.Freq {
+	case FreqWeekly:
+		if len(r.ByDay) == 0 {
+			return prev.AddDate(0, 0, 7*r.Interval)
+		}
+
+		current := mondayIndex(prev.Weekday())
+		for _, day := range r.ByDay {
+			if index := mondayIndex(day); index > current {
+				return prev.AddDate(0, 0, index-current)
+			}
+		}
+
+		// Weeks start on Monday
+		monday := prev.AddDate(0, 0, -current)
+		return monday.AddDate(0, 0, 7*r.Interval+mondayIndex(r.ByDay[0]))
+	case FreqMonthly:
+		day := r.MonthDay
+		if day == 0 {
+			day = prev.Day()
+		}
+
+		first := time.Date(prev.Year(), prev.Month()+time.Month(r.Interval), 1,
+			prev.Hour(), prev.Minute(), prev.Second(), prev.Nanosecond(), prev.Location())
+		if last := first.AddDate(0, 1, -1).Day(); day > last {
+			day = last
+		}
+
+		return first.AddDate(0, 0, day-1)
+	default:
+		return prev.AddDate(0, 0, r.Interval)
+	}
+}
+
+func mondayIndex(day time.Weekday) int {
+	return (int(day) + 6) % 7
+}
+
+func positive(value string) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, errors.New("expected a positive number")
+	}
+
+	return n, nil
+}
@@ -0,0 +1,143 @@
+package model_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	testCases := []struct {
+		name     string
+		rule     string
+		expected string
+		isValid  bool
+	}{
+		{
+			name:     "shorthand",
+			rule:     "weekly",
+			expected: "FREQ=WEEKLY",
+			isValid:  true,
+		},
+		{
+			name:     "full rule",
+			rule:     "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=FR,MO;COUNT=5",
+			expected: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR;COUNT=5",
+			isValid:  true,
+		},
+		{
+			name:     "until",
+			rule:     "FREQ=MONTHLY;BYMONTHDAY=31;UNTIL=20261231",
+			expected: "FREQ=MONTHLY;BYMONTHDAY=31;UNTIL=20261231T000000Z",
+			isValid:  true,
+		},
+		{
+			name:    "unsupported frequency",
+			rule:    "FREQ=YEARLY",
+			isValid: false,
+		},
+		{
+			name:    "byday with daily",
+			rule:    "FREQ=DAILY;BYDAY=MO",
+			isValid: false,
+		},
+		{
+			name:    "unknown weekday",
+			rule:    "FREQ=WEEKLY;BYDAY=XX",
+			isValid: false,
+		},
+		{
+			name:    "count and until",
+			rule:    "FREQ=DAILY;COUNT=2;UNTIL=20261231",
+			isValid: false,
+		},
+		{
+			name:    "zero interval",
+			rule:    "FREQ=DAILY;INTERVAL=0",
+			isValid: false,
+		},
+		{
+			name:    "unsupported key",
+			rule:    "FREQ=DAILY;BYHOUR=5",
+			isValid: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := model.ParseRecurrence(tc.rule)
+			if !tc.isValid {
+				assert.ErrorIs(t, err, model.ErrInvalidRecurrence)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, r.String())
+		})
+	}
+}
+
+func TestRecurrence_Next(t *testing.T) {
+	// Wednesday
+	wed := time.Date(2026, 1, 7, 9, 30, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		rule     string
+		prev     time.Time
+		expected time.Time
+	}{
+		{
+			name:     "daily",
+			rule:     "FREQ=DAILY;INTERVAL=3",
+			prev:     wed,
+			expected: time.Date(2026, 1, 10, 9, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "weekly",
+			rule:     "FREQ=WEEKLY",
+			prev:     wed,
+			expected: time.Date(2026, 1, 14, 9, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "weekly by day in the same week",
+			rule:     "FREQ=WEEKLY;BYDAY=MO,FR",
+			prev:     wed,
+			expected: time.Date(2026, 1, 9, 9, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "weekly by day in a following week",
+			rule:     "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TU",
+			prev:     wed,
+			expected: time.Date(2026, 1, 19, 9, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "monthly",
+			rule:     "FREQ=MONTHLY",
+			prev:     wed,
+			expected: time.Date(2026, 2, 7, 9, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "monthly on a day missing in the month",
+			rule:     "FREQ=MONTHLY;BYMONTHDAY=31",
+			prev:     time.Date(2026, 1, 31, 9, 30, 0, 0, time.UTC),
+			expected: time.Date(2026, 2, 28, 9, 30, 0, 0, time.UTC),
+		},
+		{
+			name:     "monthly back on the day",
+			rule:     "FREQ=MONTHLY;BYMONTHDAY=31",
+			prev:     time.Date(2026, 2, 28, 9, 30, 0, 0, time.UTC),
+			expected: time.Date(2026, 3, 31, 9, 30, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, err := model.ParseRecurrence(tc.rule)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, r.Next(tc.prev))
+		})
+	}
+}
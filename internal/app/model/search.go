@@ -0,0 +1,11 @@
+package model
+
+// TaskMatch is a task found by a full-text search
+type TaskMatch struct {
+	Task *Task `json:"task"`
+	// Rank is higher for more relevant tasks, matches in the title weigh more
+	Rank float64 `json:"rank"`
+	// Snippet is a fragment of the task text as HTML, escaped and with the
+	// matched words wrapped in <b></b>
+	Snippet string `json:"snippet"`
+}
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+const (
+	// RoleViewer can see a shared task with its comments, attachments and history
+	RoleViewer = "viewer"
+	// RoleEditor can also change and complete it, comment and attach files
+	RoleEditor = "editor"
+)
+
+var ErrInvalidShareTarget = errors.New("must share either a task or a project")
+
+// Share gives another user access to a task or to all tasks of a project
+type Share struct {
+	ID        int    `json:"id"`
+	OwnerID   int    `json:"owner_id"`
+	UserID    int    `json:"user_id"`
+	Email     string `json:"email"`
+	TaskID    *int   `json:"task_id,omitempty"`
+	ProjectID *int   `json:"project_id,omitempty"`
+	Role      string `json:"role"`
+	// Title and SharedBy describe the shared item to the user it is shared with
+	Title     string    `json:"title,omitempty"`
+	SharedBy  string    `json:"shared_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+var shareRoleRules = []validation.Rule{
+	validation.Required,
+	validation.In(RoleViewer, RoleEditor).Error("must be viewer or editor"),
+}
+
+// Validate validates Share fields
+func (s *Share) Validate() error {
+	return validation.ValidateStruct(
+		s,
+		validation.Field(&s.UserID, validation.Required),
+		validation.Field(&s.Role, shareRoleRules...),
+		validation.Field(&s.TaskID, validation.By(s.validateTarget)),
+	)
+}
+
+// ValidateRole validates the role alone, before the user is known
+func (s *Share) ValidateRole() error {
+	return validation.ValidateStruct(s, validation.Field(&s.Role, shareRoleRules...))
+}
+
+func (s *Share) validateTarget(value interface{}) error {
+	if (s.TaskID == nil) == (s.ProjectID == nil) {
+		return ErrInvalidShareTarget
+	}
+
+	return nil
+}
+
+// CanEdit tells whether the share lets its user change the shared tasks
+func (s *Share) CanEdit() bool {
+	return s.Role == RoleEditor
+}
@@ -0,0 +1,87 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShare_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		s       func() *model.Share
+		isValid bool
+	}{
+		{
+			name: "valid",
+			s: func() *model.Share {
+				return model.TestShare(t)
+			},
+			isValid: true,
+		},
+		{
+			name: "project",
+			s: func() *model.Share {
+				s := model.TestShare(t)
+				projectId := 1
+				s.TaskID, s.ProjectID = nil, &projectId
+				s.Role = model.RoleEditor
+
+				return s
+			},
+			isValid: true,
+		},
+		{
+			name: "unknown role",
+			s: func() *model.Share {
+				s := model.TestShare(t)
+				s.Role = "admin"
+
+				return s
+			},
+			isValid: false,
+		},
+		{
+			name: "no user",
+			s: func() *model.Share {
+				s := model.TestShare(t)
+				s.UserID = 0
+
+				return s
+			},
+			isValid: false,
+		},
+		{
+			name: "no target",
+			s: func() *model.Share {
+				s := model.TestShare(t)
+				s.TaskID = nil
+
+				return s
+			},
+			isValid: false,
+		},
+		{
+			name: "both targets",
+			s: func() *model.Share {
+				s := model.TestShare(t)
+				projectId := 1
+				s.ProjectID = &projectId
+
+				return s
+			},
+			isValid: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.isValid {
+				assert.NoError(t, tc.s().Validate())
+			} else {
+				assert.Error(t, tc.s().Validate())
+			}
+		})
+	}
+}
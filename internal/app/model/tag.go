@@ -0,0 +1,26 @@
+package model
+
+import (
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+type Tag struct {
+	ID     int    `json:"id"`
+	UserID int    `json:"-"`
+	Name   string `json:"name"`
+}
+
+// Validate validates Tag fields
+func (t *Tag) Validate() error {
+	return validation.ValidateStruct(
+		t,
+		validation.Field(&t.Name, validation.Required, validation.Length(1, 30)),
+	)
+}
+
+// Normalize trims and lowercases the tag name so "Bug" and "bug " are the same tag
+func (t *Tag) Normalize() {
+	t.Name = strings.ToLower(strings.TrimSpace(t.Name))
+}
@@ -0,0 +1,62 @@
+package model_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTag_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		t       func() *model.Tag
+		isValid bool
+	}{
+		{
+			name: "valid",
+			t: func() *model.Tag {
+				return model.TestTag(t)
+			},
+			isValid: true,
+		},
+		{
+			name: "empty name",
+			t: func() *model.Tag {
+				tag := model.TestTag(t)
+				tag.Name = ""
+
+				return tag
+			},
+			isValid: false,
+		},
+		{
+			name: "long name",
+			t: func() *model.Tag {
+				tag := model.TestTag(t)
+				tag.Name = strings.Repeat("a", 31)
+
+				return tag
+			},
+			isValid: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.isValid {
+				assert.NoError(t, tc.t().Validate())
+			} else {
+				assert.Error(t, tc.t().Validate())
+			}
+		})
+	}
+}
+
+func TestTag_Normalize(t *testing.T) {
+	tag := model.TestTag(t)
+	tag.Name = " Bug "
+	tag.Normalize()
+	assert.Equal(t, "bug", tag.Name)
+}
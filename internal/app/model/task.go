@@ -1,16 +1,47 @@
 package model
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
 	validation "github.com/go-ozzo/ozzo-validation"
 )
 
+// maxEstimate caps task estimates, a year in minutes
+const maxEstimate = 525600
+
 type Task struct {
-	ID           int    `json:"id"`
-	UserID       int    `json:"-"`
-	Title        string `json:"title"`
-	Description  string `json:"description"`
-	Done         bool   `json:"done"`
-	CreationDate string `json:"creation_date"`
+	ID           int        `json:"id"`
+	UserID       int        `json:"-"`
+	Title        string     `json:"title"`
+	Description  string     `json:"description"`
+	Done         bool       `json:"done"`
+	Status       string     `json:"status"`
+	CreationDate string     `json:"creation_date"`
+	DueAt        *time.Time `json:"due_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	Tags         []string   `json:"tags,omitempty"`
+	ProjectID    *int       `json:"project_id,omitempty"`
+	ParentID     *int       `json:"parent_id,omitempty"`
+	BlockedBy    []int      `json:"blocked_by,omitempty"`
+	Blocked      bool       `json:"blocked"`
+	Progress     *Progress  `json:"progress,omitempty"`
+	CommentCount int        `json:"comment_count"`
+	Recurrence   string     `json:"recurrence,omitempty"`
+	SeriesID     *int       `json:"series_id,omitempty"`
+	Priority     Priority   `json:"priority"`
+	Estimate     *int       `json:"estimate,omitempty"`
+	AssigneeID   *int       `json:"assignee_id,omitempty"`
+	Rank         string     `json:"rank"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Progress counts the completed direct subtasks of a task
+type Progress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
 }
 
 func (t *Task) Validate() error {
@@ -18,5 +49,134 @@ func (t *Task) Validate() error {
 		t,
 		validation.Field(&t.Title, validation.Required),
 		validation.Field(&t.Description, validation.Required),
+		validation.Field(&t.Recurrence, validation.By(t.validateRecurrence)),
+		validation.Field(&t.Priority, validation.In(
+			PriorityNone, PriorityLow, PriorityMedium, PriorityHigh, PriorityUrgent,
+		)),
+		validation.Field(&t.Estimate, validation.Min(0), validation.Max(maxEstimate)),
 	)
 }
+
+func (t *Task) validateRecurrence(value interface{}) error {
+	if t.Recurrence == "" {
+		return nil
+	}
+
+	if _, err := ParseRecurrence(t.Recurrence); err != nil {
+		return err
+	}
+
+	if t.DueAt == nil {
+		return errors.New("a recurring task needs a due date")
+	}
+
+	return nil
+}
+
+// Series returns the id of the recurring series of the task. The first
+// occurrence of a series has no SeriesID, its own id identifies the series.
+func (t *Task) Series() int {
+	if t.SeriesID != nil {
+		return *t.SeriesID
+	}
+
+	return t.ID
+}
+
+// NextOccurrence returns the task that follows t in its recurring series,
+// or nil if t is not recurring or is the last occurrence of the series
+func (t *Task) NextOccurrence() (*Task, error) {
+	if t.Recurrence == "" || t.DueAt == nil {
+		return nil, nil
+	}
+
+	rule, err := ParseRecurrence(t.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+
+	if rule.Count == 1 {
+		return nil, nil
+	}
+
+	// Pin the day of month so that a series on the 31st returns to it after shorter months
+	if rule.Freq == FreqMonthly && rule.MonthDay == 0 {
+		rule.MonthDay = t.DueAt.Day()
+	}
+
+	dueAt := rule.Next(*t.DueAt)
+	if rule.Until != nil && dueAt.After(*rule.Until) {
+		return nil, nil
+	}
+
+	if rule.Count > 1 {
+		rule.Count--
+	}
+
+	seriesId := t.Series()
+	return &Task{
+		UserID:       t.UserID,
+		Title:        t.Title,
+		Description:  t.Description,
+		CreationDate: time.Now().Format("02/01/06"),
+		DueAt:        &dueAt,
+		Tags:         append([]string(nil), t.Tags...),
+		ProjectID:    t.ProjectID,
+		ParentID:     t.ParentID,
+		Recurrence:   rule.String(),
+		SeriesID:     &seriesId,
+		Priority:     t.Priority,
+		Estimate:     t.Estimate,
+		AssigneeID:   t.AssigneeID,
+	}, nil
+}
+
+// IsOverdue reports whether the task is not completed and its due date has passed
+func (t *Task) IsOverdue(now time.Time) bool {
+	return !t.Done && t.DueAt != nil && t.DueAt.Before(now)
+}
+
+// Patch applies a JSON merge patch (RFC 7396) to the editable task fields.
+// A null value clears the field, absent fields are left untouched.
+func (t *Task) Patch(data []byte) error {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	for name, value := range fields {
+		var dest interface{}
+		switch name {
+		case "title":
+			t.Title, dest = "", &t.Title
+		case "description":
+			t.Description, dest = "", &t.Description
+		case "done":
+			t.Done, dest = false, &t.Done
+		case "status":
+			t.Status, dest = "", &t.Status
+		case "due_at":
+			t.DueAt, dest = nil, &t.DueAt
+		case "project_id":
+			t.ProjectID, dest = nil, &t.ProjectID
+		case "parent_id":
+			t.ParentID, dest = nil, &t.ParentID
+		case "recurrence":
+			t.Recurrence, dest = "", &t.Recurrence
+		case "priority":
+			t.Priority, dest = PriorityNone, &t.Priority
+		case "estimate":
+			t.Estimate, dest = nil, &t.Estimate
+		case "assignee_id":
+			t.AssigneeID, dest = nil, &t.AssigneeID
+		default:
+			return fmt.Errorf("field %q cannot be changed", name)
+		}
+
+		if err := json.Unmarshal(value, dest); err != nil {
+			return fmt.Errorf("field %q: %w", name, err)
+		}
+	}
+
+	return nil
+}
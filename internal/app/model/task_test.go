@@ -0,0 +1,246 @@
+package model_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTask_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		t       func() *model.Task
+		isValid bool
+	}{
+		{
+			name: "valid",
+			t: func() *model.Task {
+				return model.TestTask(t)
+			},
+			isValid: true,
+		},
+		{
+			name: "empty title",
+			t: func() *model.Task {
+				task := model.TestTask(t)
+				task.Title = ""
+
+				return task
+			},
+			isValid: false,
+		},
+		{
+			name: "empty description",
+			t: func() *model.Task {
+				task := model.TestTask(t)
+				task.Description = ""
+
+				return task
+			},
+			isValid: false,
+		},
+		{
+			name: "priority",
+			t: func() *model.Task {
+				task := model.TestTask(t)
+				task.Priority = model.PriorityUrgent
+
+				return task
+			},
+			isValid: true,
+		},
+		{
+			name: "unknown priority",
+			t: func() *model.Task {
+				task := model.TestTask(t)
+				task.Priority = "critical"
+
+				return task
+			},
+			isValid: false,
+		},
+		{
+			name: "estimate",
+			t: func() *model.Task {
+				task := model.TestTask(t)
+				estimate := 8
+				task.Estimate = &estimate
+
+				return task
+			},
+			isValid: true,
+		},
+		{
+			name: "negative estimate",
+			t: func() *model.Task {
+				task := model.TestTask(t)
+				estimate := -1
+				task.Estimate = &estimate
+
+				return task
+			},
+			isValid: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.isValid {
+				assert.NoError(t, tc.t().Validate())
+			} else {
+				assert.Error(t, tc.t().Validate())
+			}
+		})
+	}
+}
+
+func TestTask_IsOverdue(t *testing.T) {
+	now := time.Now()
+	task := model.TestTask(t)
+	assert.False(t, task.IsOverdue(now))
+
+	yesterday := now.Add(-24 * time.Hour)
+	task.DueAt = &yesterday
+	assert.True(t, task.IsOverdue(now))
+
+	task.Done = true
+	assert.False(t, task.IsOverdue(now))
+}
+
+func TestTask_Patch(t *testing.T) {
+	testCases := []struct {
+		name    string
+		patch   string
+		check   func(*testing.T, *model.Task)
+		isValid bool
+	}{
+		{
+			name:  "title only",
+			patch: `{"title": "Fixed typo"}`,
+			check: func(t *testing.T, task *model.Task) {
+				assert.Equal(t, "Fixed typo", task.Title)
+				assert.Equal(t, model.TestTask(t).Description, task.Description)
+			},
+			isValid: true,
+		},
+		{
+			name:  "uncomplete",
+			patch: `{"done": false}`,
+			check: func(t *testing.T, task *model.Task) {
+				assert.False(t, task.Done)
+			},
+			isValid: true,
+		},
+		{
+			name:  "clear priority",
+			patch: `{"priority": null}`,
+			check: func(t *testing.T, task *model.Task) {
+				assert.Equal(t, model.PriorityNone, task.Priority)
+			},
+			isValid: true,
+		},
+		{
+			name:  "estimate",
+			patch: `{"estimate": 13}`,
+			check: func(t *testing.T, task *model.Task) {
+				assert.Equal(t, 13, *task.Estimate)
+			},
+			isValid: true,
+		},
+		{
+			name:  "unassign",
+			patch: `{"assignee_id": null}`,
+			check: func(t *testing.T, task *model.Task) {
+				assert.Nil(t, task.AssigneeID)
+			},
+			isValid: true,
+		},
+		{
+			name:  "clear due date",
+			patch: `{"due_at": null}`,
+			check: func(t *testing.T, task *model.Task) {
+				assert.Nil(t, task.DueAt)
+			},
+			isValid: true,
+		},
+		{
+			name:  "null title",
+			patch: `{"title": null}`,
+			check: func(t *testing.T, task *model.Task) {
+				assert.Error(t, task.Validate())
+			},
+			isValid: true,
+		},
+		{
+			name:    "unknown field",
+			patch:   `{"id": 5}`,
+			isValid: false,
+		},
+		{
+			name:    "wrong type",
+			patch:   `{"done": "yes"}`,
+			isValid: false,
+		},
+		{
+			name:    "not an object",
+			patch:   `[1, 2]`,
+			isValid: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			task := model.TestTask(t)
+			task.Done = true
+			dueAt := time.Now()
+			task.DueAt = &dueAt
+
+			err := task.Patch([]byte(tc.patch))
+			if !tc.isValid {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			tc.check(t, task)
+		})
+	}
+}
+
+func TestTask_NextOccurrence(t *testing.T) {
+	task := model.TestTask(t)
+	task.ID = 3
+	next, err := task.NextOccurrence()
+	assert.NoError(t, err)
+	assert.Nil(t, next)
+
+	dueAt := time.Date(2026, 1, 31, 9, 0, 0, 0, time.UTC)
+	task.DueAt = &dueAt
+	task.Recurrence = "FREQ=MONTHLY;COUNT=2"
+	task.Tags = []string{"home"}
+	assert.NoError(t, task.Validate())
+
+	next, err = task.NextOccurrence()
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2026, 2, 28, 9, 0, 0, 0, time.UTC), *next.DueAt)
+	assert.Equal(t, "FREQ=MONTHLY;BYMONTHDAY=31;COUNT=1", next.Recurrence)
+	assert.Equal(t, 3, *next.SeriesID)
+	assert.Equal(t, task.Tags, next.Tags)
+	assert.False(t, next.Done)
+
+	// The last occurrence
+	last, err := next.NextOccurrence()
+	assert.NoError(t, err)
+	assert.Nil(t, last)
+
+	task.Recurrence = "FREQ=DAILY;UNTIL=20260131T120000Z"
+	next, err = task.NextOccurrence()
+	assert.NoError(t, err)
+	assert.Nil(t, next)
+
+	// A recurring task needs a due date
+	task.DueAt = nil
+	assert.Error(t, task.Validate())
+}
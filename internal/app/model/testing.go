@@ -22,3 +22,86 @@ func TestTask(t *testing.T) *Task {
 		CreationDate: time.Now().String(),
 	}
 }
+
+func TestTag(t *testing.T) *Tag {
+	return &Tag{
+		UserID: 1,
+		Name:   "urgent",
+	}
+}
+
+func TestProject(t *testing.T) *Project {
+	return &Project{
+		UserID:      1,
+		Name:        "Groceries",
+		Description: "Weekly shopping list",
+	}
+}
+
+func TestWorkflow(t *testing.T) *Workflow {
+	return &Workflow{
+		UserID: 1,
+		Statuses: []*Status{
+			{Name: "backlog"},
+			{Name: "review"},
+			{Name: "shipped", Done: true},
+		},
+		Transitions: map[string][]string{
+			"backlog": {"review"},
+			"review":  {"backlog", "shipped"},
+		},
+	}
+}
+
+func TestTimeEntry(t *testing.T) *TimeEntry {
+	startedAt := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	stoppedAt := startedAt.Add(90 * time.Minute)
+	return &TimeEntry{
+		UserID:    1,
+		StartedAt: startedAt,
+		StoppedAt: &stoppedAt,
+		Note:      "Code review",
+	}
+}
+
+func TestComment(t *testing.T) *Comment {
+	return &Comment{
+		UserID: 1,
+		Body:   "Looks good, but **check** the edge cases",
+	}
+}
+
+func TestShare(t *testing.T) *Share {
+	taskId := 1
+	return &Share{
+		OwnerID: 1,
+		UserID:  2,
+		TaskID:  &taskId,
+		Role:    RoleViewer,
+	}
+}
+
+func TestAttachment(t *testing.T) *Attachment {
+	return &Attachment{
+		UserID:      1,
+		Name:        "screenshot.png",
+		ContentType: "image/png",
+		Size:        2048,
+		Key:         "tasks/1/screenshot",
+	}
+}
+
+func TestOrganization(t *testing.T) *Organization {
+	return &Organization{
+		Name: "Engineering",
+	}
+}
+
+func TestInvitation(t *testing.T) *Invitation {
+	return &Invitation{
+		OrganizationID: 1,
+		Email:          "member@user.com",
+		Role:           OrgRoleMember,
+		InvitedBy:      1,
+	}
+}
@@ -0,0 +1,133 @@
+package model
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+var ErrInvalidTimeRange = errors.New("must be after started_at")
+
+// TimeEntry is time a user spent on a task, either tracked with a timer or
+// logged by hand. A running timer is an entry without StoppedAt.
+type TimeEntry struct {
+	ID        int        `json:"id"`
+	UserID    int        `json:"-"`
+	TaskID    int        `json:"task_id"`
+	StartedAt time.Time  `json:"started_at"`
+	StoppedAt *time.Time `json:"stopped_at"`
+	Note      string     `json:"note,omitempty"`
+	// Duration is in seconds, a running entry counts until now
+	Duration int64 `json:"duration"`
+}
+
+// Validate validates the fields of an entry logged by hand
+func (e *TimeEntry) Validate() error {
+	return validation.ValidateStruct(
+		e,
+		validation.Field(&e.StartedAt, validation.Required),
+		validation.Field(&e.StoppedAt, validation.Required, validation.By(e.validateStoppedAt)),
+		validation.Field(&e.Note, validation.Length(0, 255)),
+	)
+}
+
+func (e *TimeEntry) validateStoppedAt(value interface{}) error {
+	if e.StoppedAt != nil && !e.StoppedAt.After(e.StartedAt) {
+		return ErrInvalidTimeRange
+	}
+
+	return nil
+}
+
+// Running tells whether the entry is a running timer
+func (e *TimeEntry) Running() bool {
+	return e.StoppedAt == nil
+}
+
+// SetDuration computes the duration of the entry, running ones until now
+func (e *TimeEntry) SetDuration(now time.Time) {
+	e.Duration = int64(e.end(now).Sub(e.StartedAt) / time.Second)
+}
+
+func (e *TimeEntry) end(now time.Time) time.Time {
+	if e.StoppedAt == nil {
+		return now
+	}
+
+	return *e.StoppedAt
+}
+
+// TimeReport sums up time entries over a period
+type TimeReport struct {
+	From  time.Time    `json:"from"`
+	To    time.Time    `json:"to"`
+	Total int64        `json:"total"`
+	Days  []*DayTotal  `json:"days"`
+	Tasks []*TaskTotal `json:"tasks"`
+}
+
+// DayTotal is the time logged on a day, in seconds
+type DayTotal struct {
+	Date     string `json:"date"`
+	Duration int64  `json:"duration"`
+}
+
+// TaskTotal is the time logged on a task, in seconds
+type TaskTotal struct {
+	TaskID   int   `json:"task_id"`
+	Duration int64 `json:"duration"`
+}
+
+// NewTimeReport sums up the part of the entries between from and to. Entries
+// spanning midnight are split between the days, which are UTC dates.
+func NewTimeReport(entries []*TimeEntry, from, to, now time.Time) *TimeReport {
+	days := map[string]time.Duration{}
+	tasks := map[int]time.Duration{}
+	var total time.Duration
+	for _, e := range entries {
+		start, end := e.StartedAt, e.end(now)
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+
+		for start.Before(end) {
+			next := start.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+			if next.After(end) {
+				next = end
+			}
+
+			days[start.UTC().Format("2006-01-02")] += next.Sub(start)
+			tasks[e.TaskID] += next.Sub(start)
+			total += next.Sub(start)
+			start = next
+		}
+	}
+
+	report := &TimeReport{
+		From:  from,
+		To:    to,
+		Total: int64(total / time.Second),
+		Days:  []*DayTotal{},
+		Tasks: []*TaskTotal{},
+	}
+	for date, d := range days {
+		report.Days = append(report.Days, &DayTotal{Date: date, Duration: int64(d / time.Second)})
+	}
+	for taskId, d := range tasks {
+		report.Tasks = append(report.Tasks, &TaskTotal{TaskID: taskId, Duration: int64(d / time.Second)})
+	}
+
+	sort.Slice(report.Days, func(i, j int) bool {
+		return report.Days[i].Date < report.Days[j].Date
+	})
+	sort.Slice(report.Tasks, func(i, j int) bool {
+		return report.Tasks[i].TaskID < report.Tasks[j].TaskID
+	})
+
+	return report
+}
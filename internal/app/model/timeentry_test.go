@@ -0,0 +1,117 @@
+package model_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeEntry_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		e       func() *model.TimeEntry
+		isValid bool
+	}{
+		{
+			name: "valid",
+			e: func() *model.TimeEntry {
+				return model.TestTimeEntry(t)
+			},
+			isValid: true,
+		},
+		{
+			name: "no start",
+			e: func() *model.TimeEntry {
+				e := model.TestTimeEntry(t)
+				e.StartedAt = time.Time{}
+
+				return e
+			},
+			isValid: false,
+		},
+		{
+			name: "no stop",
+			e: func() *model.TimeEntry {
+				e := model.TestTimeEntry(t)
+				e.StoppedAt = nil
+
+				return e
+			},
+			isValid: false,
+		},
+		{
+			name: "stopped before started",
+			e: func() *model.TimeEntry {
+				e := model.TestTimeEntry(t)
+				stoppedAt := e.StartedAt.Add(-time.Minute)
+				e.StoppedAt = &stoppedAt
+
+				return e
+			},
+			isValid: false,
+		},
+		{
+			name: "long note",
+			e: func() *model.TimeEntry {
+				e := model.TestTimeEntry(t)
+				e.Note = strings.Repeat("a", 256)
+
+				return e
+			},
+			isValid: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.isValid {
+				assert.NoError(t, tc.e().Validate())
+			} else {
+				assert.Error(t, tc.e().Validate())
+			}
+		})
+	}
+}
+
+func TestTimeEntry_SetDuration(t *testing.T) {
+	e := model.TestTimeEntry(t)
+	e.SetDuration(time.Now())
+	assert.Equal(t, int64(90*60), e.Duration)
+
+	// A running timer counts until now
+	e.StoppedAt = nil
+	e.SetDuration(e.StartedAt.Add(time.Minute))
+	assert.Equal(t, int64(60), e.Duration)
+}
+
+func TestNewTimeReport(t *testing.T) {
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	at := func(hours float64) *time.Time {
+		t := day.Add(time.Duration(hours * float64(time.Hour)))
+		return &t
+	}
+
+	entries := []*model.TimeEntry{
+		// Spans midnight
+		{TaskID: 1, StartedAt: *at(23), StoppedAt: at(25)},
+		{TaskID: 2, StartedAt: *at(30), StoppedAt: at(30.5)},
+		// Starts before the period
+		{TaskID: 1, StartedAt: *at(-2), StoppedAt: at(1)},
+		// Still running
+		{TaskID: 2, StartedAt: *at(46)},
+	}
+
+	report := model.NewTimeReport(entries, day, *at(48), *at(47))
+	assert.Equal(t, int64((1+1+0.5+1+1)*3600), report.Total)
+	assert.Equal(t, []*model.DayTotal{
+		{Date: "2026-03-02", Duration: 2 * 3600},
+		{Date: "2026-03-03", Duration: 2.5 * 3600},
+	}, report.Days)
+	assert.Equal(t, []*model.TaskTotal{
+		{TaskID: 1, Duration: 3 * 3600},
+		{TaskID: 2, Duration: 1.5 * 3600},
+	}, report.Tasks)
+}
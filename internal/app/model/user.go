@@ -6,12 +6,20 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// passwordRules apply at sign-up and when the password is reset
+var passwordRules = []validation.Rule{validation.Required, validation.Length(6, 50)}
+
 type User struct {
 	ID                int    `json:"id"`
 	Name              string `json:"name"`
 	Email             string `json:"email"`
 	Password          string `json:"password,omitempty"`
 	EncryptedPassword string `json:"-"`
+	// Verified tells whether the user confirmed their email
+	Verified bool `json:"verified"`
+	// SessionVersion changes when the password is reset, the sessions
+	// logged in with an older version are no longer accepted
+	SessionVersion int `json:"-"`
 }
 
 // Validate validates User field
@@ -20,7 +28,15 @@ func (u *User) Validate() error {
 		u,
 		validation.Field(&u.Name, validation.Required, validation.Length(2, 20)),
 		validation.Field(&u.Email, validation.Required, is.Email),
-		validation.Field(&u.Password, validation.Required, validation.Length(6, 50)),
+		validation.Field(&u.Password, passwordRules...),
+	)
+}
+
+// ValidatePassword validates a new password of the user
+func (u *User) ValidatePassword() error {
+	return validation.ValidateStruct(
+		u,
+		validation.Field(&u.Password, passwordRules...),
 	)
 }
 
@@ -90,3 +90,11 @@ func TestUser_Sanitize(t *testing.T) {
 	u.Sanitize()
 	assert.Empty(t, u.Password)
 }
+
+func TestUser_ValidatePassword(t *testing.T) {
+	u := &model.User{Password: "Password"}
+	assert.NoError(t, u.ValidatePassword())
+
+	u.Password = "123"
+	assert.Error(t, u.ValidatePassword())
+}
@@ -0,0 +1,202 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+var (
+	ErrInvalidStatus     = errors.New("invalid status")
+	ErrInvalidTransition = errors.New("status transition not allowed")
+)
+
+var statusName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Workflow is the ordered set of statuses tasks go through. A workflow of a
+// project applies to the project tasks, the workflow of a user to all other
+// tasks of the user.
+type Workflow struct {
+	ID        int       `json:"-"`
+	UserID    int       `json:"-"`
+	ProjectID *int      `json:"project_id,omitempty"`
+	Statuses  []*Status `json:"statuses"`
+	// Transitions lists the statuses each status can be changed to. A
+	// workflow without transitions allows any change.
+	Transitions map[string][]string `json:"transitions,omitempty"`
+}
+
+// Status is a column of the board. Tasks in a done status are completed.
+type Status struct {
+	Name string `json:"name"`
+	Done bool   `json:"done"`
+}
+
+// DefaultWorkflow is used by users and projects without their own workflow
+func DefaultWorkflow() *Workflow {
+	return &Workflow{
+		Statuses: []*Status{
+			{Name: "todo"},
+			{Name: "in_progress"},
+			{Name: "blocked"},
+			{Name: "done", Done: true},
+		},
+		Transitions: map[string][]string{
+			"todo":        {"in_progress", "blocked", "done"},
+			"in_progress": {"todo", "blocked", "done"},
+			"blocked":     {"todo", "in_progress"},
+			"done":        {"todo", "in_progress"},
+		},
+	}
+}
+
+// Validate validates Workflow fields
+func (w *Workflow) Validate() error {
+	return validation.ValidateStruct(
+		w,
+		validation.Field(&w.Statuses, validation.Required, validation.By(w.validateStatuses)),
+		validation.Field(&w.Transitions, validation.By(w.validateTransitions)),
+	)
+}
+
+func (w *Workflow) validateStatuses(value interface{}) error {
+	seen := map[string]bool{}
+	for _, status := range w.Statuses {
+		if status == nil || !statusName.MatchString(status.Name) {
+			return errors.New("status names must be lowercase words joined with underscores")
+		}
+
+		if seen[status.Name] {
+			return fmt.Errorf("duplicate status %s", status.Name)
+		}
+		seen[status.Name] = true
+	}
+
+	if w.Statuses[0].Done {
+		return errors.New("the first status must not be done")
+	}
+
+	if w.DoneStatus() == "" {
+		return errors.New("at least one status must be done")
+	}
+
+	return nil
+}
+
+func (w *Workflow) validateTransitions(value interface{}) error {
+	for from, to := range w.Transitions {
+		if !w.Has(from) {
+			return fmt.Errorf("unknown status %s", from)
+		}
+
+		for _, name := range to {
+			if !w.Has(name) {
+				return fmt.Errorf("unknown status %s", name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Has tells whether the workflow has the status
+func (w *Workflow) Has(name string) bool {
+	return w.status(name) != nil
+}
+
+// IsDone tells whether the status completes tasks
+func (w *Workflow) IsDone(name string) bool {
+	s := w.status(name)
+	return s != nil && s.Done
+}
+
+// Initial returns the status of new tasks
+func (w *Workflow) Initial() string {
+	return w.Statuses[0].Name
+}
+
+// DoneStatus returns the first done status, the one completed tasks get
+func (w *Workflow) DoneStatus() string {
+	for _, s := range w.Statuses {
+		if s.Done {
+			return s.Name
+		}
+	}
+
+	return ""
+}
+
+// Allows tells whether a task can be changed from one status to another
+func (w *Workflow) Allows(from, to string) bool {
+	if w.Transitions == nil || from == to {
+		return true
+	}
+
+	for _, name := range w.Transitions[from] {
+		if name == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// StatusOf returns the status of the task in the workflow. A task with a
+// status from another workflow is in the initial or the done status.
+func (w *Workflow) StatusOf(task *Task) string {
+	if w.Has(task.Status) {
+		return task.Status
+	}
+
+	if task.Done {
+		return w.DoneStatus()
+	}
+
+	return w.Initial()
+}
+
+// Apply keeps the status and the done flag of a task in line when the task
+// is saved over its old state, or created if old is nil. Changing the status
+// follows the transitions and sets done, changing done alone moves the task
+// to the done or the initial status.
+func (w *Workflow) Apply(old, task *Task) error {
+	switch {
+	case old != nil && task.Status != old.Status:
+		if !w.Has(task.Status) {
+			return ErrInvalidStatus
+		}
+
+		if w.Has(old.Status) && !w.Allows(old.Status, task.Status) {
+			return ErrInvalidTransition
+		}
+
+		task.Done = w.IsDone(task.Status)
+	case old != nil && task.Done != old.Done:
+		task.Status = w.Initial()
+		if task.Done {
+			task.Status = w.DoneStatus()
+		}
+	case old == nil && task.Status != "":
+		if !w.Has(task.Status) {
+			return ErrInvalidStatus
+		}
+
+		task.Done = w.IsDone(task.Status)
+	default:
+		task.Status = w.StatusOf(task)
+	}
+
+	return nil
+}
+
+func (w *Workflow) status(name string) *Status {
+	for _, s := range w.Statuses {
+		if s.Name == name {
+			return s
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,170 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkflow_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		w       func() *model.Workflow
+		isValid bool
+	}{
+		{
+			name: "default",
+			w: func() *model.Workflow {
+				return model.DefaultWorkflow()
+			},
+			isValid: true,
+		},
+		{
+			name: "without transitions",
+			w: func() *model.Workflow {
+				w := model.DefaultWorkflow()
+				w.Transitions = nil
+
+				return w
+			},
+			isValid: true,
+		},
+		{
+			name: "no statuses",
+			w: func() *model.Workflow {
+				return &model.Workflow{}
+			},
+			isValid: false,
+		},
+		{
+			name: "no done status",
+			w: func() *model.Workflow {
+				return &model.Workflow{Statuses: []*model.Status{{Name: "todo"}, {Name: "review"}}}
+			},
+			isValid: false,
+		},
+		{
+			name: "first status done",
+			w: func() *model.Workflow {
+				return &model.Workflow{Statuses: []*model.Status{{Name: "done", Done: true}, {Name: "todo"}}}
+			},
+			isValid: false,
+		},
+		{
+			name: "duplicate status",
+			w: func() *model.Workflow {
+				w := model.DefaultWorkflow()
+				w.Statuses = append(w.Statuses, &model.Status{Name: "todo"})
+
+				return w
+			},
+			isValid: false,
+		},
+		{
+			name: "invalid name",
+			w: func() *model.Workflow {
+				w := model.DefaultWorkflow()
+				w.Statuses[1].Name = "In progress"
+
+				return w
+			},
+			isValid: false,
+		},
+		{
+			name: "unknown transition",
+			w: func() *model.Workflow {
+				w := model.DefaultWorkflow()
+				w.Transitions["todo"] = append(w.Transitions["todo"], "review")
+
+				return w
+			},
+			isValid: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.isValid {
+				assert.NoError(t, tc.w().Validate())
+			} else {
+				assert.Error(t, tc.w().Validate())
+			}
+		})
+	}
+}
+
+func TestWorkflow_Apply(t *testing.T) {
+	w := model.DefaultWorkflow()
+	testCases := []struct {
+		name           string
+		old            *model.Task
+		task           *model.Task
+		expectedStatus string
+		expectedDone   bool
+		expectedErr    error
+	}{
+		{
+			name:           "new task",
+			task:           &model.Task{},
+			expectedStatus: "todo",
+		},
+		{
+			name:           "new task with status",
+			task:           &model.Task{Status: "done"},
+			expectedStatus: "done",
+			expectedDone:   true,
+		},
+		{
+			name:        "new task with unknown status",
+			task:        &model.Task{Status: "review"},
+			expectedErr: model.ErrInvalidStatus,
+		},
+		{
+			name:           "allowed transition",
+			old:            &model.Task{Status: "todo"},
+			task:           &model.Task{Status: "in_progress"},
+			expectedStatus: "in_progress",
+		},
+		{
+			name:        "forbidden transition",
+			old:         &model.Task{Status: "blocked"},
+			task:        &model.Task{Status: "done", Done: false},
+			expectedErr: model.ErrInvalidTransition,
+		},
+		{
+			name:           "completed",
+			old:            &model.Task{Status: "blocked"},
+			task:           &model.Task{Status: "blocked", Done: true},
+			expectedStatus: "done",
+			expectedDone:   true,
+		},
+		{
+			name:           "reopened",
+			old:            &model.Task{Status: "done", Done: true},
+			task:           &model.Task{Status: "done", Done: false},
+			expectedStatus: "todo",
+		},
+		{
+			name:           "status of another workflow",
+			old:            &model.Task{Status: "review", Done: true},
+			task:           &model.Task{Status: "review", Done: true},
+			expectedStatus: "done",
+			expectedDone:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := w.Apply(tc.old, tc.task)
+			if tc.expectedErr != nil {
+				assert.Equal(t, tc.expectedErr, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedStatus, tc.task.Status)
+			assert.Equal(t, tc.expectedDone, tc.task.Done)
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package store
+
+import (
+	"encoding/json"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+)
+
+// Operations of a task batch
+const (
+	BatchCreate   = "create"
+	BatchComplete = "complete"
+	BatchDelete   = "delete"
+	BatchUpdate   = "update"
+)
+
+// TaskOperation is a single operation of a task batch
+type TaskOperation struct {
+	Op string
+	// TaskID is the task to complete, delete or update
+	TaskID int
+	// Task is the task to create
+	Task *model.Task
+	// Patch is the JSON merge patch applied by an update
+	Patch json.RawMessage
+}
+
+// TaskOperationResult is the outcome of a batch operation. Task holds the
+// created, completed or updated task, Err the reason the operation failed.
+type TaskOperationResult struct {
+	Task *model.Task
+	Err  error
+}
@@ -0,0 +1,31 @@
+package store
+
+// CompleteInOrder completes the tasks one by one. A task refused because of
+// its blockers is retried once the other tasks are completed, so a group of
+// tasks blocking each other completes as a whole. The tasks still blocked by
+// tasks outside of the group are returned.
+func CompleteInOrder(ids []int, complete func(int) error) ([]int, error) {
+	for len(ids) > 0 {
+		var blocked []int
+		for _, id := range ids {
+			err := complete(id)
+			if err == ErrTaskBlocked {
+				blocked = append(blocked, id)
+				continue
+			}
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		// No progress means the blockers are outside of the group
+		if len(blocked) == len(ids) {
+			return blocked, nil
+		}
+
+		ids = blocked
+	}
+
+	return []int{}, nil
+}
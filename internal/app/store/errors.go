@@ -3,6 +3,36 @@ package store
 import "errors"
 
 var (
-	ErrNoRecordsInTable = errors.New("no records in table")
-	ErrInvalidTaskId    = errors.New("invalid task id")
+	ErrNoRecordsInTable      = errors.New("no records in table")
+	ErrInvalidTaskId         = errors.New("invalid task id")
+	ErrInvalidParent         = errors.New("invalid parent task")
+	ErrInvalidTagId          = errors.New("invalid tag id")
+	ErrTagExists             = errors.New("tag already exists")
+	ErrInvalidProjectId      = errors.New("invalid project id")
+	ErrInvalidCursor         = errors.New("invalid cursor")
+	ErrInvalidSort           = errors.New("invalid sort order")
+	ErrInvalidOperation      = errors.New("invalid batch operation")
+	ErrBatchRolledBack       = errors.New("batch rolled back because an operation failed")
+	ErrInvalidMove           = errors.New("a task can't be moved next to itself")
+	ErrDependencyCycle       = errors.New("dependency would create a cycle")
+	ErrNoDependency          = errors.New("task is not blocked by the given task")
+	ErrTaskBlocked           = errors.New("task is blocked by open tasks")
+	ErrInvalidBlocker        = errors.New("the blocker must be visible to the owner of the task")
+	ErrTimerRunning          = errors.New("a timer is already running")
+	ErrNoTimerRunning        = errors.New("no timer is running for the task")
+	ErrInvalidCommentId      = errors.New("invalid comment id")
+	ErrNotCommentAuthor      = errors.New("only the author can change the comment")
+	ErrInvalidAttachmentId   = errors.New("invalid attachment id")
+	ErrInvalidShareId        = errors.New("invalid share id")
+	ErrShareWithOwner        = errors.New("can't share with the owner")
+	ErrReadOnly              = errors.New("only the owner and editors can change the task")
+	ErrInvalidAssignee       = errors.New("the assignee must be a member of the task's project")
+	ErrInvalidOrganizationId = errors.New("invalid organization id")
+	ErrInvalidMember         = errors.New("not a member of the organization")
+	ErrAlreadyMember         = errors.New("already a member of the organization")
+	ErrOrganizationOwner     = errors.New("the owner can't leave the organization or change role")
+	ErrInvalidInvitation     = errors.New("invalid or expired invitation")
+	ErrUnverifiedInvitee     = errors.New("verify your email to accept the invitation")
+	ErrInvalidResetToken     = errors.New("invalid or expired password reset token")
+	ErrInvalidVerification   = errors.New("invalid or expired email verification token")
 )
@@ -0,0 +1,403 @@
+// Package filter parses the filter expressions of task listings, e.g.
+//
+//	done:false AND (title~"report" OR priority>=high) AND created>2026-01-01
+//
+// into a syntax tree that the stores translate into their own conditions.
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+)
+
+// Fields that can be filtered on
+const (
+	FieldTitle       = "title"
+	FieldDescription = "description"
+	FieldDone        = "done"
+	FieldStatus      = "status"
+	FieldPriority    = "priority"
+	FieldDue         = "due"
+	FieldCreated     = "created"
+	FieldTag         = "tag"
+	FieldProject     = "project"
+)
+
+// Comparison operators. OpNe is always the negation of OpEq, ordered
+// comparisons never match a field without a value.
+const (
+	OpEq       = ":"
+	OpNe       = "!="
+	OpContains = "~"
+	OpGt       = ">"
+	OpGe       = ">="
+	OpLt       = "<"
+	OpLe       = "<="
+)
+
+// Expr is a node of a parsed filter expression
+type Expr interface {
+	expr()
+}
+
+// And matches tasks matching both sides
+type And struct {
+	Left, Right Expr
+}
+
+// Or matches tasks matching any of the sides
+type Or struct {
+	Left, Right Expr
+}
+
+// Not matches tasks not matching the expression
+type Not struct {
+	Expr Expr
+}
+
+// Cmp compares a task field with a value. Value holds a string for text
+// fields, statuses and tags, a bool, a model.Priority, a time.Time or an int for
+// projects, and nil when comparing with null.
+type Cmp struct {
+	Field string
+	Op    string
+	Value interface{}
+}
+
+func (*And) expr() {}
+func (*Or) expr()  {}
+func (*Not) expr() {}
+func (*Cmp) expr() {}
+
+// Error points at the token of the expression that can't be parsed
+type Error struct {
+	// Pos is the position of the token starting from 1
+	Pos   int
+	Token string
+	Msg   string
+}
+
+func (e *Error) Error() string {
+	if e.Token == "" {
+		return fmt.Sprintf("invalid filter: %s at the end of the expression", e.Msg)
+	}
+
+	return fmt.Sprintf("invalid filter: %s at position %d near %q", e.Msg, e.Pos, e.Token)
+}
+
+type kind int
+
+const (
+	kindText kind = iota
+	kindBool
+	kindPriority
+	kindTime
+	kindTag
+	kindID
+	kindStatus
+)
+
+var fields = map[string]struct {
+	kind     kind
+	nullable bool
+}{
+	FieldTitle:       {kindText, false},
+	FieldDescription: {kindText, false},
+	FieldDone:        {kindBool, false},
+	FieldStatus:      {kindStatus, false},
+	FieldPriority:    {kindPriority, false},
+	FieldDue:         {kindTime, true},
+	FieldCreated:     {kindTime, false},
+	FieldTag:         {kindTag, false},
+	FieldProject:     {kindID, true},
+}
+
+// operators lists the operators allowed for every kind of field
+var operators = map[kind][]string{
+	kindText:     {OpEq, OpNe, OpContains},
+	kindBool:     {OpEq, OpNe},
+	kindPriority: {OpEq, OpNe, OpGt, OpGe, OpLt, OpLe},
+	kindTime:     {OpGt, OpGe, OpLt, OpLe},
+	kindTag:      {OpEq, OpNe},
+	kindID:       {OpEq, OpNe},
+	kindStatus:   {OpEq, OpNe},
+}
+
+// Operators are tried in this order so that the longest one wins
+var allOperators = []string{OpGe, OpLe, OpNe, OpEq, OpContains, OpGt, OpLt}
+
+// Parse parses a filter expression. Comparisons are joined with AND, OR and
+// NOT, AND binds tighter than OR and parentheses group them.
+func Parse(input string) (Expr, error) {
+	p := &parser{input: input}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpace()
+	if p.pos < len(p.input) {
+		return nil, p.errorf(p.pos, "expected AND or OR")
+	}
+
+	return e, nil
+}
+
+type parser struct {
+	input string
+	pos   int
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &Or{Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.keyword("AND") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &And{Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	if p.keyword("NOT") {
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return &Not{Expr: e}, nil
+	}
+
+	p.skipSpace()
+	if p.pos < len(p.input) && p.input[p.pos] == '(' {
+		p.pos++
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		p.skipSpace()
+		if p.pos >= len(p.input) || p.input[p.pos] != ')' {
+			return nil, p.errorf(p.pos, "expected )")
+		}
+
+		p.pos++
+		return e, nil
+	}
+
+	return p.parseCmp()
+}
+
+func (p *parser) parseCmp() (Expr, error) {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.input) && (isLetter(p.input[p.pos]) || p.input[p.pos] == '_') {
+		p.pos++
+	}
+
+	name := strings.ToLower(p.input[start:p.pos])
+	if name == "" {
+		return nil, p.errorf(start, "expected a field name")
+	}
+
+	field, ok := fields[name]
+	if !ok {
+		return nil, p.errorf(start, "unknown field")
+	}
+
+	p.skipSpace()
+	opStart := p.pos
+	op := ""
+	for _, o := range allOperators {
+		if strings.HasPrefix(p.input[p.pos:], o) {
+			op = o
+			break
+		}
+	}
+
+	if op == "" {
+		return nil, p.errorf(opStart, "expected an operator")
+	}
+	p.pos += len(op)
+
+	p.skipSpace()
+	valueStart := p.pos
+	raw, quoted, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+
+	cmp := &Cmp{Field: name, Op: op}
+	if !quoted && raw == "null" && field.nullable {
+		if op != OpEq && op != OpNe {
+			return nil, p.errorf(opStart, "null can only be compared with : or !=")
+		}
+
+		return cmp, nil
+	}
+
+	if !allowed(operators[field.kind], op) {
+		return nil, p.errorf(opStart, "operator not supported by the field")
+	}
+
+	if cmp.Value, err = convert(field.kind, raw); err != nil {
+		return nil, p.errorf(valueStart, err.Error())
+	}
+
+	return cmp, nil
+}
+
+// value reads a quoted string or a bare word ending with a space or )
+func (p *parser) value() (string, bool, error) {
+	start := p.pos
+	if p.pos < len(p.input) && p.input[p.pos] == '"' {
+		var b strings.Builder
+		for p.pos++; p.pos < len(p.input); p.pos++ {
+			c := p.input[p.pos]
+			switch {
+			case c == '\\' && p.pos+1 < len(p.input):
+				p.pos++
+				b.WriteByte(p.input[p.pos])
+			case c == '"':
+				p.pos++
+				return b.String(), true, nil
+			default:
+				b.WriteByte(c)
+			}
+		}
+
+		return "", false, p.errorf(start, "unterminated string")
+	}
+
+	for p.pos < len(p.input) && !isSpace(p.input[p.pos]) && p.input[p.pos] != ')' {
+		p.pos++
+	}
+
+	if p.pos == start {
+		return "", false, p.errorf(start, "expected a value")
+	}
+
+	return p.input[start:p.pos], false, nil
+}
+
+// keyword consumes the keyword if it is the next word of the input
+func (p *parser) keyword(word string) bool {
+	p.skipSpace()
+	end := p.pos + len(word)
+	if end > len(p.input) || !strings.EqualFold(p.input[p.pos:end], word) {
+		return false
+	}
+
+	if end < len(p.input) && !isSpace(p.input[end]) && p.input[end] != '(' {
+		return false
+	}
+
+	p.pos = end
+	return true
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.input) && isSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+// errorf reports an error at the token starting at pos
+func (p *parser) errorf(pos int, msg string) error {
+	end := pos
+	for end < len(p.input) && !isSpace(p.input[end]) {
+		end++
+	}
+
+	return &Error{Pos: pos + 1, Token: p.input[pos:end], Msg: msg}
+}
+
+func convert(k kind, raw string) (interface{}, error) {
+	switch k {
+	case kindBool:
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("expected true or false")
+		}
+		return v, nil
+	case kindPriority:
+		v := model.Priority(strings.ToLower(raw))
+		if v.Level() < 0 || v == "" {
+			return nil, fmt.Errorf("expected none, low, medium, high or urgent")
+		}
+		return v, nil
+	case kindTime:
+		if v, err := time.Parse("2006-01-02", raw); err == nil {
+			return v, nil
+		}
+
+		v, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return nil, fmt.Errorf("expected a date as YYYY-MM-DD or RFC 3339")
+		}
+		return v, nil
+	case kindStatus:
+		return strings.ToLower(raw), nil
+	case kindTag:
+		tag := &model.Tag{Name: raw}
+		tag.Normalize()
+		return tag.Name, nil
+	case kindID:
+		v, err := strconv.Atoi(raw)
+		if err != nil {
+			return nil, fmt.Errorf("expected an id or null")
+		}
+		return v, nil
+	}
+
+	return raw, nil
+}
+
+func allowed(ops []string, op string) bool {
+	for _, o := range ops {
+		if o == op {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isLetter(c byte) bool {
+	return c < unicode.MaxASCII && unicode.IsLetter(rune(c))
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
@@ -0,0 +1,150 @@
+package filter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/pyuldashev912/todoapp/internal/app/store/filter"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected filter.Expr
+	}{
+		{
+			name:     "comparison",
+			input:    "done:false",
+			expected: &filter.Cmp{Field: filter.FieldDone, Op: filter.OpEq, Value: false},
+		},
+		{
+			name:  "and binds tighter than or",
+			input: `done:false AND title~"weekly report" or priority>=HIGH`,
+			expected: &filter.Or{
+				Left: &filter.And{
+					Left:  &filter.Cmp{Field: filter.FieldDone, Op: filter.OpEq, Value: false},
+					Right: &filter.Cmp{Field: filter.FieldTitle, Op: filter.OpContains, Value: "weekly report"},
+				},
+				Right: &filter.Cmp{Field: filter.FieldPriority, Op: filter.OpGe, Value: model.PriorityHigh},
+			},
+		},
+		{
+			name:  "parentheses and not",
+			input: "NOT (tag:Home OR project : null) AND created > 2026-01-01",
+			expected: &filter.And{
+				Left: &filter.Not{Expr: &filter.Or{
+					Left:  &filter.Cmp{Field: filter.FieldTag, Op: filter.OpEq, Value: "home"},
+					Right: &filter.Cmp{Field: filter.FieldProject, Op: filter.OpEq},
+				}},
+				Right: &filter.Cmp{
+					Field: filter.FieldCreated, Op: filter.OpGt, Value: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+		{
+			name:  "escaped quote",
+			input: `description!="say \"hi\""`,
+			expected: &filter.Cmp{
+				Field: filter.FieldDescription, Op: filter.OpNe, Value: `say "hi"`,
+			},
+		},
+		{
+			name:     "status",
+			input:    "status!=In_Progress",
+			expected: &filter.Cmp{Field: filter.FieldStatus, Op: filter.OpNe, Value: "in_progress"},
+		},
+		{
+			name:     "timestamp",
+			input:    "due<=2026-01-05T09:00:00Z",
+			expected: &filter.Cmp{Field: filter.FieldDue, Op: filter.OpLe, Value: time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := filter.Parse(tc.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, e)
+		})
+	}
+}
+
+func TestParse_Error(t *testing.T) {
+	testCases := []struct {
+		name  string
+		input string
+		pos   int
+		token string
+	}{
+		{
+			name:  "unknown field",
+			input: "done:false AND color:red",
+			pos:   16,
+			token: "color:red",
+		},
+		{
+			name:  "missing operator",
+			input: "title report",
+			pos:   7,
+			token: "report",
+		},
+		{
+			name:  "invalid value",
+			input: "done:maybe",
+			pos:   6,
+			token: "maybe",
+		},
+		{
+			name:  "unsupported operator",
+			input: "done>true",
+			pos:   5,
+			token: ">true",
+		},
+		{
+			name:  "null of not nullable field",
+			input: "created>null",
+			pos:   9,
+			token: "null",
+		},
+		{
+			name:  "missing and",
+			input: "done:true title:x",
+			pos:   11,
+			token: "title:x",
+		},
+		{
+			name:  "unterminated string",
+			input: `title:"report`,
+			pos:   7,
+			token: `"report`,
+		},
+		{
+			name:  "missing parenthesis",
+			input: "(done:true",
+		},
+		{
+			name:  "missing value",
+			input: "done:",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := filter.Parse(tc.input)
+			filterErr, ok := err.(*filter.Error)
+			assert.True(t, ok)
+			if !ok {
+				return
+			}
+
+			assert.Equal(t, tc.token, filterErr.Token)
+			if tc.token != "" {
+				assert.Equal(t, tc.pos, filterErr.Pos)
+				assert.Contains(t, err.Error(), tc.token)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/pyuldashev912/todoapp/internal/app/store/filter"
+)
+
+// Sort orders of task listings
+const (
+	SortCreated = "created"
+	SortTitle   = "title"
+	SortDue     = "due"
+	// SortPriority lists open tasks before completed ones, each from the highest priority to the lowest
+	SortPriority = "priority"
+	// SortRank lists the tasks of each owner together in the order the owner
+	// arranged them in
+	SortRank = "rank"
+)
+
+// TaskQuery describes which of User's tasks are listed and in which order
+type TaskQuery struct {
+	Done      *bool
+	DueAfter  *time.Time
+	DueBefore *time.Time
+	// OverdueAt keeps only not completed tasks that were due before it
+	OverdueAt *time.Time
+	// Tags keeps tasks labeled with any of the tag names, or all of them if AllTags is set
+	Tags    []string
+	AllTags bool
+	// ProjectID keeps only tasks of the project
+	ProjectID *int
+	// ParentID keeps only direct subtasks of the task
+	ParentID *int
+	// SeriesID keeps only occurrences of the recurring series
+	SeriesID *int
+	// AssigneeID keeps only tasks assigned to the user
+	AssigneeID *int
+	// Priorities keeps only tasks with any of the priorities
+	Priorities []model.Priority
+	// Statuses keeps only tasks in any of the workflow statuses
+	Statuses []string
+	// Filter keeps only tasks matching the parsed filter expression
+	Filter filter.Expr
+
+	Sort string
+	Desc bool
+	// Limit caps the number of returned tasks, zero means no limit
+	Limit int
+	// Cursor continues a listing after the page it was returned with
+	Cursor string
+}
+
+// ReportScope selects the tasks a report covers
+type ReportScope struct {
+	// ProjectID keeps only tasks of the project
+	ProjectID *int
+	// Tag keeps only tasks labeled with the tag name
+	Tag string
+}
+
+// SortOrDefault returns the requested sort order or SortCreated if none is set
+func (q *TaskQuery) SortOrDefault() string {
+	if q.Sort == "" {
+		return SortCreated
+	}
+
+	return q.Sort
+}
+
+// rankKeyOwner is the width of the owner id in rank keys
+const rankKeyOwner = 20
+
+// RankKey returns the key of the task in the manual order, the owner id padded
+// to a fixed width followed by the rank, so that the keys of the tasks of one
+// owner are next to each other
+func RankKey(task *model.Task) string {
+	return fmt.Sprintf("%0*d %s", rankKeyOwner, task.UserID, task.Rank)
+}
+
+// Cursor points at the last task of a page. Pages are keyset paginated on
+// the sort key with the task id as a tie breaker.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+// NewCursor returns an opaque cursor pointing right after the task
+func NewCursor(sort string, task *model.Task) string {
+	c := &Cursor{Sort: sort, ID: task.ID}
+	switch sort {
+	case SortTitle:
+		c.Value = task.Title
+	case SortRank:
+		c.Value = RankKey(task)
+	case SortDue:
+		c.Value = "infinity"
+		if task.DueAt != nil {
+			c.Value = task.DueAt.Format(time.RFC3339Nano)
+		}
+	case SortPriority:
+		c.Value = strconv.Itoa(PriorityRank(task))
+	default:
+		c.Value = task.CreatedAt.Format(time.RFC3339Nano)
+	}
+
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor parses a cursor made by NewCursor for the same sort order
+func DecodeCursor(cursor string, sort string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	c := &Cursor{}
+	if err := json.Unmarshal(b, c); err != nil || c.Sort != sort {
+		return nil, ErrInvalidCursor
+	}
+
+	if _, err := c.Task(); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+// Task returns a task holding only the id and the sort key of the cursor
+func (c *Cursor) Task() (*model.Task, error) {
+	task := &model.Task{ID: c.ID}
+	switch c.Sort {
+	case SortTitle:
+		task.Title = c.Value
+	case SortRank:
+		if len(c.Value) <= rankKeyOwner || c.Value[rankKeyOwner] != ' ' {
+			return nil, ErrInvalidCursor
+		}
+
+		owner, err := strconv.Atoi(c.Value[:rankKeyOwner])
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+
+		task.UserID, task.Rank = owner, c.Value[rankKeyOwner+1:]
+	case SortDue:
+		if c.Value != "infinity" {
+			t, err := time.Parse(time.RFC3339Nano, c.Value)
+			if err != nil {
+				return nil, err
+			}
+
+			task.DueAt = &t
+		}
+	case SortPriority:
+		rank, err := strconv.Atoi(c.Value)
+		if err != nil || rank < -4 || rank > 5 {
+			return nil, ErrInvalidCursor
+		}
+
+		task.Done = rank > 0
+		if task.Done {
+			task.Priority = model.PriorityOfLevel(5 - rank)
+		} else {
+			task.Priority = model.PriorityOfLevel(-rank)
+		}
+	default:
+		t, err := time.Parse(time.RFC3339Nano, c.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		task.CreatedAt = t
+	}
+
+	return task, nil
+}
+
+// PriorityRank is the ascending sort key of SortPriority: -4 for open urgent
+// tasks up to 0 for open ones without priority, then 1 to 5 for completed ones
+func PriorityRank(task *model.Task) int {
+	if task.Done {
+		return 5 - task.Priority.Level()
+	}
+
+	return -task.Priority.Level()
+}
@@ -1,18 +1,118 @@
 package store
 
-import "github.com/pyuldashev912/todoapp/internal/app/model"
+import (
+	"time"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+)
 
 type UserRepository interface {
 	Create(*model.User) error
 	FindByEmail(string) (*model.User, error)
 	FindById(int) (*model.User, error)
+	CreatePasswordReset(*model.PasswordReset) error
+	ResetPassword(string, string) (*model.User, error)
+	CreateVerification(*model.EmailVerification) error
+	Verify(string) (*model.User, error)
 }
 
 type TaskRepository interface {
 	Create(*model.Task) error
 	Delete(int, int) error
 	Done(int, int) error
+	Update(int, *model.Task) error
 	GetAll(int) ([]*model.Task, error)
+	Find(int, *TaskQuery) ([]*model.Task, string, error)
 	GetBool(int, bool) ([]*model.Task, error)
 	GetById(int, int) (*model.Task, error)
+	GetDue(int, *time.Time, *time.Time) ([]*model.Task, error)
+	GetOverdue(int, time.Time) ([]*model.Task, error)
+	GetTrash(int) ([]*model.Task, error)
+	Restore(int, int) error
+	Purge(int, int) error
+	EmptyTrash(int) error
+	PurgeDeleted(time.Time) (int, error)
+	History(int, int) ([]*model.TaskEvent, error)
+	Search(int, string, int) ([]*model.TaskMatch, error)
+	Batch(int, []*TaskOperation, bool) ([]*TaskOperationResult, error)
+	DeleteDone(int) (int, error)
+	CompleteAll(int) (int, []int, error)
+	Move(int, int, int, bool) error
+	AddBlocker(int, int, int) error
+	RemoveBlocker(int, int, int) error
+	ReportHistory(int, *ReportScope) ([]*model.Task, []*model.TaskEvent, error)
+}
+
+type TagRepository interface {
+	Create(*model.Tag) error
+	Rename(int, int, string) (*model.Tag, error)
+	Delete(int, int) error
+	GetAll(int) ([]*model.Tag, error)
+	Attach(int, int, int) error
+	Detach(int, int, int) error
+}
+
+type ProjectRepository interface {
+	Create(*model.Project) error
+	GetById(int, int) (*model.Project, error)
+	GetAll(int) ([]*model.Project, error)
+	Update(*model.Project) error
+	Delete(int, int, *int) error
+	DeleteWithTasks(int, int) error
+	GetByOrganization(int) ([]*model.Project, error)
+}
+
+type WorkflowRepository interface {
+	Get(int, *int) (*model.Workflow, error)
+	Save(*model.Workflow) error
+	Delete(int, *int) error
+}
+
+type CommentRepository interface {
+	Create(*model.Comment) error
+	GetByTask(int, int) ([]*model.Comment, error)
+	Update(*model.Comment) error
+	Delete(int, int, int) error
+}
+
+type ShareRepository interface {
+	Create(*model.Share) error
+	GetByTask(int, int) ([]*model.Share, error)
+	GetByProject(int, int) ([]*model.Share, error)
+	SharedWith(int) ([]*model.Share, error)
+	Delete(int, int) error
+}
+
+type OrganizationRepository interface {
+	Create(*model.Organization, int) error
+	GetById(int, int) (*model.Organization, error)
+	GetAll(int) ([]*model.Organization, error)
+	Update(*model.Organization) error
+	Delete(int, int) error
+	GetMembers(int) ([]*model.Member, error)
+	SetRole(*model.Member) error
+	RemoveMember(int, int, int) error
+	Invite(*model.Invitation) error
+	GetInvitations(int) ([]*model.Invitation, error)
+	DeleteInvitation(int, int) error
+	Accept(*model.User, string) (*model.Organization, error)
+}
+
+type AttachmentRepository interface {
+	Create(*model.Attachment) error
+	GetByTask(int, int) ([]*model.Attachment, error)
+	GetById(int, int, int) (*model.Attachment, error)
+	Delete(int, int, int) error
+	Orphans() ([]*model.Attachment, error)
+	DeleteOrphan(int) error
+}
+
+type TimeEntryRepository interface {
+	Start(int, int) (*model.TimeEntry, error)
+	Stop(int, int) (*model.TimeEntry, error)
+	Running(int) (*model.TimeEntry, error)
+	Create(*model.TimeEntry) error
+	Delete(int, int) error
+	GetByTask(int, int) ([]*model.TimeEntry, error)
+	Find(int, time.Time, time.Time) ([]*model.TimeEntry, error)
 }
@@ -0,0 +1,116 @@
+package sqlstore
+
+import (
+	"database/sql"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/pyuldashev912/todoapp/internal/app/store"
+)
+
+type AttachmentRepository struct {
+	store *Store
+}
+
+// attachmentColumns are selected in the order scanAttachment reads them. Attachments
+// of purged tasks have no task anymore.
+const attachmentColumns = "id, COALESCE(task_id, 0), user_id, name, content_type, size, key, created_at"
+
+// Create records an attachment of the task whose content is already in the blob store
+func (r *AttachmentRepository) Create(a *model.Attachment) error {
+	if err := a.Validate(); err != nil {
+		return err
+	}
+
+	if err := checkEditable(r.store.db, a.UserID, a.TaskID); err != nil {
+		return err
+	}
+
+	return r.store.db.QueryRow(
+		"INSERT INTO attachments (task_id, user_id, name, content_type, size, key) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at",
+		a.TaskID, a.UserID, a.Name, a.ContentType, a.Size, a.Key,
+	).Scan(&a.ID, &a.CreatedAt)
+}
+
+// GetByTask gets all attachments of the task from the oldest
+func (r *AttachmentRepository) GetByTask(userId int, taskId int) ([]*model.Attachment, error) {
+	if _, err := getTask(r.store.db, userId, taskId); err != nil {
+		return nil, err
+	}
+
+	return r.query("SELECT "+attachmentColumns+" FROM attachments WHERE task_id=$1 ORDER BY created_at, id", taskId)
+}
+
+// GetById gets an attachment of the task
+func (r *AttachmentRepository) GetById(userId int, taskId int, attachmentId int) (*model.Attachment, error) {
+	if _, err := getTask(r.store.db, userId, taskId); err != nil {
+		return nil, err
+	}
+
+	a, err := scanAttachment(r.store.db.QueryRow(
+		"SELECT "+attachmentColumns+" FROM attachments WHERE id=$1 AND task_id=$2", attachmentId, taskId,
+	))
+	if err == sql.ErrNoRows {
+		return nil, store.ErrInvalidAttachmentId
+	}
+
+	return a, err
+}
+
+// Delete deletes the record of an attachment of the task, the blob has to be
+// deleted by the caller
+func (r *AttachmentRepository) Delete(userId int, taskId int, attachmentId int) error {
+	if err := checkEditable(r.store.db, userId, taskId); err != nil {
+		return err
+	}
+
+	res, err := r.store.db.Exec("DELETE FROM attachments WHERE id=$1 AND task_id=$2", attachmentId, taskId)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res, store.ErrInvalidAttachmentId)
+}
+
+// Orphans gets the attachments left behind by purged tasks
+func (r *AttachmentRepository) Orphans() ([]*model.Attachment, error) {
+	return r.query("SELECT " + attachmentColumns + " FROM attachments WHERE task_id IS NULL ORDER BY id")
+}
+
+// DeleteOrphan deletes the record of an attachment left behind by a purged task
+func (r *AttachmentRepository) DeleteOrphan(attachmentId int) error {
+	res, err := r.store.db.Exec("DELETE FROM attachments WHERE id=$1 AND task_id IS NULL", attachmentId)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res, store.ErrInvalidAttachmentId)
+}
+
+func (r *AttachmentRepository) query(query string, args ...interface{}) ([]*model.Attachment, error) {
+	rows, err := r.store.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attachments := []*model.Attachment{}
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		attachments = append(attachments, a)
+	}
+
+	return attachments, rows.Err()
+}
+
+func scanAttachment(s scanner) (*model.Attachment, error) {
+	a := &model.Attachment{}
+	if err := s.Scan(&a.ID, &a.TaskID, &a.UserID, &a.Name, &a.ContentType, &a.Size, &a.Key, &a.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
@@ -0,0 +1,99 @@
+package sqlstore_test
+
+import (
+	"testing"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/pyuldashev912/todoapp/internal/app/store"
+	"github.com/pyuldashev912/todoapp/internal/app/store/sqlstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttachmentRepository_Create(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseURL)
+	defer teardown("tasks", "attachments")
+
+	s := sqlstore.New(db)
+	task := model.TestTask(t)
+	s.Task().Create(task)
+
+	a := model.TestAttachment(t)
+	a.TaskID = task.ID
+	assert.NoError(t, s.Attachment().Create(a))
+	assert.NotZero(t, a.ID)
+
+	invalid := model.TestAttachment(t)
+	invalid.TaskID = task.ID
+	invalid.ContentType = "application/zip"
+	assert.Error(t, s.Attachment().Create(invalid))
+
+	other := model.TestAttachment(t)
+	other.TaskID = task.ID
+	other.UserID = 2
+	other.Key = "tasks/1/other"
+	assert.EqualError(t, s.Attachment().Create(other), store.ErrInvalidTaskId.Error())
+
+	attachments, err := s.Attachment().GetByTask(1, task.ID)
+	assert.NoError(t, err)
+	assert.Len(t, attachments, 1)
+	assert.Equal(t, a.Name, attachments[0].Name)
+
+	found, err := s.Attachment().GetById(1, task.ID, a.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, a.Key, found.Key)
+
+	_, err = s.Attachment().GetById(1, task.ID, a.ID+1)
+	assert.EqualError(t, err, store.ErrInvalidAttachmentId.Error())
+}
+
+func TestAttachmentRepository_Delete(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseURL)
+	defer teardown("tasks", "attachments")
+
+	s := sqlstore.New(db)
+	task := model.TestTask(t)
+	s.Task().Create(task)
+	a := model.TestAttachment(t)
+	a.TaskID = task.ID
+	s.Attachment().Create(a)
+
+	assert.EqualError(t, s.Attachment().Delete(2, task.ID, a.ID), store.ErrInvalidTaskId.Error())
+	assert.NoError(t, s.Attachment().Delete(1, task.ID, a.ID))
+	assert.EqualError(t, s.Attachment().Delete(1, task.ID, a.ID), store.ErrInvalidAttachmentId.Error())
+
+	attachments, err := s.Attachment().GetByTask(1, task.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, attachments)
+}
+
+func TestAttachmentRepository_Orphans(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseURL)
+	defer teardown("tasks", "attachments")
+
+	s := sqlstore.New(db)
+	task := model.TestTask(t)
+	s.Task().Create(task)
+	a := model.TestAttachment(t)
+	a.TaskID = task.ID
+	s.Attachment().Create(a)
+
+	orphans, err := s.Attachment().Orphans()
+	assert.NoError(t, err)
+	assert.Empty(t, orphans)
+	assert.EqualError(t, s.Attachment().DeleteOrphan(a.ID), store.ErrInvalidAttachmentId.Error())
+
+	// Purging the task leaves the attachment behind until its blob is deleted
+	s.Task().Delete(1, task.ID)
+	assert.NoError(t, s.Task().Purge(1, task.ID))
+
+	orphans, err = s.Attachment().Orphans()
+	assert.NoError(t, err)
+	if assert.Len(t, orphans, 1) {
+		assert.Equal(t, a.Key, orphans[0].Key)
+		assert.Zero(t, orphans[0].TaskID)
+	}
+
+	assert.NoError(t, s.Attachment().DeleteOrphan(a.ID))
+	orphans, _ = s.Attachment().Orphans()
+	assert.Empty(t, orphans)
+}
@@ -0,0 +1,112 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/pyuldashev912/todoapp/internal/app/store"
+)
+
+type CommentRepository struct {
+	store *Store
+}
+
+// Create adds a comment to the task
+func (r *CommentRepository) Create(c *model.Comment) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	if err := checkEditable(r.store.db, c.UserID, c.TaskID); err != nil {
+		return err
+	}
+
+	return r.store.db.QueryRow(
+		"INSERT INTO comments (task_id, user_id, body) VALUES ($1, $2, $3) RETURNING id, created_at",
+		c.TaskID, c.UserID, c.Body,
+	).Scan(&c.ID, &c.CreatedAt)
+}
+
+// GetByTask gets all comments of the task from the oldest
+func (r *CommentRepository) GetByTask(userId int, taskId int) ([]*model.Comment, error) {
+	if _, err := getTask(r.store.db, userId, taskId); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.store.db.Query(
+		"SELECT id, task_id, user_id, body, created_at, updated_at FROM comments WHERE task_id=$1 ORDER BY created_at, id",
+		taskId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	comments := []*model.Comment{}
+	for rows.Next() {
+		c := &model.Comment{}
+		if err := rows.Scan(&c.ID, &c.TaskID, &c.UserID, &c.Body, &c.CreatedAt, &c.UpdatedAt); err != nil {
+			return nil, err
+		}
+
+		comments = append(comments, c)
+	}
+
+	return comments, rows.Err()
+}
+
+// Update changes the body of a comment, only its author can do that
+func (r *CommentRepository) Update(c *model.Comment) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	return r.store.inTx(func(tx *sql.Tx) error {
+		if err := checkAuthor(tx, c.UserID, c.TaskID, c.ID); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		c.UpdatedAt = &now
+		return tx.QueryRow(
+			"UPDATE comments SET body=$1, updated_at=$2 WHERE id=$3 RETURNING created_at",
+			c.Body, c.UpdatedAt, c.ID,
+		).Scan(&c.CreatedAt)
+	})
+}
+
+// Delete deletes a comment, only its author can do that
+func (r *CommentRepository) Delete(userId int, taskId int, commentId int) error {
+	return r.store.inTx(func(tx *sql.Tx) error {
+		if err := checkAuthor(tx, userId, taskId, commentId); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec("DELETE FROM comments WHERE id=$1", commentId)
+		return err
+	})
+}
+
+// checkAuthor makes sure the comment of the task exists and was written by the user
+func checkAuthor(q querier, userId int, taskId int, commentId int) error {
+	if _, err := getTask(q, userId, taskId); err != nil {
+		return err
+	}
+
+	var authorId int
+	if err := q.QueryRow(
+		"SELECT user_id FROM comments WHERE id=$1 and task_id=$2 FOR UPDATE", commentId, taskId,
+	).Scan(&authorId); err != nil {
+		if err == sql.ErrNoRows {
+			return store.ErrInvalidCommentId
+		}
+		return err
+	}
+
+	if authorId != userId {
+		return store.ErrNotCommentAuthor
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package sqlstore_test
+
+import (
+	"testing"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/pyuldashev912/todoapp/internal/app/store"
+	"github.com/pyuldashev912/todoapp/internal/app/store/sqlstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommentRepository_Create(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseURL)
+	defer teardown("tasks", "comments")
+
+	s := sqlstore.New(db)
+	task := model.TestTask(t)
+	s.Task().Create(task)
+
+	c := model.TestComment(t)
+	c.TaskID = task.ID
+	assert.NoError(t, s.Comment().Create(c))
+	assert.NotZero(t, c.ID)
+
+	empty := model.TestComment(t)
+	empty.TaskID = task.ID
+	empty.Body = ""
+	assert.Error(t, s.Comment().Create(empty))
+
+	other := model.TestComment(t)
+	other.TaskID = task.ID
+	other.UserID = 2
+	assert.EqualError(t, s.Comment().Create(other), store.ErrInvalidTaskId.Error())
+
+	comments, err := s.Comment().GetByTask(1, task.ID)
+	assert.NoError(t, err)
+	assert.Len(t, comments, 1)
+	assert.Equal(t, c.Body, comments[0].Body)
+
+	// Listings count the comments
+	found, _ := s.Task().GetById(1, task.ID)
+	assert.Equal(t, 1, found.CommentCount)
+}
+
+func TestCommentRepository_Update(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseURL)
+	defer teardown("tasks", "comments")
+
+	s := sqlstore.New(db)
+	task := model.TestTask(t)
+	s.Task().Create(task)
+	c := model.TestComment(t)
+	c.TaskID = task.ID
+	s.Comment().Create(c)
+
+	edited := &model.Comment{ID: c.ID, TaskID: task.ID, UserID: 1, Body: "Edited"}
+	assert.NoError(t, s.Comment().Update(edited))
+	assert.NotNil(t, edited.UpdatedAt)
+
+	comments, _ := s.Comment().GetByTask(1, task.ID)
+	assert.Equal(t, "Edited", comments[0].Body)
+
+	// Only the author can change a comment
+	_, err := db.Exec("INSERT INTO comments (task_id, user_id, body) VALUES ($1, 2, 'Mine')", task.ID)
+	assert.NoError(t, err)
+	comments, _ = s.Comment().GetByTask(1, task.ID)
+	foreign := &model.Comment{ID: comments[1].ID, TaskID: task.ID, UserID: 1, Body: "Edited"}
+	assert.EqualError(t, s.Comment().Update(foreign), store.ErrNotCommentAuthor.Error())
+
+	missing := &model.Comment{ID: c.ID + 1, TaskID: task.ID, UserID: 1, Body: "Edited"}
+	assert.EqualError(t, s.Comment().Update(missing), store.ErrInvalidCommentId.Error())
+}
+
+func TestCommentRepository_Delete(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseURL)
+	defer teardown("tasks", "comments")
+
+	s := sqlstore.New(db)
+	task := model.TestTask(t)
+	s.Task().Create(task)
+	c := model.TestComment(t)
+	c.TaskID = task.ID
+	s.Comment().Create(c)
+
+	assert.EqualError(t, s.Comment().Delete(2, task.ID, c.ID), store.ErrInvalidTaskId.Error())
+	assert.NoError(t, s.Comment().Delete(1, task.ID, c.ID))
+	assert.EqualError(t, s.Comment().Delete(1, task.ID, c.ID), store.ErrInvalidCommentId.Error())
+
+	comments, err := s.Comment().GetByTask(1, task.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, comments)
+}
@@ -0,0 +1,367 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/pyuldashev912/todoapp/internal/app/store"
+)
+
+// invitationColumns are selected in the order scanInvitation reads them
+const invitationColumns = "id, organization_id, email, role, invited_by, expires_at, created_at"
+
+type OrganizationRepository struct {
+	store *Store
+}
+
+// Create creates an organization owned by the user
+func (r *OrganizationRepository) Create(org *model.Organization, ownerId int) error {
+	if err := org.Validate(); err != nil {
+		return err
+	}
+
+	return r.store.inTx(func(tx *sql.Tx) error {
+		if err := tx.QueryRow(
+			"INSERT INTO organizations (name) VALUES ($1) RETURNING id, created_at", org.Name,
+		).Scan(&org.ID, &org.CreatedAt); err != nil {
+			return err
+		}
+
+		org.Role = model.OrgRoleOwner
+		_, err := tx.Exec(
+			"INSERT INTO memberships (organization_id, user_id, role) VALUES ($1, $2, $3)",
+			org.ID, ownerId, org.Role,
+		)
+
+		return err
+	})
+}
+
+// GetById returns an organization of the user together with their role in it
+func (r *OrganizationRepository) GetById(userId int, orgId int) (*model.Organization, error) {
+	org := &model.Organization{}
+	if err := r.store.db.QueryRow(`
+	SELECT organizations.id, organizations.name, memberships.role, organizations.created_at
+	FROM organizations JOIN memberships ON memberships.organization_id = organizations.id
+	WHERE memberships.user_id=$1 and organizations.id=$2`,
+		userId, orgId,
+	).Scan(&org.ID, &org.Name, &org.Role, &org.CreatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, store.ErrInvalidOrganizationId
+		}
+		return nil, err
+	}
+
+	return org, nil
+}
+
+// GetAll gets the organizations the user is a member of
+func (r *OrganizationRepository) GetAll(userId int) ([]*model.Organization, error) {
+	rows, err := r.store.db.Query(`
+	SELECT organizations.id, organizations.name, memberships.role, organizations.created_at
+	FROM organizations JOIN memberships ON memberships.organization_id = organizations.id
+	WHERE memberships.user_id=$1 ORDER BY organizations.id`,
+		userId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orgs := []*model.Organization{}
+	for rows.Next() {
+		org := &model.Organization{}
+		if err := rows.Scan(&org.ID, &org.Name, &org.Role, &org.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		orgs = append(orgs, org)
+	}
+
+	return orgs, rows.Err()
+}
+
+// Update renames an organization
+func (r *OrganizationRepository) Update(org *model.Organization) error {
+	if err := org.Validate(); err != nil {
+		return err
+	}
+
+	res, err := r.store.db.Exec("UPDATE organizations SET name=$1 WHERE id=$2", org.Name, org.ID)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res, store.ErrInvalidOrganizationId)
+}
+
+// Delete deletes an organization, its projects stay with the users who
+// created them and the other members are taken off their tasks
+func (r *OrganizationRepository) Delete(userId int, orgId int) error {
+	return r.store.inTx(func(tx *sql.Tx) error {
+		projects, err := orgProjects(tx, orgId)
+		if err != nil {
+			return err
+		}
+
+		res, err := tx.Exec("DELETE FROM organizations WHERE id=$1", orgId)
+		if err != nil {
+			return err
+		}
+
+		if err := checkAffected(res, store.ErrInvalidOrganizationId); err != nil {
+			return err
+		}
+
+		return unassignProjects(tx, userId, projects)
+	})
+}
+
+// GetMembers gets the members of an organization in the order they joined it
+func (r *OrganizationRepository) GetMembers(orgId int) ([]*model.Member, error) {
+	rows, err := r.store.db.Query(`
+	SELECT memberships.organization_id, memberships.user_id, COALESCE(users.email, ''), memberships.role,
+		memberships.created_at
+	FROM memberships LEFT JOIN users ON users.id = memberships.user_id
+	WHERE memberships.organization_id=$1 ORDER BY memberships.created_at, memberships.user_id`,
+		orgId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []*model.Member{}
+	for rows.Next() {
+		m := &model.Member{}
+		if err := rows.Scan(&m.OrganizationID, &m.UserID, &m.Email, &m.Role, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		members = append(members, m)
+	}
+
+	return members, rows.Err()
+}
+
+// SetRole changes the role of a member, the owner keeps theirs
+func (r *OrganizationRepository) SetRole(m *model.Member) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	return r.store.inTx(func(tx *sql.Tx) error {
+		if err := checkMember(tx, m.OrganizationID, m.UserID); err != nil {
+			return err
+		}
+
+		return tx.QueryRow(
+			"UPDATE memberships SET role=$1 WHERE organization_id=$2 and user_id=$3 RETURNING created_at",
+			m.Role, m.OrganizationID, m.UserID,
+		).Scan(&m.CreatedAt)
+	})
+}
+
+// RemoveMember takes a user out of an organization and off the tasks assigned
+// to them in its projects, the owner can't leave it
+func (r *OrganizationRepository) RemoveMember(userId int, orgId int, memberId int) error {
+	return r.store.inTx(func(tx *sql.Tx) error {
+		if err := checkMember(tx, orgId, memberId); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(
+			"DELETE FROM memberships WHERE organization_id=$1 and user_id=$2", orgId, memberId,
+		); err != nil {
+			return err
+		}
+
+		projects, err := orgProjects(tx, orgId)
+		if err != nil {
+			return err
+		}
+
+		return unassignProjects(tx, userId, projects)
+	})
+}
+
+// Invite creates an invitation to an organization replacing the previous
+// invitations of the same email
+func (r *OrganizationRepository) Invite(i *model.Invitation) error {
+	if err := i.Validate(); err != nil {
+		return err
+	}
+
+	if err := i.BeforeCreate(time.Now()); err != nil {
+		return err
+	}
+
+	return r.store.inTx(func(tx *sql.Tx) error {
+		var member bool
+		if err := tx.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM memberships JOIN users ON users.id = memberships.user_id
+			WHERE memberships.organization_id=$1 and lower(users.email)=lower($2))`,
+			i.OrganizationID, i.Email,
+		).Scan(&member); err != nil {
+			return err
+		}
+
+		if member {
+			return store.ErrAlreadyMember
+		}
+
+		if _, err := tx.Exec(
+			"DELETE FROM invitations WHERE organization_id=$1 and lower(email)=lower($2)", i.OrganizationID, i.Email,
+		); err != nil {
+			return err
+		}
+
+		return tx.QueryRow(`
+		INSERT INTO invitations (organization_id, email, role, token_hash, invited_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`,
+			i.OrganizationID, i.Email, i.Role, i.TokenHash, i.InvitedBy, i.ExpiresAt,
+		).Scan(&i.ID, &i.CreatedAt)
+	})
+}
+
+// GetInvitations gets the pending invitations of an organization
+func (r *OrganizationRepository) GetInvitations(orgId int) ([]*model.Invitation, error) {
+	rows, err := r.store.db.Query(
+		"SELECT "+invitationColumns+" FROM invitations WHERE organization_id=$1 and expires_at > now() ORDER BY id",
+		orgId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []*model.Invitation{}
+	for rows.Next() {
+		i, err := scanInvitation(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		invitations = append(invitations, i)
+	}
+
+	return invitations, rows.Err()
+}
+
+// DeleteInvitation revokes an invitation of an organization
+func (r *OrganizationRepository) DeleteInvitation(orgId int, invitationId int) error {
+	res, err := r.store.db.Exec(
+		"DELETE FROM invitations WHERE organization_id=$1 and id=$2", orgId, invitationId,
+	)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res, store.ErrInvalidInvitation)
+}
+
+// Accept makes the user a member of the organization they were invited to
+// with the token. The invitation must be for their verified email and can
+// only be used once.
+func (r *OrganizationRepository) Accept(user *model.User, token string) (*model.Organization, error) {
+	org := &model.Organization{}
+	if err := r.store.inTx(func(tx *sql.Tx) error {
+		i, err := scanInvitation(tx.QueryRow(
+			"SELECT "+invitationColumns+" FROM invitations WHERE token_hash=$1 FOR UPDATE", model.HashToken(token),
+		))
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return store.ErrInvalidInvitation
+			}
+			return err
+		}
+
+		if !i.ExpiresAt.After(time.Now()) || !strings.EqualFold(i.Email, user.Email) {
+			return store.ErrInvalidInvitation
+		}
+
+		if !user.Verified {
+			return store.ErrUnverifiedInvitee
+		}
+
+		if _, err := tx.Exec("DELETE FROM invitations WHERE id=$1", i.ID); err != nil {
+			return err
+		}
+
+		res, err := tx.Exec(`
+		INSERT INTO memberships (organization_id, user_id, role) VALUES ($1, $2, $3)
+		ON CONFLICT (organization_id, user_id) DO NOTHING`,
+			i.OrganizationID, user.ID, i.Role,
+		)
+		if err != nil {
+			return err
+		}
+
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			if err == nil {
+				err = store.ErrAlreadyMember
+			}
+			return err
+		}
+
+		org.Role = i.Role
+		return tx.QueryRow(
+			"SELECT id, name, created_at FROM organizations WHERE id=$1", i.OrganizationID,
+		).Scan(&org.ID, &org.Name, &org.CreatedAt)
+	}); err != nil {
+		return nil, err
+	}
+
+	return org, nil
+}
+
+func scanInvitation(row scanner) (*model.Invitation, error) {
+	i := &model.Invitation{}
+	if err := row.Scan(&i.ID, &i.OrganizationID, &i.Email, &i.Role, &i.InvitedBy, &i.ExpiresAt, &i.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	return i, nil
+}
+
+// checkMember makes sure the user is a member of the organization other than its owner
+func checkMember(q querier, orgId int, userId int) error {
+	var role string
+	if err := q.QueryRow(
+		"SELECT role FROM memberships WHERE organization_id=$1 and user_id=$2 FOR UPDATE", orgId, userId,
+	).Scan(&role); err != nil {
+		if err == sql.ErrNoRows {
+			return store.ErrInvalidMember
+		}
+		return err
+	}
+
+	if role == model.OrgRoleOwner {
+		return store.ErrOrganizationOwner
+	}
+
+	return nil
+}
+
+// orgProjects returns the ids of the projects of the organization
+func orgProjects(q querier, orgId int) ([]int64, error) {
+	rows, err := q.Query("SELECT id FROM projects WHERE organization_id=$1", orgId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
@@ -0,0 +1,181 @@
+package sqlstore
+
+import (
+	"database/sql"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/pyuldashev912/todoapp/internal/app/store"
+)
+
+type ProjectRepository struct {
+	store *Store
+}
+
+// Create creates a new project
+func (r *ProjectRepository) Create(project *model.Project) error {
+	if err := project.Validate(); err != nil {
+		return err
+	}
+
+	return r.store.db.QueryRow(
+		"INSERT INTO projects (user_id, name, description, organization_id) VALUES ($1, $2, $3, $4) RETURNING id, created_at",
+		project.UserID, project.Name, project.Description, project.OrganizationID,
+	).Scan(&project.ID, &project.CreatedAt)
+}
+
+// GetById returns User's project by id
+func (r *ProjectRepository) GetById(userId int, projectId int) (*model.Project, error) {
+	p := &model.Project{}
+	if err := r.store.db.QueryRow(
+		"SELECT id, user_id, name, description, organization_id, created_at FROM projects WHERE user_id=$1 and id=$2",
+		userId, projectId,
+	).Scan(&p.ID, &p.UserID, &p.Name, &p.Description, &p.OrganizationID, &p.CreatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, store.ErrInvalidProjectId
+		}
+
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// GetAll gets all User's projects in creation order
+func (r *ProjectRepository) GetAll(userId int) ([]*model.Project, error) {
+	rows, err := r.store.db.Query(
+		"SELECT id, user_id, name, description, organization_id, created_at FROM projects WHERE user_id=$1 ORDER BY id",
+		userId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	projects := make([]*model.Project, 0, 5)
+	for rows.Next() {
+		p := &model.Project{}
+		if err := rows.Scan(&p.ID, &p.UserID, &p.Name, &p.Description, &p.OrganizationID, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		projects = append(projects, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(projects) == 0 {
+		return nil, store.ErrNoRecordsInTable
+	}
+
+	return projects, nil
+}
+
+// Update saves the name and the description of a project
+func (r *ProjectRepository) Update(project *model.Project) error {
+	if err := project.Validate(); err != nil {
+		return err
+	}
+
+	res, err := r.store.db.Exec(
+		"UPDATE projects SET name=$1, description=$2 WHERE user_id=$3 and id=$4",
+		project.Name, project.Description, project.UserID, project.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res, store.ErrInvalidProjectId)
+}
+
+// Delete deletes a project moving its tasks into the target project,
+// or out of any project if target is nil
+func (r *ProjectRepository) Delete(userId int, projectId int, target *int) error {
+	if target != nil {
+		if *target == projectId {
+			return store.ErrInvalidProjectId
+		}
+
+		if _, err := r.GetById(userId, *target); err != nil {
+			return err
+		}
+	}
+
+	return r.store.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(
+			"UPDATE tasks SET project_id=$1 WHERE user_id=$2 and project_id=$3", target, userId, projectId,
+		); err != nil {
+			return err
+		}
+
+		if err := r.delete(tx, userId, projectId); err != nil {
+			return err
+		}
+
+		// The members of the project leave it and the moved tasks follow another workflow now
+		if err := unassign(tx, userId, userId); err != nil {
+			return err
+		}
+
+		return remapStatuses(tx, userId)
+	})
+}
+
+// DeleteWithTasks deletes a project moving all its tasks with their subtasks to the trash
+func (r *ProjectRepository) DeleteWithTasks(userId int, projectId int) error {
+	return r.store.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM tasks WHERE user_id=$1 and project_id=$2 and deleted_at IS NULL
+			UNION ALL
+			SELECT tasks.id FROM tasks JOIN subtree ON tasks.parent_id = subtree.id and tasks.deleted_at IS NULL
+		), deleted AS (
+			UPDATE tasks SET deleted_at=now() WHERE id IN (SELECT id FROM subtree) RETURNING id
+		)
+		INSERT INTO task_events (task_id, user_id, action) SELECT id, $1, $3 FROM deleted`,
+			userId, projectId, model.ActionDelete,
+		); err != nil {
+			return err
+		}
+
+		if err := r.delete(tx, userId, projectId); err != nil {
+			return err
+		}
+
+		return unassign(tx, userId, userId)
+	})
+}
+
+// GetByOrganization gets the projects of an organization in creation order
+func (r *ProjectRepository) GetByOrganization(orgId int) ([]*model.Project, error) {
+	rows, err := r.store.db.Query(
+		"SELECT id, user_id, name, description, organization_id, created_at FROM projects WHERE organization_id=$1 ORDER BY id",
+		orgId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	projects := []*model.Project{}
+	for rows.Next() {
+		p := &model.Project{}
+		if err := rows.Scan(&p.ID, &p.UserID, &p.Name, &p.Description, &p.OrganizationID, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		projects = append(projects, p)
+	}
+
+	return projects, rows.Err()
+}
+
+func (r *ProjectRepository) delete(tx *sql.Tx, userId int, projectId int) error {
+	res, err := tx.Exec("DELETE FROM projects WHERE user_id=$1 and id=$2", userId, projectId)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res, store.ErrInvalidProjectId)
+}
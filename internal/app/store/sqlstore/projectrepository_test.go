@@ -0,0 +1,118 @@
+package sqlstore_test
+
+import (
+	"testing"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/pyuldashev912/todoapp/internal/app/store"
+	"github.com/pyuldashev912/todoapp/internal/app/store/sqlstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProjectRepository_Create(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseURL)
+	defer teardown("projects")
+
+	s := sqlstore.New(db)
+	p := model.TestProject(t)
+	assert.NoError(t, s.Project().Create(p))
+	assert.NotZero(t, p.ID)
+	assert.Error(t, s.Project().Create(&model.Project{UserID: 1}))
+}
+
+func TestProjectRepository_GetById(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseURL)
+	defer teardown("projects")
+
+	s := sqlstore.New(db)
+	p := model.TestProject(t)
+	s.Project().Create(p)
+
+	res, err := s.Project().GetById(p.UserID, p.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, p.Name, res.Name)
+
+	_, err = s.Project().GetById(2, p.ID)
+	assert.EqualError(t, err, store.ErrInvalidProjectId.Error())
+}
+
+func TestProjectRepository_GetAll(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseURL)
+	defer teardown("projects")
+
+	s := sqlstore.New(db)
+	_, err := s.Project().GetAll(1)
+	assert.EqualError(t, err, store.ErrNoRecordsInTable.Error())
+
+	s.Project().Create(model.TestProject(t))
+	s.Project().Create(model.TestProject(t))
+	res, err := s.Project().GetAll(1)
+	assert.NoError(t, err)
+	assert.Len(t, res, 2)
+}
+
+func TestProjectRepository_Update(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseURL)
+	defer teardown("projects")
+
+	s := sqlstore.New(db)
+	p := model.TestProject(t)
+	s.Project().Create(p)
+
+	p.Name = "Sprint"
+	assert.NoError(t, s.Project().Update(p))
+	res, _ := s.Project().GetById(p.UserID, p.ID)
+	assert.Equal(t, "Sprint", res.Name)
+
+	p.ID++
+	assert.EqualError(t, s.Project().Update(p), store.ErrInvalidProjectId.Error())
+}
+
+func TestProjectRepository_Delete(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseURL)
+	defer teardown("tasks", "projects")
+
+	s := sqlstore.New(db)
+	p1, p2 := model.TestProject(t), model.TestProject(t)
+	s.Project().Create(p1)
+	s.Project().Create(p2)
+
+	task := model.TestTask(t)
+	task.ProjectID = &p1.ID
+	assert.NoError(t, s.Task().Create(task))
+
+	// Reassign tasks to another project
+	assert.EqualError(t, s.Project().Delete(1, p1.ID, &p1.ID), store.ErrInvalidProjectId.Error())
+	assert.NoError(t, s.Project().Delete(1, p1.ID, &p2.ID))
+	res, _ := s.Task().GetById(task.UserID, task.ID)
+	assert.Equal(t, p2.ID, *res.ProjectID)
+
+	// Leave tasks without a project
+	assert.NoError(t, s.Project().Delete(1, p2.ID, nil))
+	res, _ = s.Task().GetById(task.UserID, task.ID)
+	assert.Nil(t, res.ProjectID)
+
+	assert.EqualError(t, s.Project().Delete(1, p2.ID, nil), store.ErrInvalidProjectId.Error())
+}
+
+func TestProjectRepository_DeleteWithTasks(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseURL)
+	defer teardown("tasks", "projects")
+
+	s := sqlstore.New(db)
+	p := model.TestProject(t)
+	s.Project().Create(p)
+
+	task := model.TestTask(t)
+	task.ProjectID = &p.ID
+	s.Task().Create(task)
+	s.Task().Create(model.TestTask(t))
+
+	assert.NoError(t, s.Project().DeleteWithTasks(1, p.ID))
+	_, err := s.Task().GetById(task.UserID, task.ID)
+	assert.EqualError(t, err, store.ErrInvalidTaskId.Error())
+
+	res, err := s.Task().GetAll(1)
+	assert.NoError(t, err)
+	assert.Len(t, res, 1)
+}
@@ -0,0 +1,275 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/pyuldashev912/todoapp/internal/app/store"
+)
+
+// sharedWith is a condition on tasks which holds for the tasks shared with
+// the user $1, either directly or through their project
+const sharedWith = `EXISTS (SELECT 1 FROM shares WHERE shares.user_id=$1
+	and (shares.task_id = tasks.id or shares.project_id = tasks.project_id))`
+
+// orgMember is a condition on tasks which holds for the tasks of the projects
+// of the organizations the user $1 is a member of
+const orgMember = `EXISTS (SELECT 1 FROM projects JOIN memberships ON memberships.organization_id = projects.organization_id
+	WHERE projects.id = tasks.project_id and memberships.user_id=$1)`
+
+// visibleTo is a condition on tasks which holds for the tasks the user $1
+// owns or which are shared with them, directly or through an organization
+const visibleTo = "(tasks.user_id=$1 or " + sharedWith + " or " + orgMember + ")"
+
+// assigneeMember is a condition on tasks which holds when their assignee is
+// still a member of their project, either through a share or an organization
+const assigneeMember = `(EXISTS (SELECT 1 FROM shares WHERE shares.project_id = tasks.project_id
+		and shares.user_id = tasks.assignee_id)
+	or EXISTS (SELECT 1 FROM projects JOIN memberships ON memberships.organization_id = projects.organization_id
+		WHERE projects.id = tasks.project_id and memberships.user_id = tasks.assignee_id))`
+
+// shareColumns are selected in the order scanShare reads them
+const shareColumns = `shares.id, shares.owner_id, shares.user_id, COALESCE(users.email, ''), shares.task_id,
+	shares.project_id, shares.role, shares.created_at`
+
+type ShareRepository struct {
+	store *Store
+}
+
+// Create shares a task or a project of its owner with another user. Sharing
+// the same item with the same user again changes the role.
+func (r *ShareRepository) Create(s *model.Share) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+
+	if s.UserID == s.OwnerID {
+		return store.ErrShareWithOwner
+	}
+
+	column, targetId := "task_id", s.TaskID
+	if s.ProjectID != nil {
+		column, targetId = "project_id", s.ProjectID
+	}
+
+	if err := checkShareTarget(r.store.db, s.OwnerID, s.TaskID, s.ProjectID); err != nil {
+		return err
+	}
+
+	return r.store.db.QueryRow(fmt.Sprintf(`
+	INSERT INTO shares (owner_id, user_id, %[1]s, role) VALUES ($1, $2, $3, $4)
+	ON CONFLICT (%[1]s, user_id) DO UPDATE SET role=EXCLUDED.role RETURNING id, created_at`, column),
+		s.OwnerID, s.UserID, *targetId, s.Role,
+	).Scan(&s.ID, &s.CreatedAt)
+}
+
+// GetByTask gets the shares of the User's task
+func (r *ShareRepository) GetByTask(ownerId int, taskId int) ([]*model.Share, error) {
+	if err := checkShareTarget(r.store.db, ownerId, &taskId, nil); err != nil {
+		return nil, err
+	}
+
+	return r.query(
+		"SELECT "+shareColumns+" FROM shares LEFT JOIN users ON users.id = shares.user_id WHERE task_id=$1 ORDER BY shares.id",
+		taskId,
+	)
+}
+
+// GetByProject gets the shares of the User's project
+func (r *ShareRepository) GetByProject(ownerId int, projectId int) ([]*model.Share, error) {
+	if err := checkShareTarget(r.store.db, ownerId, nil, &projectId); err != nil {
+		return nil, err
+	}
+
+	return r.query(
+		"SELECT "+shareColumns+" FROM shares LEFT JOIN users ON users.id = shares.user_id WHERE project_id=$1 ORDER BY shares.id",
+		projectId,
+	)
+}
+
+// SharedWith gets the tasks and projects shared with the user, tasks in the
+// trash are left out
+func (r *ShareRepository) SharedWith(userId int) ([]*model.Share, error) {
+	rows, err := r.store.db.Query(`
+	SELECT `+shareColumns+`, COALESCE(tasks.title, projects.name), COALESCE(owners.email, '')
+	FROM shares
+	LEFT JOIN users ON users.id = shares.user_id
+	LEFT JOIN users AS owners ON owners.id = shares.owner_id
+	LEFT JOIN tasks ON tasks.id = shares.task_id
+	LEFT JOIN projects ON projects.id = shares.project_id
+	WHERE shares.user_id=$1 and tasks.deleted_at IS NULL
+	ORDER BY shares.id`,
+		userId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []*model.Share{}
+	for rows.Next() {
+		s := &model.Share{}
+		if err := scanShare(rows, s, &s.Title, &s.SharedBy); err != nil {
+			return nil, err
+		}
+
+		shares = append(shares, s)
+	}
+
+	return shares, rows.Err()
+}
+
+// Delete revokes a share, both its owner and the user it is shared with can do
+// that. A user leaving a project is taken off the tasks assigned to them there.
+func (r *ShareRepository) Delete(userId int, shareId int) error {
+	return r.store.inTx(func(tx *sql.Tx) error {
+		var ownerId int
+		var projectId *int
+		if err := tx.QueryRow(
+			"DELETE FROM shares WHERE id=$1 and (owner_id=$2 or user_id=$2) RETURNING owner_id, project_id",
+			shareId, userId,
+		).Scan(&ownerId, &projectId); err != nil {
+			if err == sql.ErrNoRows {
+				return store.ErrInvalidShareId
+			}
+			return err
+		}
+
+		if projectId == nil {
+			return nil
+		}
+
+		return unassign(tx, userId, ownerId)
+	})
+}
+
+func (r *ShareRepository) query(query string, args ...interface{}) ([]*model.Share, error) {
+	rows, err := r.store.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []*model.Share{}
+	for rows.Next() {
+		s := &model.Share{}
+		if err := scanShare(rows, s); err != nil {
+			return nil, err
+		}
+
+		shares = append(shares, s)
+	}
+
+	return shares, rows.Err()
+}
+
+// scanShare reads a row selected with shareColumns followed by the extra columns
+func scanShare(row scanner, s *model.Share, extra ...interface{}) error {
+	return row.Scan(append([]interface{}{
+		&s.ID, &s.OwnerID, &s.UserID, &s.Email, &s.TaskID, &s.ProjectID, &s.Role, &s.CreatedAt,
+	}, extra...)...)
+}
+
+// unassign takes the tasks of the owner off the assignees who are no longer
+// members of their project, e.g. after leaving it. The change is recorded in
+// the history of the tasks as made by the user.
+func unassign(q querier, userId int, ownerId int) error {
+	return unassignWhere(q, userId, "tasks.user_id=$1", ownerId)
+}
+
+// unassignProjects does the same as unassign for the tasks in the projects,
+// whoever created them
+func unassignProjects(q querier, userId int, projectIds []int64) error {
+	return unassignWhere(q, userId, "tasks.project_id = ANY($1)", pq.Array(projectIds))
+}
+
+// unassignWhere unassigns the tasks matching the condition on its only parameter
+func unassignWhere(q querier, userId int, condition string, arg interface{}) error {
+	rows, err := q.Query(`
+	UPDATE tasks SET assignee_id=NULL FROM tasks AS old
+	WHERE old.id = tasks.id and `+condition+` and tasks.assignee_id <> tasks.user_id
+		and NOT `+assigneeMember+`
+	RETURNING tasks.id, old.assignee_id`,
+		arg,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	changes := map[int]int{}
+	for rows.Next() {
+		var taskId, assigneeId int
+		if err := rows.Scan(&taskId, &assigneeId); err != nil {
+			return err
+		}
+
+		changes[taskId] = assigneeId
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	for taskId, assigneeId := range changes {
+		if err := recordEvent(q, userId, taskId, model.ActionUpdate, map[string]model.Change{
+			"assignee_id": {From: assigneeId, To: nil},
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkShareTarget makes sure the shared task or project belongs to the owner
+func checkShareTarget(q querier, ownerId int, taskId *int, projectId *int) error {
+	query, id, notFound := "SELECT EXISTS (SELECT 1 FROM tasks WHERE user_id=$1 and id=$2 and deleted_at IS NULL)",
+		taskId, store.ErrInvalidTaskId
+	if projectId != nil {
+		query, id, notFound = "SELECT EXISTS (SELECT 1 FROM projects WHERE user_id=$1 and id=$2)",
+			projectId, store.ErrInvalidProjectId
+	}
+
+	var found bool
+	if err := q.QueryRow(query, ownerId, *id).Scan(&found); err != nil {
+		return err
+	}
+
+	if !found {
+		return notFound
+	}
+
+	return nil
+}
+
+// checkEditable makes sure the user can change the task: its owner, the
+// editors it is shared with and the members of the organization of its
+// project can, the viewers get ErrReadOnly
+func checkEditable(q querier, userId int, taskId int) error {
+	var visible, editable bool
+	if err := q.QueryRow(`
+	SELECT `+visibleTo+`, tasks.user_id=$1 or `+orgMember+` or EXISTS (SELECT 1 FROM shares WHERE shares.user_id=$1
+		and (shares.task_id = tasks.id or shares.project_id = tasks.project_id) and shares.role=$3)
+	FROM tasks WHERE id=$2 and deleted_at IS NULL`,
+		userId, taskId, model.RoleEditor,
+	).Scan(&visible, &editable); err != nil {
+		if err == sql.ErrNoRows {
+			return store.ErrInvalidTaskId
+		}
+		return err
+	}
+
+	if !visible {
+		return store.ErrInvalidTaskId
+	}
+
+	if !editable {
+		return store.ErrReadOnly
+	}
+
+	return nil
+}
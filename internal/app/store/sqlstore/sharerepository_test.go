@@ -0,0 +1,199 @@
+package sqlstore_test
+
+import (
+	"testing"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/pyuldashev912/todoapp/internal/app/store"
+	"github.com/pyuldashev912/todoapp/internal/app/store/sqlstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShareRepository_Create(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseURL)
+	defer teardown("tasks", "projects", "shares")
+
+	s := sqlstore.New(db)
+	task := model.TestTask(t)
+	s.Task().Create(task)
+
+	share := model.TestShare(t)
+	share.TaskID = &task.ID
+	assert.NoError(t, s.Share().Create(share))
+	assert.NotZero(t, share.ID)
+
+	// Sharing again changes the role
+	again := model.TestShare(t)
+	again.TaskID = &task.ID
+	again.Role = model.RoleEditor
+	assert.NoError(t, s.Share().Create(again))
+	assert.Equal(t, share.ID, again.ID)
+
+	shares, err := s.Share().GetByTask(1, task.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, shares, 1) {
+		assert.Equal(t, model.RoleEditor, shares[0].Role)
+	}
+
+	self := model.TestShare(t)
+	self.TaskID = &task.ID
+	self.UserID = 1
+	assert.EqualError(t, s.Share().Create(self), store.ErrShareWithOwner.Error())
+
+	// Only the owner shares the task
+	other := model.TestShare(t)
+	other.TaskID = &task.ID
+	other.OwnerID, other.UserID = 2, 3
+	assert.EqualError(t, s.Share().Create(other), store.ErrInvalidTaskId.Error())
+
+	_, err = s.Share().GetByTask(2, task.ID)
+	assert.EqualError(t, err, store.ErrInvalidTaskId.Error())
+}
+
+func TestShareRepository_Access(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseURL)
+	defer teardown("tasks", "projects", "shares")
+
+	s := sqlstore.New(db)
+	task := model.TestTask(t)
+	s.Task().Create(task)
+
+	_, err := s.Task().GetById(2, task.ID)
+	assert.EqualError(t, err, store.ErrInvalidTaskId.Error())
+
+	share := model.TestShare(t)
+	share.TaskID = &task.ID
+	s.Share().Create(share)
+
+	// A viewer sees the task but can't change it
+	found, err := s.Task().GetById(2, task.ID)
+	assert.NoError(t, err)
+	tasks, err := s.Task().GetAll(2)
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 1)
+
+	edited := *found
+	edited.Title = "Edited"
+	assert.EqualError(t, s.Task().Update(2, &edited), store.ErrReadOnly.Error())
+	assert.EqualError(t, s.Task().Done(2, task.ID), store.ErrReadOnly.Error())
+
+	// An editor changes it, only the owner deletes it
+	share.Role = model.RoleEditor
+	s.Share().Create(share)
+	assert.NoError(t, s.Task().Update(2, &edited))
+	assert.NoError(t, s.Task().Done(2, task.ID))
+	assert.EqualError(t, s.Task().Delete(2, task.ID), store.ErrInvalidTaskId.Error())
+
+	// Revoking the share takes the access away
+	assert.EqualError(t, s.Share().Delete(3, share.ID), store.ErrInvalidShareId.Error())
+	assert.NoError(t, s.Share().Delete(1, share.ID))
+	_, err = s.Task().GetById(2, task.ID)
+	assert.EqualError(t, err, store.ErrInvalidTaskId.Error())
+}
+
+func TestShareRepository_Blockers(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseURL)
+	defer teardown("tasks", "projects", "shares", "task_dependencies")
+
+	s := sqlstore.New(db)
+	task, private := model.TestTask(t), model.TestTask(t)
+	s.Task().Create(task)
+	private.UserID = 2
+	s.Task().Create(private)
+	share := model.TestShare(t)
+	share.TaskID, share.Role = &task.ID, model.RoleEditor
+	s.Share().Create(share)
+
+	// An editor can't block the task with a task its owner doesn't see
+	assert.EqualError(t, s.Task().AddBlocker(2, task.ID, private.ID), store.ErrInvalidBlocker.Error())
+	assert.NoError(t, s.Task().Done(1, task.ID))
+
+	// unless it is shared with the owner
+	back := model.TestShare(t)
+	back.OwnerID, back.UserID, back.TaskID = 2, 1, &private.ID
+	s.Share().Create(back)
+	assert.NoError(t, s.Task().AddBlocker(2, task.ID, private.ID))
+}
+
+func TestShareRepository_Project(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseURL)
+	defer teardown("tasks", "projects", "shares")
+
+	s := sqlstore.New(db)
+	project := model.TestProject(t)
+	s.Project().Create(project)
+	task := model.TestTask(t)
+	task.ProjectID = &project.ID
+	s.Task().Create(task)
+	outside := model.TestTask(t)
+	s.Task().Create(outside)
+
+	share := model.TestShare(t)
+	share.TaskID, share.ProjectID = nil, &project.ID
+	assert.NoError(t, s.Share().Create(share))
+
+	// The tasks of the project are shared, the other tasks of the owner aren't
+	_, err := s.Task().GetById(2, task.ID)
+	assert.NoError(t, err)
+	_, err = s.Task().GetById(2, outside.ID)
+	assert.EqualError(t, err, store.ErrInvalidTaskId.Error())
+
+	shared, err := s.Share().SharedWith(2)
+	assert.NoError(t, err)
+	if assert.Len(t, shared, 1) {
+		assert.Equal(t, project.Name, shared[0].Title)
+	}
+
+	shares, err := s.Share().GetByProject(1, project.ID)
+	assert.NoError(t, err)
+	assert.Len(t, shares, 1)
+
+	// The user it is shared with can leave it
+	assert.NoError(t, s.Share().Delete(2, share.ID))
+	shared, _ = s.Share().SharedWith(2)
+	assert.Empty(t, shared)
+}
+
+func TestShareRepository_Assignee(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseURL)
+	defer teardown("tasks", "projects", "shares", "task_events")
+
+	s := sqlstore.New(db)
+	project := model.TestProject(t)
+	s.Project().Create(project)
+	task := model.TestTask(t)
+	task.ProjectID = &project.ID
+
+	// Only members of the project can be assigned
+	member := 2
+	task.AssigneeID = &member
+	assert.EqualError(t, s.Task().Create(task), store.ErrInvalidAssignee.Error())
+
+	share := model.TestShare(t)
+	share.TaskID, share.ProjectID, share.Role = nil, &project.ID, model.RoleEditor
+	assert.NoError(t, s.Share().Create(share))
+	assert.NoError(t, s.Task().Create(task))
+
+	outside := model.TestTask(t)
+	outside.AssigneeID = &member
+	assert.EqualError(t, s.Task().Create(outside), store.ErrInvalidAssignee.Error())
+
+	assigned, _, err := s.Task().Find(2, &store.TaskQuery{AssigneeID: &member})
+	assert.NoError(t, err)
+	if assert.Len(t, assigned, 1) {
+		assert.Equal(t, task.ID, assigned[0].ID)
+	}
+
+	// Leaving the project unassigns the tasks and keeps it in the history
+	assert.NoError(t, s.Share().Delete(2, share.ID))
+	task, err = s.Task().GetById(1, task.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, task.AssigneeID)
+
+	events, err := s.Task().History(1, task.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, 2, events[1].UserID)
+		assert.Equal(t, model.Change{From: float64(2), To: nil}, events[1].Changes["assignee_id"])
+	}
+}
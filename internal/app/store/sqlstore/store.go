@@ -8,9 +8,17 @@ import (
 )
 
 type Store struct {
-	db             *sql.DB
-	userRepository *UserRepository
-	taskRepository *TaskRepository
+	db                     *sql.DB
+	userRepository         *UserRepository
+	taskRepository         *TaskRepository
+	tagRepository          *TagRepository
+	projectRepository      *ProjectRepository
+	workflowRepository     *WorkflowRepository
+	timeEntryRepository    *TimeEntryRepository
+	commentRepository      *CommentRepository
+	attachmentRepository   *AttachmentRepository
+	shareRepository        *ShareRepository
+	organizationRepository *OrganizationRepository
 }
 
 // NewStore returns a new instance of store.
@@ -45,3 +53,134 @@ func (s *Store) Task() store.TaskRepository {
 
 	return s.taskRepository
 }
+
+// Tag returns a tagRepository. It is used to interact with the repository from the outside.
+func (s *Store) Tag() store.TagRepository {
+	if s.tagRepository != nil {
+		return s.tagRepository
+	}
+
+	s.tagRepository = &TagRepository{
+		store: s,
+	}
+
+	return s.tagRepository
+}
+
+// Project returns a projectRepository. It is used to interact with the repository from the outside.
+func (s *Store) Project() store.ProjectRepository {
+	if s.projectRepository != nil {
+		return s.projectRepository
+	}
+
+	s.projectRepository = &ProjectRepository{
+		store: s,
+	}
+
+	return s.projectRepository
+}
+
+// Workflow returns a workflowRepository. It is used to interact with the repository from the outside.
+func (s *Store) Workflow() store.WorkflowRepository {
+	if s.workflowRepository != nil {
+		return s.workflowRepository
+	}
+
+	s.workflowRepository = &WorkflowRepository{
+		store: s,
+	}
+
+	return s.workflowRepository
+}
+
+// TimeEntry returns a timeEntryRepository. It is used to interact with the repository from the outside.
+func (s *Store) TimeEntry() store.TimeEntryRepository {
+	if s.timeEntryRepository != nil {
+		return s.timeEntryRepository
+	}
+
+	s.timeEntryRepository = &TimeEntryRepository{
+		store: s,
+	}
+
+	return s.timeEntryRepository
+}
+
+// Comment returns a commentRepository. It is used to interact with the repository from the outside.
+func (s *Store) Comment() store.CommentRepository {
+	if s.commentRepository != nil {
+		return s.commentRepository
+	}
+
+	s.commentRepository = &CommentRepository{
+		store: s,
+	}
+
+	return s.commentRepository
+}
+
+// Attachment returns a attachmentRepository. It is used to interact with the repository from the outside.
+func (s *Store) Attachment() store.AttachmentRepository {
+	if s.attachmentRepository != nil {
+		return s.attachmentRepository
+	}
+
+	s.attachmentRepository = &AttachmentRepository{
+		store: s,
+	}
+
+	return s.attachmentRepository
+}
+
+// Share returns a shareRepository. It is used to interact with the repository from the outside.
+func (s *Store) Share() store.ShareRepository {
+	if s.shareRepository != nil {
+		return s.shareRepository
+	}
+
+	s.shareRepository = &ShareRepository{
+		store: s,
+	}
+
+	return s.shareRepository
+}
+
+// Organization returns a organizationRepository. It is used to interact with the repository from the outside.
+func (s *Store) Organization() store.OrganizationRepository {
+	if s.organizationRepository != nil {
+		return s.organizationRepository
+	}
+
+	s.organizationRepository = &OrganizationRepository{
+		store: s,
+	}
+
+	return s.organizationRepository
+}
+
+// inTx runs fn in a transaction which is committed if fn succeeds and rolled back otherwise
+func (s *Store) inTx(fn func(*sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Classes of the advisory locks taken for a user
+const (
+	lockDependencies = iota + 1
+	lockRanks
+)
+
+// lockUser holds the lock of the class for the user until the transaction ends
+func lockUser(q querier, class int, userId int) error {
+	_, err := q.Exec("SELECT pg_advisory_xact_lock($1, $2)", class, userId)
+	return err
+}
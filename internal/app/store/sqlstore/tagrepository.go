@@ -0,0 +1,166 @@
+package sqlstore
+
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/pyuldashev912/todoapp/internal/app/store"
+)
+
+// uniqueViolation is the PostgreSQL error code of a violated UNIQUE constraint
+const uniqueViolation = "23505"
+
+type TagRepository struct {
+	store *Store
+}
+
+// Create creates a new tag
+func (r *TagRepository) Create(tag *model.Tag) error {
+	tag.Normalize()
+	if err := tag.Validate(); err != nil {
+		return err
+	}
+
+	err := r.store.db.QueryRow(
+		"INSERT INTO tags (user_id, name) VALUES ($1, $2) RETURNING id", tag.UserID, tag.Name,
+	).Scan(&tag.ID)
+
+	return uniqueTagError(err)
+}
+
+// Rename changes the name of a tag
+func (r *TagRepository) Rename(userId int, tagId int, name string) (*model.Tag, error) {
+	tag := &model.Tag{ID: tagId, UserID: userId, Name: name}
+	tag.Normalize()
+	if err := tag.Validate(); err != nil {
+		return nil, err
+	}
+
+	res, err := r.store.db.Exec(
+		"UPDATE tags SET name=$1 WHERE user_id=$2 and id=$3", tag.Name, userId, tagId,
+	)
+	if err != nil {
+		return nil, uniqueTagError(err)
+	}
+
+	if err := checkAffected(res, store.ErrInvalidTagId); err != nil {
+		return nil, err
+	}
+
+	return tag, nil
+}
+
+// Delete deletes a tag and detaches it from all tasks
+func (r *TagRepository) Delete(userId int, tagId int) error {
+	res, err := r.store.db.Exec(
+		"DELETE FROM tags WHERE user_id=$1 and id=$2", userId, tagId,
+	)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res, store.ErrInvalidTagId)
+}
+
+// GetAll gets all User's tags ordered by name
+func (r *TagRepository) GetAll(userId int) ([]*model.Tag, error) {
+	rows, err := r.store.db.Query(
+		"SELECT id, user_id, name FROM tags WHERE user_id=$1 ORDER BY name", userId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := make([]*model.Tag, 0, 5)
+	for rows.Next() {
+		tag := &model.Tag{}
+		if err := rows.Scan(&tag.ID, &tag.UserID, &tag.Name); err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, tag)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(tags) == 0 {
+		return nil, store.ErrNoRecordsInTable
+	}
+
+	return tags, nil
+}
+
+// Attach labels User's task with the tag. Attaching a tag twice is not an error.
+func (r *TagRepository) Attach(userId int, taskId int, tagId int) error {
+	if err := r.checkOwner(userId, taskId, tagId); err != nil {
+		return err
+	}
+
+	_, err := r.store.db.Exec(
+		"INSERT INTO task_tags (task_id, tag_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", taskId, tagId,
+	)
+
+	return err
+}
+
+// Detach removes the tag from User's task
+func (r *TagRepository) Detach(userId int, taskId int, tagId int) error {
+	if err := r.checkOwner(userId, taskId, tagId); err != nil {
+		return err
+	}
+
+	_, err := r.store.db.Exec(
+		"DELETE FROM task_tags WHERE task_id=$1 and tag_id=$2", taskId, tagId,
+	)
+
+	return err
+}
+
+// checkOwner makes sure both the task and the tag belong to the user
+func (r *TagRepository) checkOwner(userId int, taskId int, tagId int) error {
+	var taskFound, tagFound bool
+	if err := r.store.db.QueryRow(`SELECT
+		EXISTS (SELECT 1 FROM tasks WHERE user_id=$1 and id=$2 and deleted_at IS NULL),
+		EXISTS (SELECT 1 FROM tags WHERE user_id=$1 and id=$3)`,
+		userId, taskId, tagId,
+	).Scan(&taskFound, &tagFound); err != nil {
+		return err
+	}
+
+	if !taskFound {
+		return store.ErrInvalidTaskId
+	}
+
+	if !tagFound {
+		return store.ErrInvalidTagId
+	}
+
+	return nil
+}
+
+// uniqueTagError replaces a unique violation with store.ErrTagExists
+func uniqueTagError(err error) error {
+	if err, ok := err.(*pq.Error); ok && err.Code == uniqueViolation {
+		return store.ErrTagExists
+	}
+
+	return err
+}
+
+// checkAffected returns notFound if the statement changed no rows
+func checkAffected(res sql.Result, notFound error) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return notFound
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package sqlstore_test
+
+import (
+	"testing"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/pyuldashev912/todoapp/internal/app/store"
+	"github.com/pyuldashev912/todoapp/internal/app/store/sqlstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagRepository_Create(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseURL)
+	defer teardown("tags")
+
+	s := sqlstore.New(db)
+	tag := model.TestTag(t)
+	assert.NoError(t, s.Tag().Create(tag))
+	assert.EqualError(t, s.Tag().Create(&model.Tag{UserID: 1, Name: "Urgent"}), store.ErrTagExists.Error())
+	assert.NoError(t, s.Tag().Create(&model.Tag{UserID: 2, Name: "urgent"}))
+}
+
+func TestTagRepository_Rename(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseURL)
+	defer teardown("tasks", "tags")
+
+	s := sqlstore.New(db)
+	task := model.TestTask(t)
+	s.Task().Create(task)
+	tag := model.TestTag(t)
+	s.Tag().Create(tag)
+	s.Tag().Create(&model.Tag{UserID: 1, Name: "home"})
+	s.Tag().Attach(task.UserID, task.ID, tag.ID)
+
+	res, err := s.Tag().Rename(tag.UserID, tag.ID, "Critical")
+	assert.NoError(t, err)
+	assert.Equal(t, "critical", res.Name)
+
+	res2, err := s.Task().GetById(task.UserID, task.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"critical"}, res2.Tags)
+
+	_, err = s.Tag().Rename(tag.UserID, tag.ID, "home")
+	assert.EqualError(t, err, store.ErrTagExists.Error())
+
+	_, err = s.Tag().Rename(2, tag.ID, "other")
+	assert.EqualError(t, err, store.ErrInvalidTagId.Error())
+}
+
+func TestTagRepository_Delete(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseURL)
+	defer teardown("tasks", "tags")
+
+	s := sqlstore.New(db)
+	task := model.TestTask(t)
+	s.Task().Create(task)
+	tag := model.TestTag(t)
+	s.Tag().Create(tag)
+	s.Tag().Attach(task.UserID, task.ID, tag.ID)
+
+	assert.NoError(t, s.Tag().Delete(tag.UserID, tag.ID))
+	res, _ := s.Task().GetById(task.UserID, task.ID)
+	assert.Empty(t, res.Tags)
+
+	assert.EqualError(t, s.Tag().Delete(tag.UserID, tag.ID), store.ErrInvalidTagId.Error())
+}
+
+func TestTagRepository_GetAll(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseURL)
+	defer teardown("tags")
+
+	s := sqlstore.New(db)
+	_, err := s.Tag().GetAll(1)
+	assert.EqualError(t, err, store.ErrNoRecordsInTable.Error())
+
+	s.Tag().Create(model.TestTag(t))
+	s.Tag().Create(&model.Tag{UserID: 1, Name: "bug"})
+	tags, err := s.Tag().GetAll(1)
+	assert.NoError(t, err)
+	assert.Len(t, tags, 2)
+	assert.Equal(t, "bug", tags[0].Name)
+}
+
+func TestTagRepository_AttachDetach(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseURL)
+	defer teardown("tasks", "tags")
+
+	s := sqlstore.New(db)
+	task := model.TestTask(t)
+	s.Task().Create(task)
+	tag := model.TestTag(t)
+	s.Tag().Create(tag)
+
+	assert.NoError(t, s.Tag().Attach(task.UserID, task.ID, tag.ID))
+	assert.NoError(t, s.Tag().Attach(task.UserID, task.ID, tag.ID))
+	res, _ := s.Task().GetById(task.UserID, task.ID)
+	assert.Equal(t, []string{"urgent"}, res.Tags)
+
+	assert.EqualError(t, s.Tag().Attach(task.UserID, task.ID+1, tag.ID), store.ErrInvalidTaskId.Error())
+	assert.EqualError(t, s.Tag().Attach(task.UserID, task.ID, tag.ID+1), store.ErrInvalidTagId.Error())
+
+	assert.NoError(t, s.Tag().Detach(task.UserID, task.ID, tag.ID))
+	res, _ = s.Task().GetById(task.UserID, task.ID)
+	assert.Empty(t, res.Tags)
+}
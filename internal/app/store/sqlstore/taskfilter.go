@@ -0,0 +1,73 @@
+package sqlstore
+
+import (
+	"fmt"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/pyuldashev912/todoapp/internal/app/store/filter"
+)
+
+// filterColumns maps filter fields to the columns of tasks
+var filterColumns = map[string]string{
+	filter.FieldTitle:       "title",
+	filter.FieldDescription: "description",
+	filter.FieldDone:        "done",
+	filter.FieldStatus:      "status",
+	filter.FieldPriority:    "priority",
+	filter.FieldDue:         "due_at",
+	filter.FieldCreated:     "created_at",
+	filter.FieldProject:     "project_id",
+}
+
+var filterOperators = map[string]string{
+	filter.OpEq: "=",
+	filter.OpGt: ">",
+	filter.OpGe: ">=",
+	filter.OpLt: "<",
+	filter.OpLe: "<=",
+}
+
+// filterCondition translates a filter expression into a condition on tasks
+// with all values passed as query parameters. Comparisons with NULL are made
+// false so that NOT works the same way as in the other stores.
+func filterCondition(e filter.Expr, param func(interface{}) string) string {
+	switch e := e.(type) {
+	case *filter.And:
+		return "(" + filterCondition(e.Left, param) + " and " + filterCondition(e.Right, param) + ")"
+	case *filter.Or:
+		return "(" + filterCondition(e.Left, param) + " or " + filterCondition(e.Right, param) + ")"
+	case *filter.Not:
+		return "NOT " + filterCondition(e.Expr, param)
+	case *filter.Cmp:
+		if e.Op == filter.OpNe {
+			return "NOT " + cmpCondition(&filter.Cmp{Field: e.Field, Op: filter.OpEq, Value: e.Value}, param)
+		}
+
+		return cmpCondition(e, param)
+	}
+
+	panic(fmt.Sprintf("unexpected filter expression %T", e))
+}
+
+func cmpCondition(c *filter.Cmp, param func(interface{}) string) string {
+	if c.Field == filter.FieldTag {
+		return "id IN (SELECT task_tags.task_id FROM task_tags JOIN tags ON tags.id = task_tags.tag_id " +
+			"WHERE tags.name = " + param(c.Value) + ")"
+	}
+
+	column := filterColumns[c.Field]
+	if c.Value == nil {
+		return column + " IS NULL"
+	}
+
+	value := c.Value
+	if priority, ok := value.(model.Priority); ok {
+		value = priority.Level()
+	}
+
+	if c.Op == filter.OpContains {
+		return fmt.Sprintf("strpos(lower(%s), lower(%s)) > 0", column, param(value))
+	}
+
+	return fmt.Sprintf("COALESCE(%s %s %s, FALSE)", column, filterOperators[c.Op], param(value))
+}
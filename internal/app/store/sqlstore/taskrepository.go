@@ -2,36 +2,151 @@ package sqlstore
 
 import (
 	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/lib/pq"
 	"github.com/pyuldashev912/todoapp/internal/app/model"
 	"github.com/pyuldashev912/todoapp/internal/app/store"
 )
 
+// taskColumns lists the tasks columns in the order scanTask expects them
+const taskColumns = `id, user_id, title, description, done, status, creation_date, due_at, created_at, project_id, parent_id,
+	recurrence, series_id, priority, estimate, assignee_id, rank, deleted_at,
+	ARRAY(SELECT tags.name FROM task_tags JOIN tags ON tags.id = task_tags.tag_id
+		WHERE task_tags.task_id = tasks.id ORDER BY tags.name),
+	ARRAY(SELECT task_dependencies.blocker_id FROM task_dependencies
+		JOIN tasks AS blockers ON blockers.id = task_dependencies.blocker_id
+		WHERE task_dependencies.task_id = tasks.id and blockers.deleted_at IS NULL ORDER BY task_dependencies.blocker_id),
+	EXISTS (SELECT 1 FROM task_dependencies JOIN tasks AS blockers ON blockers.id = task_dependencies.blocker_id
+		WHERE task_dependencies.task_id = tasks.id and blockers.deleted_at IS NULL and blockers.done=FALSE),
+	(SELECT COUNT(*) FROM comments WHERE comments.task_id = tasks.id),
+	(SELECT COUNT(*) FROM tasks AS subtasks
+		WHERE subtasks.parent_id = tasks.id and subtasks.deleted_at IS NULL and subtasks.done),
+	(SELECT COUNT(*) FROM tasks AS subtasks WHERE subtasks.parent_id = tasks.id and subtasks.deleted_at IS NULL)`
+
 type TaskRepository struct {
 	store *Store
 }
 
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// scanTask reads a single tasks row selected with taskColumns
+func scanTask(row scanner) (*model.Task, error) {
+	t := &model.Task{}
+	progress := &model.Progress{}
+	var priority int
+	var blockedBy pq.Int64Array
+	if err := row.Scan(
+		&t.ID, &t.UserID, &t.Title, &t.Description, &t.Done, &t.Status, &t.CreationDate, &t.DueAt, &t.CreatedAt,
+		&t.ProjectID, &t.ParentID, &t.Recurrence, &t.SeriesID, &priority, &t.Estimate, &t.AssigneeID, &t.Rank, &t.DeletedAt,
+		pq.Array(&t.Tags), &blockedBy, &t.Blocked, &t.CommentCount, &progress.Done, &progress.Total,
+	); err != nil {
+		return nil, err
+	}
+
+	for _, id := range blockedBy {
+		t.BlockedBy = append(t.BlockedBy, int(id))
+	}
+
+	t.Priority = model.PriorityOfLevel(priority)
+
+	if progress.Total > 0 {
+		t.Progress = progress
+	}
+
+	return t, nil
+}
+
 // Create creates a new task
 func (r *TaskRepository) Create(task *model.Task) error {
+	return r.store.inTx(func(tx *sql.Tx) error {
+		return r.create(tx, task)
+	})
+}
+
+func (r *TaskRepository) create(q querier, task *model.Task) error {
 	if err := task.Validate(); err != nil {
 		return err
 	}
 
-	return r.store.db.QueryRow(`
-	INSERT INTO tasks (user_id, title, description, done, creation_date) VALUES ($1, $2, $3, $4, $5) RETURNING id`,
-		task.UserID, task.Title, task.Description, task.Done, task.CreationDate,
-	).Scan(&task.ID)
+	if err := checkProject(q, task); err != nil {
+		return err
+	}
+
+	if err := checkParent(q, task); err != nil {
+		return err
+	}
+
+	if err := checkAssignee(q, task); err != nil {
+		return err
+	}
+
+	if err := insertTask(q, task); err != nil {
+		return err
+	}
+
+	return recordEvent(q, task.UserID, task.ID, model.ActionCreate, model.DiffTasks(nil, task))
 }
 
-// GetById return task by id
+// insertTask inserts the task at the end of the manual order of its owner
+// in the initial status of its workflow unless it is given one
+func insertTask(q querier, task *model.Task) error {
+	workflows, err := loadWorkflows(q, task.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := workflows.of(task.ProjectID).Apply(nil, task); err != nil {
+		return err
+	}
+
+	// Tasks created at once would get the same rank
+	if err := lockUser(q, lockRanks, task.UserID); err != nil {
+		return err
+	}
+
+	var last string
+	if err := q.QueryRow(
+		"SELECT COALESCE(MAX(rank), '') FROM tasks WHERE user_id=$1", task.UserID,
+	).Scan(&last); err != nil {
+		return err
+	}
+
+	task.Rank = model.RankAfter(last)
+	return q.QueryRow(`
+	INSERT INTO tasks (user_id, title, description, done, status, creation_date, due_at, project_id, parent_id,
+		recurrence, series_id, priority, estimate, assignee_id, rank)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15) RETURNING id, created_at`,
+		task.UserID, task.Title, task.Description, task.Done, task.Status, task.CreationDate, task.DueAt,
+		task.ProjectID, task.ParentID, task.Recurrence, task.SeriesID, task.Priority.Level(), task.Estimate,
+		task.AssigneeID, task.Rank,
+	).Scan(&task.ID, &task.CreatedAt)
+}
+
+// GetById return task by id, the task may be shared with the user
 func (r *TaskRepository) GetById(userId int, taskId int) (*model.Task, error) {
-	u := &model.Task{}
+	return getTask(r.store.db, userId, taskId)
+}
 
-	if err := r.store.db.QueryRow(
-		"SELECT * FROM tasks WHERE user_id=$1 and id=$2", userId, taskId,
-	).Scan(
-		&u.ID, &u.UserID, &u.Title, &u.Description, &u.Done, &u.CreationDate,
-	); err != nil {
+func getTask(q querier, userId int, taskId int) (*model.Task, error) {
+	u, err := scanTask(q.QueryRow(
+		"SELECT "+taskColumns+" FROM tasks WHERE "+visibleTo+" and id=$2 and deleted_at IS NULL", userId, taskId,
+	))
+	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, store.ErrInvalidTaskId
 		}
@@ -41,78 +156,1079 @@ func (r *TaskRepository) GetById(userId int, taskId int) (*model.Task, error) {
 	return u, nil
 }
 
-// Done marks tasks as complited
+// Done marks tasks as complited together with all their subtasks.
+// Completing an occurrence of a recurring task creates the next occurrence.
+// Editors of a shared task can complete it too.
 func (r *TaskRepository) Done(userId int, taskId int) error {
-	res, err := r.store.db.Exec(
-		"UPDATE tasks SET done=TRUE WHERE user_id=$1 and id=$2", userId, taskId,
+	return r.store.inTx(func(tx *sql.Tx) error {
+		return r.done(tx, userId, taskId)
+	})
+}
+
+func (r *TaskRepository) done(q querier, userId int, taskId int) error {
+	if err := checkEditable(q, userId, taskId); err != nil {
+		return err
+	}
+
+	var wasDone bool
+	var ownerId int
+	if err := q.QueryRow(
+		"SELECT done, user_id FROM tasks WHERE id=$1 FOR UPDATE", taskId,
+	).Scan(&wasDone, &ownerId); err != nil {
+		return err
+	}
+
+	// The tasks follow the workflows of their owner
+	workflows, err := loadWorkflows(q, ownerId)
+	if err != nil {
+		return err
+	}
+
+	rows, err := q.Query(`
+	WITH RECURSIVE subtree AS (
+		SELECT id FROM tasks WHERE id=$1
+		UNION ALL
+		SELECT tasks.id FROM tasks JOIN subtree ON tasks.parent_id = subtree.id and tasks.deleted_at IS NULL
+	)
+	SELECT id, project_id, status FROM tasks WHERE id IN (SELECT id FROM subtree) and done=FALSE`,
+		taskId,
 	)
 	if err != nil {
 		return err
 	}
 
-	n, err := res.RowsAffected()
+	var open []*model.Task
+	for rows.Next() {
+		task := &model.Task{}
+		if err := rows.Scan(&task.ID, &task.ProjectID, &task.Status); err != nil {
+			rows.Close()
+			return err
+		}
+
+		open = append(open, task)
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	ids := make([]int64, len(open))
+	for i, task := range open {
+		ids[i] = int64(task.ID)
+	}
+
+	if err := checkBlockers(q, ids); err != nil {
+		return err
+	}
+
+	// Every task of the subtree goes to the done status of its own workflow
+	for _, task := range open {
+		status := workflows.of(task.ProjectID).DoneStatus()
+		if _, err := q.Exec("UPDATE tasks SET done=TRUE, status=$1 WHERE id=$2", status, task.ID); err != nil {
+			return err
+		}
+
+		if err := recordEvent(q, userId, task.ID, model.ActionDone, map[string]model.Change{
+			"done":   {From: false, To: true},
+			"status": {From: task.Status, To: status},
+		}); err != nil {
+			return err
+		}
+	}
+
+	if wasDone {
+		return nil
+	}
+
+	return r.createNextOccurrence(q, userId, taskId)
+}
+
+// createNextOccurrence creates the occurrence following the task unless
+// the series already has a later one, e.g. when a task is completed again
+func (r *TaskRepository) createNextOccurrence(q querier, userId int, taskId int) error {
+	task, err := scanTask(q.QueryRow("SELECT "+taskColumns+" FROM tasks WHERE id=$1", taskId))
+	if err != nil {
+		return err
+	}
+
+	next, err := task.NextOccurrence()
+	if err != nil || next == nil {
+		return err
+	}
+
+	var exists bool
+	if err := q.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM tasks WHERE COALESCE(series_id, id)=$1 and due_at > $2)",
+		*next.SeriesID, task.DueAt,
+	).Scan(&exists); err != nil || exists {
+		return err
+	}
+
+	if err := insertTask(q, next); err != nil {
+		return err
+	}
+
+	if err := recordEvent(q, userId, next.ID, model.ActionCreate, model.DiffTasks(nil, next)); err != nil {
+		return err
+	}
+
+	_, err = q.Exec(
+		"INSERT INTO task_tags (task_id, tag_id) SELECT $1, tag_id FROM task_tags WHERE task_id=$2",
+		next.ID, task.ID,
+	)
+
+	return err
+}
+
+// Update saves the editable fields of an existing task, the user is either
+// its owner or an editor it is shared with
+func (r *TaskRepository) Update(userId int, task *model.Task) error {
+	return r.store.inTx(func(tx *sql.Tx) error {
+		return r.update(tx, userId, task)
+	})
+}
+
+func (r *TaskRepository) update(q querier, userId int, task *model.Task) error {
+	if err := task.Validate(); err != nil {
+		return err
+	}
+
+	if err := checkEditable(q, userId, task.ID); err != nil {
+		return err
+	}
+
+	if err := checkProject(q, task); err != nil {
+		return err
+	}
+
+	if err := checkParent(q, task); err != nil {
+		return err
+	}
+
+	if err := checkAssignee(q, task); err != nil {
+		return err
+	}
+
+	old, err := scanTask(q.QueryRow(
+		"SELECT "+taskColumns+" FROM tasks WHERE user_id=$1 and id=$2 and deleted_at IS NULL FOR UPDATE",
+		task.UserID, task.ID,
+	))
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return store.ErrInvalidTaskId
+		}
+		return err
+	}
+
+	workflows, err := loadWorkflows(q, task.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := workflows.of(task.ProjectID).Apply(old, task); err != nil {
+		return err
+	}
+
+	// Completing a task goes through done like Done does, so that its
+	// subtasks are completed too and a recurring task gets its next occurrence
+	completing := task.Done && !old.Done
+	saved := *task
+	if completing {
+		saved.Done, saved.Status = false, old.Status
+	}
+
+	if _, err := q.Exec(`
+	UPDATE tasks SET title=$1, description=$2, done=$3, status=$4, due_at=$5, project_id=$6, parent_id=$7,
+		recurrence=$8, priority=$9, estimate=$10, assignee_id=$11
+	WHERE id=$12`,
+		saved.Title, saved.Description, saved.Done, saved.Status, saved.DueAt, saved.ProjectID, saved.ParentID,
+		saved.Recurrence, saved.Priority.Level(), saved.Estimate, saved.AssigneeID, saved.ID,
+	); err != nil {
+		return err
+	}
+
+	if changes := model.DiffTasks(old, &saved); len(changes) > 0 {
+		if err := recordEvent(q, userId, task.ID, model.ActionUpdate, changes); err != nil {
+			return err
+		}
+	}
+
+	if completing {
+		return r.done(q, userId, task.ID)
+	}
+
+	return nil
+}
+
+// checkBlockers refuses to complete the tasks while any of them is blocked
+// by an open task other than them
+func checkBlockers(q querier, ids []int64) error {
+	var blocked bool
+	if err := q.QueryRow(`
+	SELECT EXISTS (
+		SELECT 1 FROM task_dependencies JOIN tasks ON tasks.id = task_dependencies.blocker_id
+		WHERE task_dependencies.task_id = ANY($1) and NOT task_dependencies.blocker_id = ANY($1)
+			and tasks.done=FALSE and tasks.deleted_at IS NULL
+	)`, pq.Array(ids),
+	).Scan(&blocked); err != nil {
+		return err
+	}
+
+	if blocked {
+		return store.ErrTaskBlocked
+	}
+
+	return nil
+}
+
+// checkProject makes sure the task is placed into a project of its owner or
+// of an organization they are a member of
+func checkProject(q querier, task *model.Task) error {
+	if task.ProjectID == nil {
+		return nil
+	}
+
+	var found bool
+	if err := q.QueryRow(`
+	SELECT EXISTS (SELECT 1 FROM projects WHERE id=$2 and (projects.user_id=$1
+		or EXISTS (SELECT 1 FROM memberships WHERE memberships.organization_id = projects.organization_id
+			and memberships.user_id=$1)))`,
+		task.UserID, *task.ProjectID,
+	).Scan(&found); err != nil {
+		return err
+	}
+
+	if !found {
+		return store.ErrInvalidProjectId
+	}
+
+	return nil
+}
+
+// checkAssignee makes sure the task is assigned to a member of its project,
+// which are the owner, the users the project is shared with and the members
+// of its organization. Tasks outside of projects can only be assigned to
+// their owner.
+func checkAssignee(q querier, task *model.Task) error {
+	if task.AssigneeID == nil || *task.AssigneeID == task.UserID {
+		return nil
+	}
+
+	if task.ProjectID == nil {
+		return store.ErrInvalidAssignee
+	}
+
+	var member bool
+	if err := q.QueryRow(`
+	SELECT EXISTS (SELECT 1 FROM shares WHERE project_id=$1 and user_id=$2)
+		or EXISTS (SELECT 1 FROM projects JOIN memberships ON memberships.organization_id = projects.organization_id
+			WHERE projects.id=$1 and memberships.user_id=$2)`,
+		*task.ProjectID, *task.AssigneeID,
+	).Scan(&member); err != nil {
 		return err
 	}
 
-	if n == 0 {
-		return store.ErrInvalidTaskId
+	if !member {
+		return store.ErrInvalidAssignee
 	}
 
 	return nil
 }
 
-// Delete deletes tasks
+// checkParent makes sure the parent of the task is another task of its owner
+// and that the task is not one of its own ancestors
+func checkParent(q querier, task *model.Task) error {
+	if task.ParentID == nil {
+		return nil
+	}
+
+	var found, cycle bool
+	if err := q.QueryRow(`
+	WITH RECURSIVE ancestors AS (
+		SELECT id, parent_id FROM tasks WHERE user_id=$1 and id=$2 and deleted_at IS NULL
+		UNION ALL
+		SELECT tasks.id, tasks.parent_id FROM tasks JOIN ancestors ON tasks.id = ancestors.parent_id
+	)
+	SELECT EXISTS (SELECT 1 FROM ancestors), EXISTS (SELECT 1 FROM ancestors WHERE id=$3)`,
+		task.UserID, *task.ParentID, task.ID,
+	).Scan(&found, &cycle); err != nil {
+		return err
+	}
+
+	if !found || cycle {
+		return store.ErrInvalidParent
+	}
+
+	return nil
+}
+
+// Delete moves tasks together with all their subtasks to the trash
 func (r *TaskRepository) Delete(userId int, taskId int) error {
-	res, err := r.store.db.Exec(
-		"DELETE FROM tasks WHERE user_id=$1 and id=$2", userId, taskId,
+	return r.delete(r.store.db, userId, taskId)
+}
+
+func (r *TaskRepository) delete(q querier, userId int, taskId int) error {
+	res, err := q.Exec(`
+	WITH RECURSIVE subtree AS (
+		SELECT id FROM tasks WHERE user_id=$1 and id=$2 and deleted_at IS NULL
+		UNION ALL
+		SELECT tasks.id FROM tasks JOIN subtree ON tasks.parent_id = subtree.id and tasks.deleted_at IS NULL
+	), deleted AS (
+		UPDATE tasks SET deleted_at=now() WHERE id IN (SELECT id FROM subtree) RETURNING id
+	)
+	INSERT INTO task_events (task_id, user_id, action) SELECT id, $1, $3 FROM deleted`,
+		userId, taskId, model.ActionDelete,
 	)
 	if err != nil {
 		return err
 	}
 
+	return checkAffected(res, store.ErrInvalidTaskId)
+}
+
+// Batch runs the operations of a user in one transaction. Every operation
+// runs in its own savepoint, so a failed one leaves the others applied unless
+// the batch is atomic: then any failure rolls back the whole batch and
+// ErrBatchRolledBack is returned along with the results of all operations.
+func (r *TaskRepository) Batch(userId int, ops []*store.TaskOperation, atomic bool) ([]*store.TaskOperationResult, error) {
+	results := make([]*store.TaskOperationResult, 0, len(ops))
+	err := r.store.inTx(func(tx *sql.Tx) error {
+		failed := false
+		for _, op := range ops {
+			if _, err := tx.Exec("SAVEPOINT batch_operation"); err != nil {
+				return err
+			}
+
+			task, opErr := r.apply(tx, userId, op)
+			results = append(results, &store.TaskOperationResult{Task: task, Err: opErr})
+
+			release := "RELEASE SAVEPOINT batch_operation"
+			if opErr != nil {
+				failed = true
+				release = "ROLLBACK TO SAVEPOINT batch_operation"
+			}
+
+			if _, err := tx.Exec(release); err != nil {
+				return err
+			}
+		}
+
+		if atomic && failed {
+			return store.ErrBatchRolledBack
+		}
+
+		return nil
+	})
+
+	if err == store.ErrBatchRolledBack {
+		return results, err
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// apply runs a single batch operation and returns the resulting task
+func (r *TaskRepository) apply(q querier, userId int, op *store.TaskOperation) (*model.Task, error) {
+	switch op.Op {
+	case store.BatchCreate:
+		if op.Task == nil {
+			return nil, store.ErrInvalidOperation
+		}
+
+		op.Task.UserID = userId
+		if err := r.create(q, op.Task); err != nil {
+			return nil, err
+		}
+
+		return getTask(q, userId, op.Task.ID)
+	case store.BatchComplete:
+		if err := r.done(q, userId, op.TaskID); err != nil {
+			return nil, err
+		}
+
+		return getTask(q, userId, op.TaskID)
+	case store.BatchDelete:
+		return nil, r.delete(q, userId, op.TaskID)
+	case store.BatchUpdate:
+		task, err := getTask(q, userId, op.TaskID)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := task.Patch(op.Patch); err != nil {
+			return nil, fmt.Errorf("%w: %v", store.ErrInvalidOperation, err)
+		}
+
+		if err := r.update(q, userId, task); err != nil {
+			return nil, err
+		}
+
+		return getTask(q, userId, op.TaskID)
+	}
+
+	return nil, store.ErrInvalidOperation
+}
+
+// DeleteDone moves all User's completed tasks together with their subtasks
+// to the trash and returns how many tasks were moved
+func (r *TaskRepository) DeleteDone(userId int) (int, error) {
+	res, err := r.store.db.Exec(`
+	WITH RECURSIVE subtree AS (
+		SELECT id FROM tasks WHERE user_id=$1 and done and deleted_at IS NULL
+		UNION ALL
+		SELECT tasks.id FROM tasks JOIN subtree ON tasks.parent_id = subtree.id and tasks.deleted_at IS NULL
+	), deleted AS (
+		UPDATE tasks SET deleted_at=now() WHERE id IN (SELECT id FROM subtree) RETURNING id
+	)
+	INSERT INTO task_events (task_id, user_id, action) SELECT id, $1, $2 FROM deleted`,
+		userId, model.ActionDelete,
+	)
+	if err != nil {
+		return 0, err
+	}
+
 	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// CompleteAll completes all User's open tasks the same way Done does and
+// returns how many tasks were completed. The tasks blocked by open tasks of
+// other users are left open and returned.
+func (r *TaskRepository) CompleteAll(userId int) (int, []int, error) {
+	var ids, blocked []int
+	err := r.store.inTx(func(tx *sql.Tx) error {
+		rows, err := tx.Query(
+			"SELECT id FROM tasks WHERE user_id=$1 and deleted_at IS NULL and done=FALSE ORDER BY id FOR UPDATE",
+			userId,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				return err
+			}
+
+			ids = append(ids, id)
+		}
+
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		blocked, err = store.CompleteInOrder(ids, func(id int) error {
+			return r.done(tx, userId, id)
+		})
+		return err
+	})
 	if err != nil {
+		return 0, nil, err
+	}
+
+	return len(ids) - len(blocked), blocked, nil
+}
+
+// AddBlocker makes the task blocked by the blocker task until the blocker is
+// completed. A dependency closing a cycle is rejected with the tasks of the cycle.
+func (r *TaskRepository) AddBlocker(userId int, taskId int, blockerId int) error {
+	return r.store.inTx(func(tx *sql.Tx) error {
+		if err := checkEditable(tx, userId, taskId); err != nil {
+			return err
+		}
+
+		task, err := getTask(tx, userId, taskId)
+		if err != nil {
+			return err
+		}
+
+		blocker, err := getTask(tx, userId, blockerId)
+		if err != nil {
+			return err
+		}
+
+		// The owner has to see what blocks their task
+		if _, err := getTask(tx, task.UserID, blockerId); err != nil {
+			if err == store.ErrInvalidTaskId {
+				return store.ErrInvalidBlocker
+			}
+			return err
+		}
+
+		if taskId == blockerId {
+			return fmt.Errorf("%w: %d -> %d", store.ErrDependencyCycle, taskId, taskId)
+		}
+
+		// Concurrent additions could close a cycle together, the owners are
+		// locked in the same order to not wait on each other
+		owners := []int{task.UserID, blocker.UserID}
+		sort.Ints(owners)
+		for _, owner := range owners {
+			if err := lockUser(tx, lockDependencies, owner); err != nil {
+				return err
+			}
+		}
+
+		var cycle pq.Int64Array
+		err = tx.QueryRow(`
+		WITH RECURSIVE chain (id, path) AS (
+			SELECT blocker_id, ARRAY[$2::bigint, blocker_id] FROM task_dependencies WHERE task_id=$2
+			UNION ALL
+			SELECT task_dependencies.blocker_id, chain.path || task_dependencies.blocker_id
+			FROM task_dependencies JOIN chain ON task_dependencies.task_id = chain.id
+			WHERE NOT task_dependencies.blocker_id = ANY(chain.path)
+		)
+		SELECT path FROM chain WHERE id=$1 LIMIT 1`,
+			taskId, blockerId,
+		).Scan(&cycle)
+		if err == nil {
+			return fmt.Errorf("%w: %s", store.ErrDependencyCycle, formatCycle(taskId, cycle))
+		}
+
+		if err != sql.ErrNoRows {
+			return err
+		}
+
+		_, err = tx.Exec(
+			"INSERT INTO task_dependencies (task_id, blocker_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+			taskId, blockerId,
+		)
+		return err
+	})
+}
+
+// formatCycle reads a cycle as the task followed by its chain of blockers back to it
+func formatCycle(taskId int, path []int64) string {
+	cycle := strconv.Itoa(taskId)
+	for _, id := range path {
+		cycle += fmt.Sprintf(" -> %d", id)
+	}
+
+	return cycle
+}
+
+// RemoveBlocker makes the task no longer blocked by the blocker task
+func (r *TaskRepository) RemoveBlocker(userId int, taskId int, blockerId int) error {
+	if err := checkEditable(r.store.db, userId, taskId); err != nil {
 		return err
 	}
 
-	if n == 0 {
-		return store.ErrInvalidTaskId
+	res, err := r.store.db.Exec(
+		"DELETE FROM task_dependencies WHERE task_id=$1 and blocker_id=$2", taskId, blockerId,
+	)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return checkAffected(res, store.ErrNoDependency)
+}
+
+// GetTrash gets all User's tasks in the trash, recently deleted first
+func (r *TaskRepository) GetTrash(userId int) ([]*model.Task, error) {
+	return r.getUnderHood(
+		"SELECT "+taskColumns+" FROM tasks WHERE user_id=$1 and deleted_at IS NOT NULL ORDER BY deleted_at DESC, id",
+		userId,
+	)
+}
+
+// Restore takes tasks out of the trash together with the subtasks deleted
+// with them. A subtask whose parent is still in the trash becomes a top level task.
+func (r *TaskRepository) Restore(userId int, taskId int) error {
+	return r.store.inTx(func(tx *sql.Tx) error {
+		var orphan bool
+		if err := tx.QueryRow(`
+		SELECT COALESCE(parents.deleted_at IS NOT NULL, FALSE) FROM tasks
+		LEFT JOIN tasks AS parents ON parents.id = tasks.parent_id
+		WHERE tasks.user_id=$1 and tasks.id=$2 and tasks.deleted_at IS NOT NULL FOR UPDATE OF tasks`,
+			userId, taskId,
+		).Scan(&orphan); err != nil {
+			if err == sql.ErrNoRows {
+				return store.ErrInvalidTaskId
+			}
+			return err
+		}
+
+		if orphan {
+			if _, err := tx.Exec("UPDATE tasks SET parent_id=NULL WHERE id=$1", taskId); err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.Exec(`
+		WITH RECURSIVE subtree AS (
+			SELECT id, deleted_at FROM tasks WHERE id=$2
+			UNION ALL
+			SELECT tasks.id, tasks.deleted_at FROM tasks
+			JOIN subtree ON tasks.parent_id = subtree.id and tasks.deleted_at = subtree.deleted_at
+		), restored AS (
+			UPDATE tasks SET deleted_at=NULL WHERE id IN (SELECT id FROM subtree) RETURNING id
+		)
+		INSERT INTO task_events (task_id, user_id, action) SELECT id, $1, $3 FROM restored`,
+			userId, taskId, model.ActionRestore,
+		)
+
+		return err
+	})
 }
 
-// GetAll gets all User's tasks
+// Purge permanently deletes a task in the trash with all its subtasks
+func (r *TaskRepository) Purge(userId int, taskId int) error {
+	res, err := r.store.db.Exec(
+		"DELETE FROM tasks WHERE user_id=$1 and id=$2 and deleted_at IS NOT NULL", userId, taskId,
+	)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res, store.ErrInvalidTaskId)
+}
+
+// EmptyTrash permanently deletes all User's tasks in the trash
+func (r *TaskRepository) EmptyTrash(userId int) error {
+	_, err := r.store.db.Exec("DELETE FROM tasks WHERE user_id=$1 and deleted_at IS NOT NULL", userId)
+	return err
+}
+
+// PurgeDeleted permanently deletes the tasks of all users moved to the trash
+// before the given time and returns how many of them were deleted
+func (r *TaskRepository) PurgeDeleted(before time.Time) (int, error) {
+	res, err := r.store.db.Exec("DELETE FROM tasks WHERE deleted_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// GetAll gets all User's tasks and the ones shared with them in their manual order
 func (r *TaskRepository) GetAll(userId int) ([]*model.Task, error) {
-	return r.getUnderHood(userId)
+	return r.getUnderHood(
+		"SELECT "+taskColumns+" FROM tasks WHERE "+visibleTo+" and deleted_at IS NULL ORDER BY user_id, rank, id", userId,
+	)
+}
+
+// Move places the task right after the target task in the manual order, or
+// right before it unless after is set. Both tasks must belong to the user, whose
+// tasks are listed together. Only the moved task gets a new rank unless there
+// is no room left next to the target.
+func (r *TaskRepository) Move(userId int, taskId int, targetId int, after bool) error {
+	if taskId == targetId {
+		return store.ErrInvalidMove
+	}
+
+	return r.store.inTx(func(tx *sql.Tx) error {
+		if err := lockUser(tx, lockRanks, userId); err != nil {
+			return err
+		}
+
+		for reranked := false; ; reranked = true {
+			prev, next, err := moveBounds(tx, userId, taskId, targetId, after)
+			if err != nil {
+				return err
+			}
+
+			rank, err := model.RankBetween(prev, next)
+			if err == model.ErrNoRankBetween && !reranked {
+				if err := rerank(tx, userId); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.Exec("UPDATE tasks SET rank=$1 WHERE id=$2", rank, taskId)
+			return err
+		}
+	})
+}
+
+// moveBounds returns the ranks the moved task goes between, the target and
+// its neighbor on the other side
+func moveBounds(q querier, userId int, taskId int, targetId int, after bool) (string, string, error) {
+	var current, target string
+	for _, row := range []struct {
+		id   int
+		rank *string
+	}{{taskId, &current}, {targetId, &target}} {
+		if err := q.QueryRow(
+			"SELECT rank FROM tasks WHERE user_id=$1 and id=$2 and deleted_at IS NULL FOR UPDATE", userId, row.id,
+		).Scan(row.rank); err != nil {
+			if err == sql.ErrNoRows {
+				return "", "", store.ErrInvalidTaskId
+			}
+			return "", "", err
+		}
+	}
+
+	query := "SELECT COALESCE(MAX(rank), '') FROM tasks WHERE user_id=$1 and id<>$2 and rank < $3"
+	if after {
+		query = "SELECT COALESCE(MIN(rank), '') FROM tasks WHERE user_id=$1 and id<>$2 and rank > $3"
+	}
+
+	var neighbor string
+	if err := q.QueryRow(query, userId, taskId, target).Scan(&neighbor); err != nil {
+		return "", "", err
+	}
+
+	if after {
+		return target, neighbor, nil
+	}
+
+	return neighbor, target, nil
+}
+
+// rerank spreads the ranks of the user's tasks out again keeping their order,
+// the same way the ranks were first given
+func rerank(q querier, userId int) error {
+	_, err := q.Exec(`
+	UPDATE tasks SET rank = ranked.rank FROM (
+		SELECT id, lpad(to_hex(row_number() OVER (ORDER BY rank, id)), 6, '0') AS rank FROM tasks WHERE user_id=$1
+	) AS ranked WHERE tasks.id = ranked.id`,
+		userId,
+	)
+	return err
 }
 
 // GetBool gets all User's tasks that are completed or not completed
 func (r *TaskRepository) GetBool(userId int, status bool) ([]*model.Task, error) {
-	return r.getUnderHood(userId, status)
+	return r.getUnderHood(
+		"SELECT "+taskColumns+" FROM tasks WHERE "+visibleTo+" and deleted_at IS NULL and done=$2", userId, status,
+	)
 }
 
-// Slice of empty interface allows to make more complex database queries
-func (r *TaskRepository) getUnderHood(values ...interface{}) ([]*model.Task, error) {
-	var rows *sql.Rows
-	var err error
-
-	// When we need to get all concrete User's tasks
-	if len(values) == 1 {
-		rows, err = r.store.db.Query(
-			"SELECT * FROM tasks WHERE user_id=$1", values[0].(int),
-		)
+// GetDue gets all User's tasks whose due date lies between after and before.
+// A nil bound leaves that side of the range open.
+func (r *TaskRepository) GetDue(userId int, after, before *time.Time) ([]*model.Task, error) {
+	return r.getUnderHood(
+		"SELECT "+taskColumns+" FROM tasks WHERE "+visibleTo+` and deleted_at IS NULL and due_at IS NOT NULL
+		and ($2::timestamptz IS NULL OR due_at > $2)
+		and ($3::timestamptz IS NULL OR due_at < $3)
+		ORDER BY due_at`,
+		userId, after, before,
+	)
+}
+
+// GetOverdue gets all User's not completed tasks whose due date is before now
+func (r *TaskRepository) GetOverdue(userId int, now time.Time) ([]*model.Task, error) {
+	return r.getUnderHood(
+		"SELECT "+taskColumns+" FROM tasks WHERE "+visibleTo+" and deleted_at IS NULL and done=FALSE and due_at < $2 ORDER BY due_at",
+		userId, now,
+	)
+}
+
+// taskSortKeys maps sort orders to the ordered expression and the type of its cursor value
+var taskSortKeys = map[string]struct{ expr, cast string }{
+	store.SortCreated: {"created_at", "timestamptz"},
+	store.SortTitle:   {"title", "varchar"},
+	// Same as store.RankKey
+	store.SortRank: {`(lpad(user_id::text, 20, '0') || ' ' || rank) COLLATE "C"`, "varchar"},
+	store.SortDue:  {"COALESCE(due_at, 'infinity')", "timestamptz"},
+	// Same as store.PriorityRank
+	store.SortPriority: {"(CASE WHEN done THEN 5 - priority ELSE -priority END)", "integer"},
+}
+
+// Find gets a page of User's tasks and the ones shared with them matching the
+// query. The returned cursor points to the next page and is empty on the last
+// one, a page without tasks is an empty list.
+func (r *TaskRepository) Find(userId int, q *store.TaskQuery) ([]*model.Task, string, error) {
+	sort := q.SortOrDefault()
+	key, ok := taskSortKeys[sort]
+	if !ok {
+		return nil, "", store.ErrInvalidSort
 	}
 
-	// When we need to get all completed/not completed concrete User's tasks
-	if len(values) == 2 {
-		rows, err = r.store.db.Query(
-			"SELECT * FROM tasks WHERE user_id=$1 and done=$2",
-			values[0].(int), values[1].(bool),
-		)
+	conditions := []string{visibleTo, "deleted_at IS NULL"}
+	args := []interface{}{userId}
+	param := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if q.Done != nil {
+		conditions = append(conditions, "done="+param(*q.Done))
+	}
+
+	if q.DueAfter != nil {
+		conditions = append(conditions, "due_at > "+param(*q.DueAfter))
+	}
+
+	if q.DueBefore != nil {
+		conditions = append(conditions, "due_at < "+param(*q.DueBefore))
+	}
+
+	if q.OverdueAt != nil {
+		conditions = append(conditions, "done=FALSE and due_at < "+param(*q.OverdueAt))
+	}
+
+	if q.ProjectID != nil {
+		conditions = append(conditions, "project_id="+param(*q.ProjectID))
+	}
+
+	if q.ParentID != nil {
+		conditions = append(conditions, "parent_id="+param(*q.ParentID))
 	}
 
+	if q.SeriesID != nil {
+		conditions = append(conditions, "COALESCE(series_id, id)="+param(*q.SeriesID))
+	}
+
+	if q.AssigneeID != nil {
+		conditions = append(conditions, "assignee_id="+param(*q.AssigneeID))
+	}
+
+	if len(q.Statuses) > 0 {
+		conditions = append(conditions, "status = ANY("+param(pq.Array(q.Statuses))+")")
+	}
+
+	if len(q.Priorities) > 0 {
+		levels := make([]int64, len(q.Priorities))
+		for i, priority := range q.Priorities {
+			levels[i] = int64(priority.Level())
+		}
+
+		conditions = append(conditions, "priority = ANY("+param(pq.Array(levels))+")")
+	}
+
+	if q.Filter != nil {
+		conditions = append(conditions, filterCondition(q.Filter, param))
+	}
+
+	if len(q.Tags) > 0 {
+		tagged := "SELECT task_tags.task_id FROM task_tags JOIN tags ON tags.id = task_tags.tag_id " +
+			"WHERE tags.name = ANY(" + param(pq.Array(q.Tags)) + ")"
+		if q.AllTags {
+			tagged += " GROUP BY task_tags.task_id HAVING COUNT(*) = " + param(len(q.Tags))
+		}
+
+		conditions = append(conditions, "id IN ("+tagged+")")
+	}
+
+	direction, comparison := "ASC", ">"
+	if q.Desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	if q.Cursor != "" {
+		c, err := store.DecodeCursor(q.Cursor, sort)
+		if err != nil {
+			return nil, "", err
+		}
+
+		conditions = append(conditions, fmt.Sprintf(
+			"(%s, id) %s (%s::%s, %s::bigint)",
+			key.expr, comparison, param(c.Value), key.cast, param(c.ID),
+		))
+	}
+
+	query := fmt.Sprintf(
+		"SELECT %s FROM tasks WHERE %s ORDER BY %s %s, id %s",
+		taskColumns, strings.Join(conditions, " and "), key.expr, direction, direction,
+	)
+
+	// One extra row tells whether there is a next page
+	if q.Limit > 0 {
+		query += " LIMIT " + param(q.Limit+1)
+	}
+
+	tasks, err := r.getUnderHood(query, args...)
+	if err == store.ErrNoRecordsInTable {
+		// An empty page is a list without tasks rather than missing
+		return []*model.Task{}, "", nil
+	}
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	if q.Limit > 0 && len(tasks) > q.Limit {
+		tasks = tasks[:q.Limit]
+		return tasks, store.NewCursor(sort, tasks[len(tasks)-1]), nil
+	}
+
+	return tasks, "", nil
+}
+
+// History gets the events of a User's task or one shared with them, including
+// one in the trash, from the oldest
+func (r *TaskRepository) History(userId int, taskId int) ([]*model.TaskEvent, error) {
+	var found bool
+	if err := r.store.db.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM tasks WHERE "+visibleTo+" and id=$2)", userId, taskId,
+	).Scan(&found); err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, store.ErrInvalidTaskId
+	}
+
+	events, err := getEvents(r.store.db,
+		"SELECT id, task_id, user_id, action, changes, created_at FROM task_events WHERE task_id=$1 ORDER BY created_at, id",
+		taskId,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(events) == 0 {
+		return nil, store.ErrNoRecordsInTable
+	}
+
+	return events, nil
+}
+
+// ReportHistory returns the User's tasks in the scope of a report, the ones
+// in the trash too, together with all their events
+func (r *TaskRepository) ReportHistory(userId int, scope *store.ReportScope) ([]*model.Task, []*model.TaskEvent, error) {
+	tasks, err := r.getUnderHood(`
+	SELECT `+taskColumns+` FROM tasks
+	WHERE user_id=$1 and ($2::bigint IS NULL or project_id=$2) and ($3 = '' or EXISTS (
+		SELECT 1 FROM task_tags JOIN tags ON tags.id = task_tags.tag_id
+		WHERE task_tags.task_id = tasks.id and tags.name=$3))
+	ORDER BY id`,
+		userId, scope.ProjectID, scope.Tag,
+	)
+	if err != nil && err != store.ErrNoRecordsInTable {
+		return nil, nil, err
+	}
+
+	ids := make(pq.Int64Array, len(tasks))
+	for i, task := range tasks {
+		ids[i] = int64(task.ID)
+	}
+
+	events, err := getEvents(r.store.db,
+		"SELECT id, task_id, user_id, action, changes, created_at FROM task_events WHERE task_id = ANY($1) ORDER BY created_at, id",
+		ids,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return tasks, events, nil
+}
+
+func getEvents(q querier, query string, args ...interface{}) ([]*model.TaskEvent, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*model.TaskEvent
+	for rows.Next() {
+		e := &model.TaskEvent{}
+		var changes []byte
+		if err := rows.Scan(&e.ID, &e.TaskID, &e.UserID, &e.Action, &changes, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(changes, &e.Changes); err != nil {
+			return nil, err
+		}
+
+		if len(e.Changes) == 0 {
+			e.Changes = nil
+		}
+
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
+
+// recordEvent adds an event made by the user to the task history
+func recordEvent(q querier, userId int, taskId int, action string, changes map[string]model.Change) error {
+	if changes == nil {
+		changes = map[string]model.Change{}
+	}
+
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.Exec(
+		"INSERT INTO task_events (task_id, user_id, action, changes) VALUES ($1, $2, $3, $4)",
+		taskId, userId, action, data,
+	)
+
+	return err
+}
+
+// escapedText is the text of a task with the HTML special characters escaped
+// like html.EscapeString does, so that only the highlights of a snippet are markup
+const escapedText = `replace(replace(replace(replace(replace(title || ' ' || description,
+	'&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&#34;'), '''', '&#39;')`
+
+// Search finds User's tasks and the ones shared with them matching a web
+// search style query like "milk or bread -cheese", the most relevant first.
+// A zero limit means no limit.
+func (r *TaskRepository) Search(userId int, text string, limit int) ([]*model.TaskMatch, error) {
+	query := "SELECT " + taskColumns + `,
+		ts_rank(search, query) AS search_rank,
+		ts_headline('english', ` + escapedText + `, query,
+			'StartSel=<b>, StopSel=</b>, MinWords=5, MaxWords=20, MaxFragments=2')
+	FROM tasks, websearch_to_tsquery('english', $2) AS query
+	WHERE ` + visibleTo + ` and deleted_at IS NULL and search @@ query
+	ORDER BY search_rank DESC, id`
+	args := []interface{}{userId, text}
+	if limit > 0 {
+		query += " LIMIT $3"
+		args = append(args, limit)
+	}
+
+	rows, err := r.store.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []*model.TaskMatch
+	for rows.Next() {
+		m := &model.TaskMatch{}
+		if m.Task, err = scanTask(extraScanner{rows, []interface{}{&m.Rank, &m.Snippet}}); err != nil {
+			return nil, err
+		}
+
+		matches = append(matches, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(matches) == 0 {
+		return nil, store.ErrNoRecordsInTable
+	}
+
+	return matches, nil
+}
+
+// extraScanner reads the columns selected after taskColumns into extra
+type extraScanner struct {
+	scanner
+	extra []interface{}
+}
+
+func (s extraScanner) Scan(dest ...interface{}) error {
+	return s.scanner.Scan(append(dest, s.extra...)...)
+}
+
+// Slice of empty interface allows to make more complex database queries
+func (r *TaskRepository) getUnderHood(query string, args ...interface{}) ([]*model.Task, error) {
+	rows, err := r.store.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -120,8 +1236,7 @@ func (r *TaskRepository) getUnderHood(values ...interface{}) ([]*model.Task, err
 
 	tasks := make([]*model.Task, 0, 5)
 	for rows.Next() {
-		s := &model.Task{}
-		err := rows.Scan(&s.ID, &s.UserID, &s.Title, &s.Description, &s.Done, &s.CreationDate)
+		s, err := scanTask(rows)
 		if err != nil {
 			return nil, err
 		}
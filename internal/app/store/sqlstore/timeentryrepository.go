@@ -0,0 +1,145 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/pyuldashev912/todoapp/internal/app/store"
+)
+
+const timeEntryColumns = "id, user_id, task_id, started_at, stopped_at, note"
+
+type TimeEntryRepository struct {
+	store *Store
+}
+
+// Start starts a timer on the task. The database keeps at most one running
+// timer per user.
+func (r *TimeEntryRepository) Start(userId int, taskId int) (*model.TimeEntry, error) {
+	if err := checkEditable(r.store.db, userId, taskId); err != nil {
+		return nil, err
+	}
+
+	e := &model.TimeEntry{UserID: userId, TaskID: taskId, StartedAt: time.Now()}
+	if err := r.store.db.QueryRow(
+		"INSERT INTO time_entries (user_id, task_id, started_at) VALUES ($1, $2, $3) RETURNING id",
+		e.UserID, e.TaskID, e.StartedAt,
+	).Scan(&e.ID); err != nil {
+		if err, ok := err.(*pq.Error); ok && err.Code == uniqueViolation {
+			return nil, store.ErrTimerRunning
+		}
+		return nil, err
+	}
+
+	return e, nil
+}
+
+// Stop stops the timer running on the task
+func (r *TimeEntryRepository) Stop(userId int, taskId int) (*model.TimeEntry, error) {
+	e, err := scanTimeEntry(r.store.db.QueryRow(
+		"UPDATE time_entries SET stopped_at=$3 WHERE user_id=$1 and task_id=$2 and stopped_at IS NULL RETURNING "+timeEntryColumns,
+		userId, taskId, time.Now(),
+	))
+	if err == sql.ErrNoRows {
+		return nil, store.ErrNoTimerRunning
+	}
+
+	return e, err
+}
+
+// Running returns the timer running for the user
+func (r *TimeEntryRepository) Running(userId int) (*model.TimeEntry, error) {
+	e, err := scanTimeEntry(r.store.db.QueryRow(
+		"SELECT "+timeEntryColumns+" FROM time_entries WHERE user_id=$1 and stopped_at IS NULL", userId,
+	))
+	if err == sql.ErrNoRows {
+		return nil, store.ErrNoRecordsInTable
+	}
+
+	return e, err
+}
+
+// Create logs time spent on the task by hand
+func (r *TimeEntryRepository) Create(e *model.TimeEntry) error {
+	if err := e.Validate(); err != nil {
+		return err
+	}
+
+	if err := checkEditable(r.store.db, e.UserID, e.TaskID); err != nil {
+		return err
+	}
+
+	e.SetDuration(time.Now())
+	return r.store.db.QueryRow(
+		"INSERT INTO time_entries (user_id, task_id, started_at, stopped_at, note) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+		e.UserID, e.TaskID, e.StartedAt, e.StoppedAt, e.Note,
+	).Scan(&e.ID)
+}
+
+// Delete deletes a time entry, a running timer is discarded
+func (r *TimeEntryRepository) Delete(userId int, entryId int) error {
+	res, err := r.store.db.Exec("DELETE FROM time_entries WHERE user_id=$1 and id=$2", userId, entryId)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res, store.ErrNoRecordsInTable)
+}
+
+// GetByTask gets all entries of the task from the oldest
+func (r *TimeEntryRepository) GetByTask(userId int, taskId int) ([]*model.TimeEntry, error) {
+	if _, err := getTask(r.store.db, userId, taskId); err != nil {
+		return nil, err
+	}
+
+	return r.getUnderHood(
+		"SELECT "+timeEntryColumns+" FROM time_entries WHERE user_id=$1 and task_id=$2 ORDER BY started_at, id",
+		userId, taskId,
+	)
+}
+
+// Find gets the entries of the User's tasks overlapping the period, entries
+// of tasks in the trash are left out
+func (r *TimeEntryRepository) Find(userId int, from time.Time, to time.Time) ([]*model.TimeEntry, error) {
+	return r.getUnderHood(`
+	SELECT time_entries.id, time_entries.user_id, task_id, started_at, stopped_at, note FROM time_entries
+	JOIN tasks ON tasks.id = time_entries.task_id
+	WHERE time_entries.user_id=$1 and tasks.deleted_at IS NULL
+		and started_at < $3 and (stopped_at IS NULL or stopped_at > $2)
+	ORDER BY started_at, time_entries.id`,
+		userId, from, to,
+	)
+}
+
+func (r *TimeEntryRepository) getUnderHood(query string, args ...interface{}) ([]*model.TimeEntry, error) {
+	rows, err := r.store.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []*model.TimeEntry{}
+	for rows.Next() {
+		e, err := scanTimeEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// scanTimeEntry reads a single time_entries row selected with timeEntryColumns
+func scanTimeEntry(row scanner) (*model.TimeEntry, error) {
+	e := &model.TimeEntry{}
+	if err := row.Scan(&e.ID, &e.UserID, &e.TaskID, &e.StartedAt, &e.StoppedAt, &e.Note); err != nil {
+		return nil, err
+	}
+
+	e.SetDuration(time.Now())
+	return e, nil
+}
@@ -2,6 +2,7 @@ package sqlstore
 
 import (
 	"database/sql"
+	"time"
 
 	"github.com/pyuldashev912/todoapp/internal/app/model"
 	"github.com/pyuldashev912/todoapp/internal/app/store"
@@ -33,8 +34,8 @@ func (r *UserRepository) Create(user *model.User) error {
 func (r *UserRepository) FindByEmail(email string) (*model.User, error) {
 	user := &model.User{}
 	if err := r.store.db.QueryRow(
-		`SELECT id, name, email, encrypted_password FROM users WHERE email=$1`, email,
-	).Scan(&user.ID, &user.Name, &user.Email, &user.EncryptedPassword); err != nil {
+		`SELECT id, name, email, encrypted_password, verified, session_version FROM users WHERE email=$1`, email,
+	).Scan(&user.ID, &user.Name, &user.Email, &user.EncryptedPassword, &user.Verified, &user.SessionVersion); err != nil {
 		return nil, err
 	}
 
@@ -45,8 +46,8 @@ func (r *UserRepository) FindByEmail(email string) (*model.User, error) {
 func (r *UserRepository) FindById(userId int) (*model.User, error) {
 	user := &model.User{}
 	if err := r.store.db.QueryRow(
-		`SELECT id, name, email FROM users WHERE id=$1`, userId,
-	).Scan(&user.ID, &user.Name, &user.Email); err != nil {
+		`SELECT id, name, email, verified, session_version FROM users WHERE id=$1`, userId,
+	).Scan(&user.ID, &user.Name, &user.Email, &user.Verified, &user.SessionVersion); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, store.ErrNoRecordsInTable
 		}
@@ -56,3 +57,119 @@ func (r *UserRepository) FindById(userId int) (*model.User, error) {
 
 	return user, nil
 }
+
+// CreatePasswordReset creates a password reset token of the user replacing
+// their previous ones
+func (r *UserRepository) CreatePasswordReset(p *model.PasswordReset) error {
+	if err := p.BeforeCreate(time.Now()); err != nil {
+		return err
+	}
+
+	return r.store.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM password_resets WHERE user_id=$1", p.UserID); err != nil {
+			return err
+		}
+
+		return tx.QueryRow(
+			"INSERT INTO password_resets (user_id, token_hash, expires_at) VALUES ($1, $2, $3) RETURNING id, created_at",
+			p.UserID, p.TokenHash, p.ExpiresAt,
+		).Scan(&p.ID, &p.CreatedAt)
+	})
+}
+
+// ResetPassword sets a new password of the user the token was created for.
+// The token can only be used once and the sessions of the user logged in
+// before the reset are invalidated. Getting the token by email verifies it.
+func (r *UserRepository) ResetPassword(token string, password string) (*model.User, error) {
+	user := &model.User{Password: password}
+	if err := user.ValidatePassword(); err != nil {
+		return nil, err
+	}
+
+	if err := user.EncryptPassword(); err != nil {
+		return nil, err
+	}
+
+	if err := r.store.inTx(func(tx *sql.Tx) error {
+		var expiresAt time.Time
+		if err := tx.QueryRow(
+			"SELECT user_id, expires_at FROM password_resets WHERE token_hash=$1 FOR UPDATE", model.HashToken(token),
+		).Scan(&user.ID, &expiresAt); err != nil {
+			if err == sql.ErrNoRows {
+				return store.ErrInvalidResetToken
+			}
+			return err
+		}
+
+		if !expiresAt.After(time.Now()) {
+			return store.ErrInvalidResetToken
+		}
+
+		if _, err := tx.Exec("DELETE FROM password_resets WHERE user_id=$1", user.ID); err != nil {
+			return err
+		}
+
+		return tx.QueryRow(`
+		UPDATE users SET encrypted_password=$1, session_version=session_version+1, verified=true WHERE id=$2
+		RETURNING name, email, verified, session_version`,
+			user.EncryptedPassword, user.ID,
+		).Scan(&user.Name, &user.Email, &user.Verified, &user.SessionVersion)
+	}); err != nil {
+		return nil, err
+	}
+
+	user.Sanitize()
+	return user, nil
+}
+
+// CreateVerification creates an email verification token of the user
+// replacing their previous ones
+func (r *UserRepository) CreateVerification(v *model.EmailVerification) error {
+	if err := v.BeforeCreate(time.Now()); err != nil {
+		return err
+	}
+
+	return r.store.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec("DELETE FROM email_verifications WHERE user_id=$1", v.UserID); err != nil {
+			return err
+		}
+
+		return tx.QueryRow(
+			"INSERT INTO email_verifications (user_id, token_hash, expires_at) VALUES ($1, $2, $3) RETURNING id, created_at",
+			v.UserID, v.TokenHash, v.ExpiresAt,
+		).Scan(&v.ID, &v.CreatedAt)
+	})
+}
+
+// Verify marks the email of the user the token was created for as verified,
+// the token can only be used once
+func (r *UserRepository) Verify(token string) (*model.User, error) {
+	user := &model.User{}
+	if err := r.store.inTx(func(tx *sql.Tx) error {
+		var expiresAt time.Time
+		if err := tx.QueryRow(
+			"SELECT user_id, expires_at FROM email_verifications WHERE token_hash=$1 FOR UPDATE", model.HashToken(token),
+		).Scan(&user.ID, &expiresAt); err != nil {
+			if err == sql.ErrNoRows {
+				return store.ErrInvalidVerification
+			}
+			return err
+		}
+
+		if !expiresAt.After(time.Now()) {
+			return store.ErrInvalidVerification
+		}
+
+		if _, err := tx.Exec("DELETE FROM email_verifications WHERE user_id=$1", user.ID); err != nil {
+			return err
+		}
+
+		return tx.QueryRow(
+			"UPDATE users SET verified=true WHERE id=$1 RETURNING name, email, verified, session_version", user.ID,
+		).Scan(&user.Name, &user.Email, &user.Verified, &user.SessionVersion)
+	}); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
@@ -50,3 +50,66 @@ func TestUserRepository_FindById(t *testing.T) {
 	_, err = s.User().FindById(3)
 	assert.EqualError(t, err, store.ErrNoRecordsInTable.Error())
 }
+
+func TestUserRepository_ResetPassword(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseURL)
+	defer teardown("users", "password_resets")
+
+	s := sqlstore.New(db)
+	u := model.TestUser(t)
+	s.User().Create(u)
+
+	first := &model.PasswordReset{UserID: u.ID}
+	assert.NoError(t, s.User().CreatePasswordReset(first))
+	p := &model.PasswordReset{UserID: u.ID}
+	assert.NoError(t, s.User().CreatePasswordReset(p))
+	assert.NotEmpty(t, p.Token)
+
+	_, err := s.User().ResetPassword(first.Token, "NewPassword")
+	assert.EqualError(t, err, store.ErrInvalidResetToken.Error())
+
+	_, err = s.User().ResetPassword(p.Token, "123")
+	assert.Error(t, err)
+
+	res, err := s.User().ResetPassword(p.Token, "NewPassword")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, res.SessionVersion)
+
+	found, _ := s.User().FindByEmail(u.Email)
+	assert.True(t, found.ComparePassword("NewPassword"))
+	assert.Equal(t, 1, found.SessionVersion)
+	assert.True(t, found.Verified)
+
+	_, err = s.User().ResetPassword(p.Token, "OtherPassword")
+	assert.EqualError(t, err, store.ErrInvalidResetToken.Error())
+}
+
+func TestUserRepository_Verify(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseURL)
+	defer teardown("users", "email_verifications")
+
+	s := sqlstore.New(db)
+	u := model.TestUser(t)
+	s.User().Create(u)
+
+	found, _ := s.User().FindById(u.ID)
+	assert.False(t, found.Verified)
+
+	first := &model.EmailVerification{UserID: u.ID}
+	assert.NoError(t, s.User().CreateVerification(first))
+	v := &model.EmailVerification{UserID: u.ID}
+	assert.NoError(t, s.User().CreateVerification(v))
+
+	_, err := s.User().Verify(first.Token)
+	assert.EqualError(t, err, store.ErrInvalidVerification.Error())
+
+	res, err := s.User().Verify(v.Token)
+	assert.NoError(t, err)
+	assert.True(t, res.Verified)
+
+	found, _ = s.User().FindByEmail(u.Email)
+	assert.True(t, found.Verified)
+
+	_, err = s.User().Verify(v.Token)
+	assert.EqualError(t, err, store.ErrInvalidVerification.Error())
+}
@@ -0,0 +1,184 @@
+package sqlstore
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/pyuldashev912/todoapp/internal/app/store"
+)
+
+type WorkflowRepository struct {
+	store *Store
+}
+
+// Get returns the workflow followed by the tasks of the project, or by the
+// tasks outside of projects if projectId is nil
+func (r *WorkflowRepository) Get(userId int, projectId *int) (*model.Workflow, error) {
+	if err := checkProject(r.store.db, &model.Task{UserID: userId, ProjectID: projectId}); err != nil {
+		return nil, err
+	}
+
+	workflows, err := loadWorkflows(r.store.db, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return workflows.of(projectId), nil
+}
+
+// Save replaces the own workflow of the user or of the project. Tasks left
+// with a status the new workflow doesn't have get its initial or done status.
+func (r *WorkflowRepository) Save(w *model.Workflow) error {
+	if err := w.Validate(); err != nil {
+		return err
+	}
+
+	statuses, err := json.Marshal(w.Statuses)
+	if err != nil {
+		return err
+	}
+
+	var transitions []byte
+	if w.Transitions != nil {
+		if transitions, err = json.Marshal(w.Transitions); err != nil {
+			return err
+		}
+	}
+
+	return r.store.inTx(func(tx *sql.Tx) error {
+		if err := checkProject(tx, &model.Task{UserID: w.UserID, ProjectID: w.ProjectID}); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(
+			"DELETE FROM workflows WHERE user_id=$1 and project_id IS NOT DISTINCT FROM $2::bigint",
+			w.UserID, w.ProjectID,
+		); err != nil {
+			return err
+		}
+
+		if err := tx.QueryRow(
+			"INSERT INTO workflows (user_id, project_id, statuses, transitions) VALUES ($1, $2, $3, $4) RETURNING id",
+			w.UserID, w.ProjectID, statuses, transitions,
+		).Scan(&w.ID); err != nil {
+			return err
+		}
+
+		return remapStatuses(tx, w.UserID)
+	})
+}
+
+// Delete removes the own workflow of the user or of the project, the tasks
+// follow the workflow of the user or the default one again
+func (r *WorkflowRepository) Delete(userId int, projectId *int) error {
+	return r.store.inTx(func(tx *sql.Tx) error {
+		res, err := tx.Exec(
+			"DELETE FROM workflows WHERE user_id=$1 and project_id IS NOT DISTINCT FROM $2::bigint",
+			userId, projectId,
+		)
+		if err != nil {
+			return err
+		}
+
+		if err := checkAffected(res, store.ErrNoRecordsInTable); err != nil {
+			return err
+		}
+
+		return remapStatuses(tx, userId)
+	})
+}
+
+// workflowSet holds all workflows of a user
+type workflowSet struct {
+	user     *model.Workflow
+	projects map[int]*model.Workflow
+}
+
+// of returns the workflow followed by the tasks of the project
+func (s *workflowSet) of(projectId *int) *model.Workflow {
+	if projectId != nil {
+		if w, ok := s.projects[*projectId]; ok {
+			return w
+		}
+	}
+
+	return s.user
+}
+
+func loadWorkflows(q querier, userId int) (*workflowSet, error) {
+	rows, err := q.Query(
+		"SELECT id, project_id, statuses, transitions FROM workflows WHERE user_id=$1", userId,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	s := &workflowSet{user: model.DefaultWorkflow(), projects: map[int]*model.Workflow{}}
+	for rows.Next() {
+		w := &model.Workflow{UserID: userId}
+		var statuses, transitions []byte
+		if err := rows.Scan(&w.ID, &w.ProjectID, &statuses, &transitions); err != nil {
+			return nil, err
+		}
+
+		if err := json.Unmarshal(statuses, &w.Statuses); err != nil {
+			return nil, err
+		}
+
+		if transitions != nil {
+			if err := json.Unmarshal(transitions, &w.Transitions); err != nil {
+				return nil, err
+			}
+		}
+
+		if w.ProjectID == nil {
+			s.user = w
+		} else {
+			s.projects[*w.ProjectID] = w
+		}
+	}
+
+	return s, rows.Err()
+}
+
+// remapStatuses moves the User's tasks with a status their workflow doesn't
+// have to its initial or done status
+func remapStatuses(q querier, userId int) error {
+	workflows, err := loadWorkflows(q, userId)
+	if err != nil {
+		return err
+	}
+
+	rows, err := q.Query("SELECT id, project_id, status, done FROM tasks WHERE user_id=$1", userId)
+	if err != nil {
+		return err
+	}
+
+	remapped := map[int]string{}
+	for rows.Next() {
+		task := &model.Task{}
+		if err := rows.Scan(&task.ID, &task.ProjectID, &task.Status, &task.Done); err != nil {
+			rows.Close()
+			return err
+		}
+
+		if status := workflows.of(task.ProjectID).StatusOf(task); status != task.Status {
+			remapped[task.ID] = status
+		}
+	}
+	rows.Close()
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, status := range remapped {
+		if _, err := q.Exec("UPDATE tasks SET status=$1 WHERE id=$2", status, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
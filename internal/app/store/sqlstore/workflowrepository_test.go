@@ -0,0 +1,90 @@
+package sqlstore_test
+
+import (
+	"testing"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/pyuldashev912/todoapp/internal/app/store"
+	"github.com/pyuldashev912/todoapp/internal/app/store/sqlstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkflowRepository_Get(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseURL)
+	defer teardown("tasks", "projects", "workflows")
+
+	s := sqlstore.New(db)
+	p := model.TestProject(t)
+	s.Project().Create(p)
+
+	// Without own workflows everything follows the default one
+	w, err := s.Workflow().Get(1, &p.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, model.DefaultWorkflow().Statuses, w.Statuses)
+
+	assert.NoError(t, s.Workflow().Save(model.TestWorkflow(t)))
+	w, err = s.Workflow().Get(1, &p.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, w.ProjectID)
+	assert.Equal(t, "backlog", w.Initial())
+
+	_, err = s.Workflow().Get(2, &p.ID)
+	assert.EqualError(t, err, store.ErrInvalidProjectId.Error())
+}
+
+func TestWorkflowRepository_Save(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseURL)
+	defer teardown("tasks", "projects", "workflows")
+
+	s := sqlstore.New(db)
+	p := model.TestProject(t)
+	s.Project().Create(p)
+	open, done, inProject := model.TestTask(t), model.TestTask(t), model.TestTask(t)
+	s.Task().Create(open)
+	s.Task().Create(done)
+	s.Task().Done(done.UserID, done.ID)
+	inProject.ProjectID = &p.ID
+	s.Task().Create(inProject)
+
+	assert.Error(t, s.Workflow().Save(&model.Workflow{UserID: 1}))
+	missing := model.TestWorkflow(t)
+	missing.ProjectID = &p.ID
+	missing.UserID = 2
+	assert.EqualError(t, s.Workflow().Save(missing), store.ErrInvalidProjectId.Error())
+
+	// Tasks get the initial or the done status of the new workflow
+	assert.NoError(t, s.Workflow().Save(model.TestWorkflow(t)))
+	for task, status := range map[*model.Task]string{open: "backlog", done: "shipped", inProject: "backlog"} {
+		res, err := s.Task().GetById(task.UserID, task.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, status, res.Status)
+	}
+
+	// A project workflow applies to the project tasks only
+	w := model.DefaultWorkflow()
+	w.UserID, w.ProjectID = 1, &p.ID
+	assert.NoError(t, s.Workflow().Save(w))
+	res, err := s.Task().GetById(inProject.UserID, inProject.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "todo", res.Status)
+	res, err = s.Task().GetById(open.UserID, open.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "backlog", res.Status)
+}
+
+func TestWorkflowRepository_Delete(t *testing.T) {
+	db, teardown := sqlstore.TestDB(t, databaseURL)
+	defer teardown("tasks", "projects", "workflows")
+
+	s := sqlstore.New(db)
+	task := model.TestTask(t)
+	s.Task().Create(task)
+
+	assert.EqualError(t, s.Workflow().Delete(1, nil), store.ErrNoRecordsInTable.Error())
+	s.Workflow().Save(model.TestWorkflow(t))
+	assert.NoError(t, s.Workflow().Delete(1, nil))
+
+	res, err := s.Task().GetById(task.UserID, task.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "todo", res.Status)
+}
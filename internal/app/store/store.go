@@ -3,4 +3,12 @@ package store
 type Store interface {
 	User() UserRepository
 	Task() TaskRepository
+	Tag() TagRepository
+	Project() ProjectRepository
+	Workflow() WorkflowRepository
+	TimeEntry() TimeEntryRepository
+	Comment() CommentRepository
+	Attachment() AttachmentRepository
+	Share() ShareRepository
+	Organization() OrganizationRepository
 }
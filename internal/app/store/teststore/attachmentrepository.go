@@ -0,0 +1,112 @@
+package teststore
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/pyuldashev912/todoapp/internal/app/store"
+)
+
+type AttachmentRepository struct {
+	store       *Store
+	attachments map[int]*model.Attachment
+	lastId      int
+}
+
+func (r *AttachmentRepository) Create(a *model.Attachment) error {
+	if err := a.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := r.store.tasks().editable(a.UserID, a.TaskID); err != nil {
+		return err
+	}
+
+	r.lastId++
+	a.ID = r.lastId
+	a.CreatedAt = time.Now()
+	stored := *a
+	r.attachments[a.ID] = &stored
+
+	return nil
+}
+
+func (r *AttachmentRepository) GetByTask(userId int, taskId int) ([]*model.Attachment, error) {
+	if _, err := r.store.Task().GetById(userId, taskId); err != nil {
+		return nil, err
+	}
+
+	return r.filter(func(a *model.Attachment) bool {
+		return a.TaskID == taskId
+	}), nil
+}
+
+func (r *AttachmentRepository) GetById(userId int, taskId int, attachmentId int) (*model.Attachment, error) {
+	if _, err := r.store.Task().GetById(userId, taskId); err != nil {
+		return nil, err
+	}
+
+	a, ok := r.attachments[attachmentId]
+	if !ok || a.TaskID != taskId {
+		return nil, store.ErrInvalidAttachmentId
+	}
+
+	found := *a
+	return &found, nil
+}
+
+func (r *AttachmentRepository) Delete(userId int, taskId int, attachmentId int) error {
+	if _, err := r.store.tasks().editable(userId, taskId); err != nil {
+		return err
+	}
+
+	if _, err := r.GetById(userId, taskId, attachmentId); err != nil {
+		return err
+	}
+
+	delete(r.attachments, attachmentId)
+	return nil
+}
+
+func (r *AttachmentRepository) Orphans() ([]*model.Attachment, error) {
+	return r.filter(func(a *model.Attachment) bool {
+		return a.TaskID == 0
+	}), nil
+}
+
+func (r *AttachmentRepository) DeleteOrphan(attachmentId int) error {
+	a, ok := r.attachments[attachmentId]
+	if !ok || a.TaskID != 0 {
+		return store.ErrInvalidAttachmentId
+	}
+
+	delete(r.attachments, attachmentId)
+	return nil
+}
+
+func (r *AttachmentRepository) filter(keep func(*model.Attachment) bool) []*model.Attachment {
+	attachments := []*model.Attachment{}
+	for _, a := range r.attachments {
+		if keep(a) {
+			found := *a
+			attachments = append(attachments, &found)
+		}
+	}
+
+	sort.Slice(attachments, func(i, j int) bool {
+		return attachments[i].ID < attachments[j].ID
+	})
+
+	return attachments
+}
+
+// deleteTask orphans the attachments of a purged task the same way the database
+// sets their task to NULL
+func (r *AttachmentRepository) deleteTask(taskId int) {
+	for _, a := range r.attachments {
+		if a.TaskID == taskId {
+			a.TaskID = 0
+		}
+	}
+}
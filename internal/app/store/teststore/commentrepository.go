@@ -0,0 +1,118 @@
+package teststore
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/pyuldashev912/todoapp/internal/app/store"
+)
+
+type CommentRepository struct {
+	store    *Store
+	comments map[int]*model.Comment
+	lastId   int
+}
+
+func (r *CommentRepository) Create(c *model.Comment) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := r.store.tasks().editable(c.UserID, c.TaskID); err != nil {
+		return err
+	}
+
+	r.lastId++
+	c.ID = r.lastId
+	c.CreatedAt = time.Now()
+	stored := *c
+	r.comments[c.ID] = &stored
+
+	return nil
+}
+
+func (r *CommentRepository) GetByTask(userId int, taskId int) ([]*model.Comment, error) {
+	if _, err := r.store.Task().GetById(userId, taskId); err != nil {
+		return nil, err
+	}
+
+	comments := []*model.Comment{}
+	for _, c := range r.comments {
+		if c.TaskID == taskId {
+			found := *c
+			comments = append(comments, &found)
+		}
+	}
+
+	sort.Slice(comments, func(i, j int) bool {
+		return comments[i].ID < comments[j].ID
+	})
+
+	return comments, nil
+}
+
+func (r *CommentRepository) Update(c *model.Comment) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	stored, err := r.checkAuthor(c.UserID, c.TaskID, c.ID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	stored.Body = c.Body
+	stored.UpdatedAt = &now
+	c.CreatedAt, c.UpdatedAt = stored.CreatedAt, stored.UpdatedAt
+
+	return nil
+}
+
+func (r *CommentRepository) Delete(userId int, taskId int, commentId int) error {
+	if _, err := r.checkAuthor(userId, taskId, commentId); err != nil {
+		return err
+	}
+
+	delete(r.comments, commentId)
+	return nil
+}
+
+func (r *CommentRepository) checkAuthor(userId int, taskId int, commentId int) (*model.Comment, error) {
+	if _, err := r.store.Task().GetById(userId, taskId); err != nil {
+		return nil, err
+	}
+
+	c, ok := r.comments[commentId]
+	if !ok || c.TaskID != taskId {
+		return nil, store.ErrInvalidCommentId
+	}
+
+	if c.UserID != userId {
+		return nil, store.ErrNotCommentAuthor
+	}
+
+	return c, nil
+}
+
+// count returns the number of comments on the task
+func (r *CommentRepository) count(taskId int) int {
+	n := 0
+	for _, c := range r.comments {
+		if c.TaskID == taskId {
+			n++
+		}
+	}
+
+	return n
+}
+
+// deleteTask drops the comments of a purged task the same way the database cascades
+func (r *CommentRepository) deleteTask(taskId int) {
+	for id, c := range r.comments {
+		if c.TaskID == taskId {
+			delete(r.comments, id)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package teststore_test
+
+import (
+	"testing"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/pyuldashev912/todoapp/internal/app/store"
+	"github.com/pyuldashev912/todoapp/internal/app/store/teststore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommentRepository_Create(t *testing.T) {
+	s := teststore.New()
+	task := model.TestTask(t)
+	s.Task().Create(task)
+
+	c := model.TestComment(t)
+	c.TaskID = task.ID
+	assert.NoError(t, s.Comment().Create(c))
+	assert.NotZero(t, c.ID)
+
+	empty := model.TestComment(t)
+	empty.TaskID = task.ID
+	empty.Body = ""
+	assert.Error(t, s.Comment().Create(empty))
+
+	other := model.TestComment(t)
+	other.TaskID = task.ID
+	other.UserID = 2
+	assert.EqualError(t, s.Comment().Create(other), store.ErrInvalidTaskId.Error())
+
+	comments, err := s.Comment().GetByTask(1, task.ID)
+	assert.NoError(t, err)
+	assert.Len(t, comments, 1)
+	assert.Equal(t, c.Body, comments[0].Body)
+
+	// Listings count the comments
+	found, _ := s.Task().GetById(1, task.ID)
+	assert.Equal(t, 1, found.CommentCount)
+}
+
+func TestCommentRepository_Update(t *testing.T) {
+	s := teststore.New()
+	task := model.TestTask(t)
+	s.Task().Create(task)
+	c := model.TestComment(t)
+	c.TaskID = task.ID
+	s.Comment().Create(c)
+
+	edited := &model.Comment{ID: c.ID, TaskID: task.ID, UserID: 1, Body: "Edited"}
+	assert.NoError(t, s.Comment().Update(edited))
+	assert.NotNil(t, edited.UpdatedAt)
+
+	comments, _ := s.Comment().GetByTask(1, task.ID)
+	assert.Equal(t, "Edited", comments[0].Body)
+
+	missing := &model.Comment{ID: c.ID + 1, TaskID: task.ID, UserID: 1, Body: "Edited"}
+	assert.EqualError(t, s.Comment().Update(missing), store.ErrInvalidCommentId.Error())
+}
+
+func TestCommentRepository_Delete(t *testing.T) {
+	s := teststore.New()
+	task := model.TestTask(t)
+	s.Task().Create(task)
+	c := model.TestComment(t)
+	c.TaskID = task.ID
+	s.Comment().Create(c)
+
+	assert.EqualError(t, s.Comment().Delete(2, task.ID, c.ID), store.ErrInvalidTaskId.Error())
+	assert.NoError(t, s.Comment().Delete(1, task.ID, c.ID))
+	assert.EqualError(t, s.Comment().Delete(1, task.ID, c.ID), store.ErrInvalidCommentId.Error())
+
+	comments, err := s.Comment().GetByTask(1, task.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, comments)
+}
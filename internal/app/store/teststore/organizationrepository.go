@@ -0,0 +1,264 @@
+package teststore
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/pyuldashev912/todoapp/internal/app/store"
+)
+
+type OrganizationRepository struct {
+	store *Store
+	orgs  map[int]*model.Organization
+	// members holds the members of each organization by user id
+	members          map[int]map[int]*model.Member
+	invitations      map[int]*model.Invitation
+	lastId           int
+	lastInvitationId int
+}
+
+func (r *OrganizationRepository) Create(org *model.Organization, ownerId int) error {
+	if err := org.Validate(); err != nil {
+		return err
+	}
+
+	r.lastId++
+	org.ID = r.lastId
+	org.CreatedAt = time.Now()
+	org.Role = model.OrgRoleOwner
+	stored := *org
+	stored.Role = ""
+	r.orgs[org.ID] = &stored
+	r.members[org.ID] = map[int]*model.Member{
+		ownerId: {OrganizationID: org.ID, UserID: ownerId, Role: model.OrgRoleOwner, CreatedAt: org.CreatedAt},
+	}
+
+	return nil
+}
+
+func (r *OrganizationRepository) GetById(userId int, orgId int) (*model.Organization, error) {
+	org, ok := r.orgs[orgId]
+	m, member := r.members[orgId][userId]
+	if !ok || !member {
+		return nil, store.ErrInvalidOrganizationId
+	}
+
+	found := *org
+	found.Role = m.Role
+	return &found, nil
+}
+
+func (r *OrganizationRepository) GetAll(userId int) ([]*model.Organization, error) {
+	orgs := []*model.Organization{}
+	for id := range r.orgs {
+		if org, err := r.GetById(userId, id); err == nil {
+			orgs = append(orgs, org)
+		}
+	}
+
+	sort.Slice(orgs, func(i, j int) bool {
+		return orgs[i].ID < orgs[j].ID
+	})
+
+	return orgs, nil
+}
+
+func (r *OrganizationRepository) Update(org *model.Organization) error {
+	if err := org.Validate(); err != nil {
+		return err
+	}
+
+	stored, ok := r.orgs[org.ID]
+	if !ok {
+		return store.ErrInvalidOrganizationId
+	}
+
+	stored.Name = org.Name
+	return nil
+}
+
+func (r *OrganizationRepository) Delete(userId int, orgId int) error {
+	if _, ok := r.orgs[orgId]; !ok {
+		return store.ErrInvalidOrganizationId
+	}
+
+	projects := r.projectIds(orgId)
+	delete(r.orgs, orgId)
+	delete(r.members, orgId)
+	for id, i := range r.invitations {
+		if i.OrganizationID == orgId {
+			delete(r.invitations, id)
+		}
+	}
+
+	r.store.projects().leaveOrganization(orgId)
+	r.store.tasks().unassignProjects(userId, projects)
+
+	return nil
+}
+
+func (r *OrganizationRepository) GetMembers(orgId int) ([]*model.Member, error) {
+	members := []*model.Member{}
+	for _, m := range r.members[orgId] {
+		found := *m
+		if user, err := r.store.User().FindById(m.UserID); err == nil {
+			found.Email = user.Email
+		}
+
+		members = append(members, &found)
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		if !members[i].CreatedAt.Equal(members[j].CreatedAt) {
+			return members[i].CreatedAt.Before(members[j].CreatedAt)
+		}
+		return members[i].UserID < members[j].UserID
+	})
+
+	return members, nil
+}
+
+func (r *OrganizationRepository) SetRole(m *model.Member) error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	stored, err := r.member(m.OrganizationID, m.UserID)
+	if err != nil {
+		return err
+	}
+
+	stored.Role = m.Role
+	m.CreatedAt = stored.CreatedAt
+	return nil
+}
+
+func (r *OrganizationRepository) RemoveMember(userId int, orgId int, memberId int) error {
+	if _, err := r.member(orgId, memberId); err != nil {
+		return err
+	}
+
+	delete(r.members[orgId], memberId)
+	r.store.tasks().unassignProjects(userId, r.projectIds(orgId))
+
+	return nil
+}
+
+func (r *OrganizationRepository) Invite(i *model.Invitation) error {
+	if err := i.Validate(); err != nil {
+		return err
+	}
+
+	for _, m := range r.members[i.OrganizationID] {
+		if user, err := r.store.User().FindById(m.UserID); err == nil && strings.EqualFold(user.Email, i.Email) {
+			return store.ErrAlreadyMember
+		}
+	}
+
+	if err := i.BeforeCreate(time.Now()); err != nil {
+		return err
+	}
+
+	// A new invitation of the same email replaces the previous ones
+	for id, stored := range r.invitations {
+		if stored.OrganizationID == i.OrganizationID && strings.EqualFold(stored.Email, i.Email) {
+			delete(r.invitations, id)
+		}
+	}
+
+	r.lastInvitationId++
+	i.ID = r.lastInvitationId
+	i.CreatedAt = time.Now()
+	stored := *i
+	stored.Token = ""
+	r.invitations[i.ID] = &stored
+
+	return nil
+}
+
+func (r *OrganizationRepository) GetInvitations(orgId int) ([]*model.Invitation, error) {
+	invitations := []*model.Invitation{}
+	for _, i := range r.invitations {
+		if i.OrganizationID == orgId && i.ExpiresAt.After(time.Now()) {
+			found := *i
+			invitations = append(invitations, &found)
+		}
+	}
+
+	sort.Slice(invitations, func(i, j int) bool {
+		return invitations[i].ID < invitations[j].ID
+	})
+
+	return invitations, nil
+}
+
+func (r *OrganizationRepository) DeleteInvitation(orgId int, invitationId int) error {
+	i, ok := r.invitations[invitationId]
+	if !ok || i.OrganizationID != orgId {
+		return store.ErrInvalidInvitation
+	}
+
+	delete(r.invitations, invitationId)
+	return nil
+}
+
+func (r *OrganizationRepository) Accept(user *model.User, token string) (*model.Organization, error) {
+	hash := model.HashToken(token)
+	for id, i := range r.invitations {
+		if i.TokenHash != hash {
+			continue
+		}
+
+		if !i.ExpiresAt.After(time.Now()) || !strings.EqualFold(i.Email, user.Email) {
+			return nil, store.ErrInvalidInvitation
+		}
+
+		if !user.Verified {
+			return nil, store.ErrUnverifiedInvitee
+		}
+
+		if _, ok := r.members[i.OrganizationID][user.ID]; ok {
+			return nil, store.ErrAlreadyMember
+		}
+
+		delete(r.invitations, id)
+		r.members[i.OrganizationID][user.ID] = &model.Member{
+			OrganizationID: i.OrganizationID,
+			UserID:         user.ID,
+			Role:           i.Role,
+			CreatedAt:      time.Now(),
+		}
+
+		return r.GetById(user.ID, i.OrganizationID)
+	}
+
+	return nil, store.ErrInvalidInvitation
+}
+
+// member returns a member of the organization other than its owner
+func (r *OrganizationRepository) member(orgId int, userId int) (*model.Member, error) {
+	m, ok := r.members[orgId][userId]
+	if !ok {
+		return nil, store.ErrInvalidMember
+	}
+
+	if m.Role == model.OrgRoleOwner {
+		return nil, store.ErrOrganizationOwner
+	}
+
+	return m, nil
+}
+
+// projectIds returns the ids of the projects of the organization
+func (r *OrganizationRepository) projectIds(orgId int) map[int]bool {
+	ids := map[int]bool{}
+	for _, p := range r.store.projects().projects {
+		if sameId(p.OrganizationID, &orgId) {
+			ids[p.ID] = true
+		}
+	}
+
+	return ids
+}
@@ -0,0 +1,166 @@
+package teststore_test
+
+import (
+	"testing"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/pyuldashev912/todoapp/internal/app/store"
+	"github.com/pyuldashev912/todoapp/internal/app/store/teststore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrganizationRepository_Invite(t *testing.T) {
+	s := teststore.New()
+	owner := model.TestUser(t)
+	s.User().Create(owner)
+	member := model.TestUser(t)
+	member.Email = "member@user.com"
+	s.User().Create(member)
+
+	org := model.TestOrganization(t)
+	assert.NoError(t, s.Organization().Create(org, owner.ID))
+	assert.Equal(t, model.OrgRoleOwner, org.Role)
+
+	i := model.TestInvitation(t)
+	i.OrganizationID, i.InvitedBy = org.ID, owner.ID
+	assert.NoError(t, s.Organization().Invite(i))
+	assert.NotEmpty(t, i.Token)
+
+	invitations, err := s.Organization().GetInvitations(org.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, invitations, 1) {
+		assert.Empty(t, invitations[0].Token)
+	}
+
+	// The invitation is for another email
+	_, err = s.Organization().Accept(owner, i.Token)
+	assert.EqualError(t, err, store.ErrInvalidInvitation.Error())
+
+	// The email has to be verified
+	_, err = s.Organization().Accept(member, i.Token)
+	assert.EqualError(t, err, store.ErrUnverifiedInvitee.Error())
+	member.Verified = true
+
+	joined, err := s.Organization().Accept(member, i.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, model.OrgRoleMember, joined.Role)
+
+	// The token can only be used once
+	_, err = s.Organization().Accept(member, i.Token)
+	assert.EqualError(t, err, store.ErrInvalidInvitation.Error())
+	assert.EqualError(t, s.Organization().Invite(i), store.ErrAlreadyMember.Error())
+
+	members, err := s.Organization().GetMembers(org.ID)
+	assert.NoError(t, err)
+	assert.Len(t, members, 2)
+
+	orgs, err := s.Organization().GetAll(member.ID)
+	assert.NoError(t, err)
+	assert.Len(t, orgs, 1)
+}
+
+func TestOrganizationRepository_Members(t *testing.T) {
+	s := teststore.New()
+	org := model.TestOrganization(t)
+	s.Organization().Create(org, 1)
+
+	assert.EqualError(t, s.Organization().SetRole(&model.Member{
+		OrganizationID: org.ID, UserID: 1, Role: model.OrgRoleAdmin,
+	}), store.ErrOrganizationOwner.Error())
+	assert.EqualError(t, s.Organization().SetRole(&model.Member{
+		OrganizationID: org.ID, UserID: 2, Role: model.OrgRoleAdmin,
+	}), store.ErrInvalidMember.Error())
+	assert.EqualError(t, s.Organization().RemoveMember(1, org.ID, 1), store.ErrOrganizationOwner.Error())
+
+	_, err := s.Organization().GetById(2, org.ID)
+	assert.EqualError(t, err, store.ErrInvalidOrganizationId.Error())
+}
+
+func TestOrganizationRepository_MemberTasks(t *testing.T) {
+	s := teststore.New()
+	owner := model.TestUser(t)
+	s.User().Create(owner)
+	org := model.TestOrganization(t)
+	s.Organization().Create(org, owner.ID)
+
+	var members []*model.User
+	for _, email := range []string{"member@user.com", "other@user.com", "third@user.com"} {
+		u := model.TestUser(t)
+		u.Email, u.Verified = email, true
+		s.User().Create(u)
+		i := model.TestInvitation(t)
+		i.OrganizationID, i.Email = org.ID, email
+		s.Organization().Invite(i)
+		_, err := s.Organization().Accept(u, i.Token)
+		assert.NoError(t, err)
+		members = append(members, u)
+	}
+
+	project := model.TestProject(t)
+	project.UserID, project.OrganizationID = owner.ID, &org.ID
+	s.Project().Create(project)
+
+	// A member other than the creator of the project owns the tasks
+	var ids []int
+	for _, assignee := range members[1:] {
+		task := model.TestTask(t)
+		task.UserID, task.ProjectID, task.AssigneeID = members[0].ID, &project.ID, &assignee.ID
+		assert.NoError(t, s.Task().Create(task))
+		ids = append(ids, task.ID)
+	}
+
+	assert.NoError(t, s.Organization().RemoveMember(members[1].ID, org.ID, members[1].ID))
+	task, err := s.Task().GetById(members[0].ID, ids[0])
+	assert.NoError(t, err)
+	assert.Nil(t, task.AssigneeID)
+	task, _ = s.Task().GetById(members[0].ID, ids[1])
+	assert.Equal(t, members[2].ID, *task.AssigneeID)
+
+	assert.NoError(t, s.Organization().Delete(owner.ID, org.ID))
+	task, err = s.Task().GetById(members[0].ID, ids[1])
+	assert.NoError(t, err)
+	assert.Nil(t, task.AssigneeID)
+}
+
+func TestOrganizationRepository_Projects(t *testing.T) {
+	s := teststore.New()
+	owner := model.TestUser(t)
+	s.User().Create(owner)
+	member := model.TestUser(t)
+	member.Email, member.Verified = "member@user.com", true
+	s.User().Create(member)
+
+	org := model.TestOrganization(t)
+	s.Organization().Create(org, owner.ID)
+	i := model.TestInvitation(t)
+	i.OrganizationID = org.ID
+	s.Organization().Invite(i)
+	s.Organization().Accept(member, i.Token)
+
+	project := model.TestProject(t)
+	project.UserID, project.OrganizationID = owner.ID, &org.ID
+	assert.NoError(t, s.Project().Create(project))
+	projects, err := s.Project().GetByOrganization(org.ID)
+	assert.NoError(t, err)
+	assert.Len(t, projects, 1)
+
+	// The members of the organization work on the tasks of its projects
+	task := model.TestTask(t)
+	task.UserID, task.ProjectID, task.AssigneeID = owner.ID, &project.ID, &member.ID
+	assert.NoError(t, s.Task().Create(task))
+	task.Title = "Edited by a member"
+	assert.NoError(t, s.Task().Update(member.ID, task))
+
+	// Leaving the organization takes them off its tasks
+	assert.NoError(t, s.Organization().RemoveMember(member.ID, org.ID, member.ID))
+	_, err = s.Task().GetById(member.ID, task.ID)
+	assert.EqualError(t, err, store.ErrInvalidTaskId.Error())
+	task, _ = s.Task().GetById(owner.ID, task.ID)
+	assert.Nil(t, task.AssigneeID)
+
+	// The projects stay with the users who created them
+	assert.NoError(t, s.Organization().Delete(owner.ID, org.ID))
+	project, err = s.Project().GetById(owner.ID, project.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, project.OrganizationID)
+}
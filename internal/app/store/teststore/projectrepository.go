@@ -0,0 +1,158 @@
+package teststore
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/pyuldashev912/todoapp/internal/app/store"
+)
+
+type ProjectRepository struct {
+	store    *Store
+	projects map[int]*model.Project
+	lastId   int
+}
+
+func (r *ProjectRepository) Create(project *model.Project) error {
+	if err := project.Validate(); err != nil {
+		return err
+	}
+
+	r.lastId++
+	project.ID = r.lastId
+	project.CreatedAt = time.Now()
+	r.projects[project.ID] = project
+
+	return nil
+}
+
+func (r *ProjectRepository) GetById(userId int, projectId int) (*model.Project, error) {
+	p, ok := r.projects[projectId]
+	if !ok || p.UserID != userId {
+		return nil, store.ErrInvalidProjectId
+	}
+
+	return p, nil
+}
+
+func (r *ProjectRepository) GetAll(userId int) ([]*model.Project, error) {
+	var projects []*model.Project
+	for _, p := range r.projects {
+		if p.UserID == userId {
+			projects = append(projects, p)
+		}
+	}
+
+	if len(projects) == 0 {
+		return nil, store.ErrNoRecordsInTable
+	}
+
+	sort.Slice(projects, func(i, j int) bool {
+		return projects[i].ID < projects[j].ID
+	})
+
+	return projects, nil
+}
+
+func (r *ProjectRepository) Update(project *model.Project) error {
+	if err := project.Validate(); err != nil {
+		return err
+	}
+
+	p, err := r.GetById(project.UserID, project.ID)
+	if err != nil {
+		return err
+	}
+
+	p.Name = project.Name
+	p.Description = project.Description
+	return nil
+}
+
+func (r *ProjectRepository) Delete(userId int, projectId int, target *int) error {
+	if _, err := r.GetById(userId, projectId); err != nil {
+		return err
+	}
+
+	if target != nil {
+		if *target == projectId {
+			return store.ErrInvalidProjectId
+		}
+
+		if _, err := r.GetById(userId, *target); err != nil {
+			return err
+		}
+	}
+
+	for _, task := range r.projectTasks(userId, projectId) {
+		if target == nil {
+			task.ProjectID = nil
+		} else {
+			moveTo := *target
+			task.ProjectID = &moveTo
+		}
+	}
+
+	delete(r.projects, projectId)
+	r.store.shares().deleteProject(projectId)
+	// The members of the project leave it and the moved tasks follow another workflow now
+	r.store.tasks().unassign(userId, userId)
+	r.store.workflows().remap(userId)
+	return nil
+}
+
+func (r *ProjectRepository) DeleteWithTasks(userId int, projectId int) error {
+	if _, err := r.GetById(userId, projectId); err != nil {
+		return err
+	}
+
+	// Like the database, keeps the trashed tasks without a project
+	for _, task := range r.projectTasks(userId, projectId) {
+		r.store.Task().Delete(userId, task.ID)
+		task.ProjectID = nil
+	}
+
+	delete(r.projects, projectId)
+	r.store.shares().deleteProject(projectId)
+	r.store.tasks().unassign(userId, userId)
+	r.store.workflows().remap(userId)
+	return nil
+}
+
+func (r *ProjectRepository) GetByOrganization(orgId int) ([]*model.Project, error) {
+	projects := []*model.Project{}
+	for _, p := range r.projects {
+		if sameId(p.OrganizationID, &orgId) {
+			projects = append(projects, p)
+		}
+	}
+
+	sort.Slice(projects, func(i, j int) bool {
+		return projects[i].ID < projects[j].ID
+	})
+
+	return projects, nil
+}
+
+// leaveOrganization keeps the projects of a deleted organization with the
+// users who created them the same way the database does
+func (r *ProjectRepository) leaveOrganization(orgId int) {
+	for _, p := range r.projects {
+		if sameId(p.OrganizationID, &orgId) {
+			p.OrganizationID = nil
+		}
+	}
+}
+
+// projectTasks returns all tasks of the project including the ones in the trash
+func (r *ProjectRepository) projectTasks(userId int, projectId int) []*model.Task {
+	var tasks []*model.Task
+	for _, task := range r.store.tasks().tasks {
+		if task.UserID == userId && task.ProjectID != nil && *task.ProjectID == projectId {
+			tasks = append(tasks, task)
+		}
+	}
+
+	return tasks
+}
@@ -0,0 +1,187 @@
+package teststore
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/pyuldashev912/todoapp/internal/app/store"
+)
+
+type ShareRepository struct {
+	store  *Store
+	shares map[int]*model.Share
+	lastId int
+}
+
+func (r *ShareRepository) Create(s *model.Share) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+
+	if s.UserID == s.OwnerID {
+		return store.ErrShareWithOwner
+	}
+
+	if err := r.checkTarget(s.OwnerID, s.TaskID, s.ProjectID); err != nil {
+		return err
+	}
+
+	// Sharing the same item with the same user again changes the role
+	for _, stored := range r.shares {
+		if stored.UserID == s.UserID && sameId(stored.TaskID, s.TaskID) && sameId(stored.ProjectID, s.ProjectID) {
+			stored.Role = s.Role
+			s.ID, s.CreatedAt = stored.ID, stored.CreatedAt
+			return nil
+		}
+	}
+
+	r.lastId++
+	s.ID = r.lastId
+	s.CreatedAt = time.Now()
+	stored := *s
+	r.shares[s.ID] = &stored
+
+	return nil
+}
+
+func (r *ShareRepository) GetByTask(ownerId int, taskId int) ([]*model.Share, error) {
+	if err := r.checkTarget(ownerId, &taskId, nil); err != nil {
+		return nil, err
+	}
+
+	return r.filter(func(s *model.Share) bool {
+		return sameId(s.TaskID, &taskId)
+	}), nil
+}
+
+func (r *ShareRepository) GetByProject(ownerId int, projectId int) ([]*model.Share, error) {
+	if err := r.checkTarget(ownerId, nil, &projectId); err != nil {
+		return nil, err
+	}
+
+	return r.filter(func(s *model.Share) bool {
+		return sameId(s.ProjectID, &projectId)
+	}), nil
+}
+
+func (r *ShareRepository) SharedWith(userId int) ([]*model.Share, error) {
+	shares := r.filter(func(s *model.Share) bool {
+		return s.UserID == userId && (s.TaskID == nil || r.store.tasks().tasks[*s.TaskID].DeletedAt == nil)
+	})
+
+	for _, s := range shares {
+		if s.TaskID != nil {
+			s.Title = r.store.tasks().tasks[*s.TaskID].Title
+		} else {
+			s.Title = r.store.projects().projects[*s.ProjectID].Name
+		}
+
+		if owner, err := r.store.User().FindById(s.OwnerID); err == nil {
+			s.SharedBy = owner.Email
+		}
+	}
+
+	return shares, nil
+}
+
+func (r *ShareRepository) Delete(userId int, shareId int) error {
+	s, ok := r.shares[shareId]
+	if !ok || s.OwnerID != userId && s.UserID != userId {
+		return store.ErrInvalidShareId
+	}
+
+	delete(r.shares, shareId)
+	if s.ProjectID != nil {
+		r.store.tasks().unassign(userId, s.OwnerID)
+	}
+
+	return nil
+}
+
+// checkTarget makes sure the shared task or project belongs to the owner
+func (r *ShareRepository) checkTarget(ownerId int, taskId *int, projectId *int) error {
+	if projectId != nil {
+		_, err := r.store.Project().GetById(ownerId, *projectId)
+		return err
+	}
+
+	_, err := getKeyFromMap(r.store.tasks().tasks, ownerId, *taskId)
+	return err
+}
+
+func (r *ShareRepository) filter(keep func(*model.Share) bool) []*model.Share {
+	shares := []*model.Share{}
+	for _, s := range r.shares {
+		if keep(s) {
+			found := *s
+			if user, err := r.store.User().FindById(s.UserID); err == nil {
+				found.Email = user.Email
+			}
+
+			shares = append(shares, &found)
+		}
+	}
+
+	sort.Slice(shares, func(i, j int) bool {
+		return shares[i].ID < shares[j].ID
+	})
+
+	return shares
+}
+
+// role returns the role the user has on the task through its shares, editor
+// wins over viewer and an empty role means the task isn't shared with them
+func (r *ShareRepository) role(userId int, task *model.Task) string {
+	role := ""
+	for _, s := range r.shares {
+		if s.UserID != userId {
+			continue
+		}
+
+		if sameId(s.TaskID, &task.ID) || s.ProjectID != nil && sameId(s.ProjectID, task.ProjectID) {
+			if role == "" || s.CanEdit() {
+				role = s.Role
+			}
+		}
+	}
+
+	return role
+}
+
+// member tells whether the project is shared with the user
+func (r *ShareRepository) member(userId int, projectId int) bool {
+	for _, s := range r.shares {
+		if s.UserID == userId && sameId(s.ProjectID, &projectId) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// deleteTask drops the shares of a purged task the same way the database cascades
+func (r *ShareRepository) deleteTask(taskId int) {
+	for id, s := range r.shares {
+		if sameId(s.TaskID, &taskId) {
+			delete(r.shares, id)
+		}
+	}
+}
+
+// deleteProject drops the shares of a deleted project the same way the database cascades
+func (r *ShareRepository) deleteProject(projectId int) {
+	for id, s := range r.shares {
+		if sameId(s.ProjectID, &projectId) {
+			delete(r.shares, id)
+		}
+	}
+}
+
+func sameId(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return *a == *b
+}
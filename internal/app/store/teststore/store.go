@@ -6,8 +6,16 @@ import (
 )
 
 type Store struct {
-	userRepository *UserRepository
-	taskRepository *TaskRepository
+	userRepository         *UserRepository
+	taskRepository         *TaskRepository
+	tagRepository          *TagRepository
+	projectRepository      *ProjectRepository
+	workflowRepository     *WorkflowRepository
+	timeEntryRepository    *TimeEntryRepository
+	commentRepository      *CommentRepository
+	attachmentRepository   *AttachmentRepository
+	shareRepository        *ShareRepository
+	organizationRepository *OrganizationRepository
 }
 
 func New() *Store {
@@ -20,20 +28,170 @@ func (s *Store) User() store.UserRepository {
 	}
 
 	s.userRepository = &UserRepository{
-		users: make(map[int]*model.User),
+		users:         make(map[int]*model.User),
+		resets:        make(map[string]*model.PasswordReset),
+		verifications: make(map[string]*model.EmailVerification),
 	}
 
 	return s.userRepository
 }
 
 func (s *Store) Task() store.TaskRepository {
+	return s.tasks()
+}
+
+// tasks returns the task repository the other repositories look into
+func (s *Store) tasks() *TaskRepository {
 	if s.taskRepository != nil {
 		return s.taskRepository
 	}
 
 	s.taskRepository = &TaskRepository{
-		tasks: make(map[int]*model.Task),
+		store:    s,
+		tasks:    make(map[int]*model.Task),
+		blockers: make(map[int][]int),
 	}
 
 	return s.taskRepository
 }
+
+func (s *Store) Tag() store.TagRepository {
+	if s.tagRepository != nil {
+		return s.tagRepository
+	}
+
+	s.tagRepository = &TagRepository{
+		store: s,
+		tags:  make(map[int]*model.Tag),
+	}
+
+	return s.tagRepository
+}
+
+func (s *Store) Project() store.ProjectRepository {
+	return s.projects()
+}
+
+// projects returns the project repository the other repositories look into
+func (s *Store) projects() *ProjectRepository {
+	if s.projectRepository != nil {
+		return s.projectRepository
+	}
+
+	s.projectRepository = &ProjectRepository{
+		store:    s,
+		projects: make(map[int]*model.Project),
+	}
+
+	return s.projectRepository
+}
+
+func (s *Store) Workflow() store.WorkflowRepository {
+	return s.workflows()
+}
+
+// workflows returns the workflow repository the other repositories look into
+func (s *Store) workflows() *WorkflowRepository {
+	if s.workflowRepository != nil {
+		return s.workflowRepository
+	}
+
+	s.workflowRepository = &WorkflowRepository{
+		store:     s,
+		workflows: make(map[int]*model.Workflow),
+	}
+
+	return s.workflowRepository
+}
+
+func (s *Store) TimeEntry() store.TimeEntryRepository {
+	return s.timeEntries()
+}
+
+// timeEntries returns the time entry repository the other repositories look into
+func (s *Store) timeEntries() *TimeEntryRepository {
+	if s.timeEntryRepository != nil {
+		return s.timeEntryRepository
+	}
+
+	s.timeEntryRepository = &TimeEntryRepository{
+		store:   s,
+		entries: make(map[int]*model.TimeEntry),
+	}
+
+	return s.timeEntryRepository
+}
+
+func (s *Store) Comment() store.CommentRepository {
+	return s.comments()
+}
+
+// comments returns the comment repository the other repositories look into
+func (s *Store) comments() *CommentRepository {
+	if s.commentRepository != nil {
+		return s.commentRepository
+	}
+
+	s.commentRepository = &CommentRepository{
+		store:    s,
+		comments: make(map[int]*model.Comment),
+	}
+
+	return s.commentRepository
+}
+
+func (s *Store) Attachment() store.AttachmentRepository {
+	return s.attachments()
+}
+
+// attachments returns the attachment repository the other repositories look into
+func (s *Store) attachments() *AttachmentRepository {
+	if s.attachmentRepository != nil {
+		return s.attachmentRepository
+	}
+
+	s.attachmentRepository = &AttachmentRepository{
+		store:       s,
+		attachments: make(map[int]*model.Attachment),
+	}
+
+	return s.attachmentRepository
+}
+
+func (s *Store) Share() store.ShareRepository {
+	return s.shares()
+}
+
+// shares returns the share repository the other repositories look into
+func (s *Store) shares() *ShareRepository {
+	if s.shareRepository != nil {
+		return s.shareRepository
+	}
+
+	s.shareRepository = &ShareRepository{
+		store:  s,
+		shares: make(map[int]*model.Share),
+	}
+
+	return s.shareRepository
+}
+
+func (s *Store) Organization() store.OrganizationRepository {
+	return s.organizations()
+}
+
+// organizations returns the organization repository the other repositories look into
+func (s *Store) organizations() *OrganizationRepository {
+	if s.organizationRepository != nil {
+		return s.organizationRepository
+	}
+
+	s.organizationRepository = &OrganizationRepository{
+		store:       s,
+		orgs:        make(map[int]*model.Organization),
+		members:     make(map[int]map[int]*model.Member),
+		invitations: make(map[int]*model.Invitation),
+	}
+
+	return s.organizationRepository
+}
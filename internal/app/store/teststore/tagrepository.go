@@ -0,0 +1,181 @@
+package teststore
+
+import (
+	"sort"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/pyuldashev912/todoapp/internal/app/store"
+)
+
+// TagRepository keeps the Tags field of the stored tasks up to date
+// instead of a separate task-tag relation.
+type TagRepository struct {
+	store  *Store
+	tags   map[int]*model.Tag
+	lastId int
+}
+
+func (r *TagRepository) Create(tag *model.Tag) error {
+	tag.Normalize()
+	if err := tag.Validate(); err != nil {
+		return err
+	}
+
+	if r.findByName(tag.UserID, tag.Name) != nil {
+		return store.ErrTagExists
+	}
+
+	r.lastId++
+	tag.ID = r.lastId
+	r.tags[tag.ID] = tag
+
+	return nil
+}
+
+func (r *TagRepository) Rename(userId int, tagId int, name string) (*model.Tag, error) {
+	tag, err := r.get(userId, tagId)
+	if err != nil {
+		return nil, err
+	}
+
+	renamed := &model.Tag{ID: tagId, UserID: userId, Name: name}
+	renamed.Normalize()
+	if err := renamed.Validate(); err != nil {
+		return nil, err
+	}
+
+	if other := r.findByName(userId, renamed.Name); other != nil && other.ID != tagId {
+		return nil, store.ErrTagExists
+	}
+
+	for _, task := range r.userTasks(userId) {
+		if removeTag(task, tag.Name) {
+			addTag(task, renamed.Name)
+		}
+	}
+
+	tag.Name = renamed.Name
+	return tag, nil
+}
+
+func (r *TagRepository) Delete(userId int, tagId int) error {
+	tag, err := r.get(userId, tagId)
+	if err != nil {
+		return err
+	}
+
+	for _, task := range r.userTasks(userId) {
+		removeTag(task, tag.Name)
+	}
+
+	delete(r.tags, tagId)
+	return nil
+}
+
+func (r *TagRepository) GetAll(userId int) ([]*model.Tag, error) {
+	var tags []*model.Tag
+	for _, tag := range r.tags {
+		if tag.UserID == userId {
+			tags = append(tags, tag)
+		}
+	}
+
+	if len(tags) == 0 {
+		return nil, store.ErrNoRecordsInTable
+	}
+
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+
+	return tags, nil
+}
+
+func (r *TagRepository) Attach(userId int, taskId int, tagId int) error {
+	task, tag, err := r.taskAndTag(userId, taskId, tagId)
+	if err != nil {
+		return err
+	}
+
+	addTag(task, tag.Name)
+	return nil
+}
+
+func (r *TagRepository) Detach(userId int, taskId int, tagId int) error {
+	task, tag, err := r.taskAndTag(userId, taskId, tagId)
+	if err != nil {
+		return err
+	}
+
+	removeTag(task, tag.Name)
+	return nil
+}
+
+func (r *TagRepository) get(userId int, tagId int) (*model.Tag, error) {
+	tag, ok := r.tags[tagId]
+	if !ok || tag.UserID != userId {
+		return nil, store.ErrInvalidTagId
+	}
+
+	return tag, nil
+}
+
+func (r *TagRepository) findByName(userId int, name string) *model.Tag {
+	for _, tag := range r.tags {
+		if tag.UserID == userId && tag.Name == name {
+			return tag
+		}
+	}
+
+	return nil
+}
+
+func (r *TagRepository) taskAndTag(userId int, taskId int, tagId int) (*model.Task, *model.Tag, error) {
+	task, err := r.store.tasks().owned(userId, taskId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tag, err := r.get(userId, tagId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return task, tag, nil
+}
+
+// userTasks returns all tasks of the user including the ones in the trash
+func (r *TagRepository) userTasks(userId int) []*model.Task {
+	var tasks []*model.Task
+	for _, task := range r.store.tasks().tasks {
+		if task.UserID == userId {
+			tasks = append(tasks, task)
+		}
+	}
+
+	return tasks
+}
+
+// addTag adds the name to the task tags keeping them sorted
+func addTag(task *model.Task, name string) {
+	for _, t := range task.Tags {
+		if t == name {
+			return
+		}
+	}
+
+	task.Tags = append(task.Tags, name)
+	sort.Strings(task.Tags)
+}
+
+// removeTag removes the name from the task tags and reports whether it was there
+func removeTag(task *model.Task, name string) bool {
+	for i, t := range task.Tags {
+		if t == name {
+			task.Tags = append(task.Tags[:i:i], task.Tags[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,90 @@
+package teststore_test
+
+import (
+	"testing"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/pyuldashev912/todoapp/internal/app/store"
+	"github.com/pyuldashev912/todoapp/internal/app/store/teststore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagRepository_Create(t *testing.T) {
+	s := teststore.New()
+	tag := model.TestTag(t)
+	assert.NoError(t, s.Tag().Create(tag))
+	assert.EqualError(t, s.Tag().Create(&model.Tag{UserID: 1, Name: "Urgent"}), store.ErrTagExists.Error())
+	assert.NoError(t, s.Tag().Create(&model.Tag{UserID: 2, Name: "urgent"}))
+	assert.Error(t, s.Tag().Create(&model.Tag{UserID: 1}))
+}
+
+func TestTagRepository_Rename(t *testing.T) {
+	s := teststore.New()
+	task := model.TestTask(t)
+	s.Task().Create(task)
+	tag := model.TestTag(t)
+	s.Tag().Create(tag)
+	s.Tag().Create(&model.Tag{UserID: 1, Name: "home"})
+	s.Tag().Attach(task.UserID, task.ID, tag.ID)
+
+	res, err := s.Tag().Rename(tag.UserID, tag.ID, "Critical")
+	assert.NoError(t, err)
+	assert.Equal(t, "critical", res.Name)
+
+	res2, _ := s.Task().GetById(task.UserID, task.ID)
+	assert.Equal(t, []string{"critical"}, res2.Tags)
+
+	_, err = s.Tag().Rename(tag.UserID, tag.ID, "home")
+	assert.EqualError(t, err, store.ErrTagExists.Error())
+
+	_, err = s.Tag().Rename(2, tag.ID, "other")
+	assert.EqualError(t, err, store.ErrInvalidTagId.Error())
+}
+
+func TestTagRepository_Delete(t *testing.T) {
+	s := teststore.New()
+	task := model.TestTask(t)
+	s.Task().Create(task)
+	tag := model.TestTag(t)
+	s.Tag().Create(tag)
+	s.Tag().Attach(task.UserID, task.ID, tag.ID)
+
+	assert.NoError(t, s.Tag().Delete(tag.UserID, tag.ID))
+	res, _ := s.Task().GetById(task.UserID, task.ID)
+	assert.Empty(t, res.Tags)
+
+	assert.EqualError(t, s.Tag().Delete(tag.UserID, tag.ID), store.ErrInvalidTagId.Error())
+}
+
+func TestTagRepository_GetAll(t *testing.T) {
+	s := teststore.New()
+	_, err := s.Tag().GetAll(1)
+	assert.EqualError(t, err, store.ErrNoRecordsInTable.Error())
+
+	s.Tag().Create(model.TestTag(t))
+	s.Tag().Create(&model.Tag{UserID: 1, Name: "bug"})
+	tags, err := s.Tag().GetAll(1)
+	assert.NoError(t, err)
+	assert.Len(t, tags, 2)
+	assert.Equal(t, "bug", tags[0].Name)
+}
+
+func TestTagRepository_AttachDetach(t *testing.T) {
+	s := teststore.New()
+	task := model.TestTask(t)
+	s.Task().Create(task)
+	tag := model.TestTag(t)
+	s.Tag().Create(tag)
+
+	assert.NoError(t, s.Tag().Attach(task.UserID, task.ID, tag.ID))
+	assert.NoError(t, s.Tag().Attach(task.UserID, task.ID, tag.ID))
+	res, _ := s.Task().GetById(task.UserID, task.ID)
+	assert.Equal(t, []string{"urgent"}, res.Tags)
+
+	assert.EqualError(t, s.Tag().Attach(task.UserID, 5, tag.ID), store.ErrInvalidTaskId.Error())
+	assert.EqualError(t, s.Tag().Attach(task.UserID, task.ID, 5), store.ErrInvalidTagId.Error())
+
+	assert.NoError(t, s.Tag().Detach(task.UserID, task.ID, tag.ID))
+	res, _ = s.Task().GetById(task.UserID, task.ID)
+	assert.Empty(t, res.Tags)
+}
@@ -0,0 +1,105 @@
+package teststore
+
+import (
+	"strings"
+	"time"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/pyuldashev912/todoapp/internal/app/store/filter"
+)
+
+// matchesFilter evaluates a filter expression the same way sqlstore translates it
+func matchesFilter(task *model.Task, e filter.Expr) bool {
+	switch e := e.(type) {
+	case *filter.And:
+		return matchesFilter(task, e.Left) && matchesFilter(task, e.Right)
+	case *filter.Or:
+		return matchesFilter(task, e.Left) || matchesFilter(task, e.Right)
+	case *filter.Not:
+		return !matchesFilter(task, e.Expr)
+	case *filter.Cmp:
+		if e.Op == filter.OpNe {
+			return !matchesCmp(task, &filter.Cmp{Field: e.Field, Op: filter.OpEq, Value: e.Value})
+		}
+
+		return matchesCmp(task, e)
+	}
+
+	return false
+}
+
+func matchesCmp(task *model.Task, c *filter.Cmp) bool {
+	switch c.Field {
+	case filter.FieldTitle, filter.FieldDescription:
+		text := task.Title
+		if c.Field == filter.FieldDescription {
+			text = task.Description
+		}
+
+		if c.Op == filter.OpContains {
+			return strings.Contains(strings.ToLower(text), strings.ToLower(c.Value.(string)))
+		}
+
+		return text == c.Value.(string)
+	case filter.FieldDone:
+		return task.Done == c.Value.(bool)
+	case filter.FieldStatus:
+		return task.Status == c.Value.(string)
+	case filter.FieldPriority:
+		return compare(c.Op, task.Priority.Level()-c.Value.(model.Priority).Level())
+	case filter.FieldDue:
+		if c.Value == nil || task.DueAt == nil {
+			return c.Value == nil && task.DueAt == nil
+		}
+
+		return compare(c.Op, compareTime(*task.DueAt, c.Value.(time.Time)))
+	case filter.FieldCreated:
+		return compare(c.Op, compareTime(task.CreatedAt, c.Value.(time.Time)))
+	case filter.FieldTag:
+		for _, tag := range task.Tags {
+			if tag == c.Value {
+				return true
+			}
+		}
+
+		return false
+	case filter.FieldProject:
+		if c.Value == nil || task.ProjectID == nil {
+			return c.Value == nil && task.ProjectID == nil
+		}
+
+		return *task.ProjectID == c.Value.(int)
+	}
+
+	return false
+}
+
+// compare tells whether the result of comparing a task value with the
+// filter value, negative, zero or positive, satisfies the operator
+func compare(op string, c int) bool {
+	switch op {
+	case filter.OpEq:
+		return c == 0
+	case filter.OpGt:
+		return c > 0
+	case filter.OpGe:
+		return c >= 0
+	case filter.OpLt:
+		return c < 0
+	case filter.OpLe:
+		return c <= 0
+	}
+
+	return false
+}
+
+func compareTime(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+
+	return 0
+}
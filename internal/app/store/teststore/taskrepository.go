@@ -1,58 +1,1055 @@
 package teststore
 
 import (
+	"fmt"
+	"html"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
 	"github.com/pyuldashev912/todoapp/internal/app/model"
 	"github.com/pyuldashev912/todoapp/internal/app/store"
 )
 
-type TaskRepository struct {
-	tasks map[int]*model.Task
+type TaskRepository struct {
+	store       *Store
+	tasks       map[int]*model.Task
+	lastId      int
+	events      []*model.TaskEvent
+	lastEventId int
+	// blockers holds the ids of the tasks blocking each task
+	blockers map[int][]int
+}
+
+func (r *TaskRepository) Create(task *model.Task) error {
+	if err := task.Validate(); err != nil {
+		return err
+	}
+
+	if err := r.checkProject(task); err != nil {
+		return err
+	}
+
+	if err := r.checkParent(task); err != nil {
+		return err
+	}
+
+	if err := r.checkAssignee(task); err != nil {
+		return err
+	}
+
+	if err := r.workflow(task).Apply(nil, task); err != nil {
+		return err
+	}
+
+	// Tasks read back from the database always have a priority
+	task.Priority = model.PriorityOfLevel(task.Priority.Level())
+	task.Rank = model.RankAfter(r.lastRank(task.UserID))
+	r.lastId++
+	task.ID = r.lastId
+	if task.CreatedAt.IsZero() {
+		task.CreatedAt = time.Now()
+	}
+
+	r.tasks[task.ID] = task
+	r.record(task.UserID, task.ID, model.ActionCreate, model.DiffTasks(nil, task))
+
+	return nil
+}
+
+// workflow returns the workflow the task follows
+func (r *TaskRepository) workflow(task *model.Task) *model.Workflow {
+	return r.store.workflows().of(task.UserID, task.ProjectID)
+}
+
+// lastRank returns the highest rank among all tasks of the user
+func (r *TaskRepository) lastRank(userId int) string {
+	last := ""
+	for _, task := range r.tasks {
+		if task.UserID == userId && task.Rank > last {
+			last = task.Rank
+		}
+	}
+
+	return last
+}
+
+func (r *TaskRepository) Move(userId int, taskId int, targetId int, after bool) error {
+	if taskId == targetId {
+		return store.ErrInvalidMove
+	}
+
+	task, err := r.owned(userId, taskId)
+	if err != nil {
+		return err
+	}
+
+	target, err := r.owned(userId, targetId)
+	if err != nil {
+		return err
+	}
+
+	rank, err := r.moveRank(userId, taskId, target, after)
+	if err == model.ErrNoRankBetween {
+		// Like the database, spreads the ranks out again and retries once
+		r.rerank(userId)
+		rank, err = r.moveRank(userId, taskId, target, after)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	task.Rank = rank
+	return nil
+}
+
+// moveRank returns the rank between the target and its neighbor on the other side
+func (r *TaskRepository) moveRank(userId int, taskId int, target *model.Task, after bool) (string, error) {
+	neighbor := ""
+	for _, t := range r.tasks {
+		if t.UserID != userId || t.ID == taskId {
+			continue
+		}
+
+		if after && t.Rank > target.Rank && (neighbor == "" || t.Rank < neighbor) {
+			neighbor = t.Rank
+		}
+
+		if !after && t.Rank < target.Rank && t.Rank > neighbor {
+			neighbor = t.Rank
+		}
+	}
+
+	if after {
+		return model.RankBetween(target.Rank, neighbor)
+	}
+
+	return model.RankBetween(neighbor, target.Rank)
+}
+
+// rerank spreads the ranks of the user's tasks out again keeping their order
+func (r *TaskRepository) rerank(userId int) {
+	var tasks []*model.Task
+	for _, t := range r.tasks {
+		if t.UserID == userId {
+			tasks = append(tasks, t)
+		}
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		return taskLess[store.SortRank](tasks[i], tasks[j])
+	})
+	for i, t := range tasks {
+		t.Rank = fmt.Sprintf("%06x", i+1)
+	}
+}
+
+func (r *TaskRepository) Delete(userId int, taskId int) error {
+	targetTaskId, err := getKeyFromMap(r.tasks, userId, taskId)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, task := range r.subtree(r.tasks[targetTaskId]) {
+		task.DeletedAt = &now
+		r.record(userId, task.ID, model.ActionDelete, nil)
+	}
+
+	return nil
+}
+
+func (r *TaskRepository) GetTrash(userId int) ([]*model.Task, error) {
+	var tasks []*model.Task
+	for _, task := range r.tasks {
+		if task.UserID == userId && task.DeletedAt != nil {
+			tasks = append(tasks, task)
+		}
+	}
+
+	if len(tasks) == 0 {
+		return nil, store.ErrNoRecordsInTable
+	}
+
+	// Recently deleted first
+	sort.Slice(tasks, func(i, j int) bool {
+		if !tasks[i].DeletedAt.Equal(*tasks[j].DeletedAt) {
+			return tasks[i].DeletedAt.After(*tasks[j].DeletedAt)
+		}
+
+		return tasks[i].ID < tasks[j].ID
+	})
+	r.refresh(tasks...)
+	return tasks, nil
+}
+
+func (r *TaskRepository) Restore(userId int, taskId int) error {
+	task, err := r.trashed(userId, taskId)
+	if err != nil {
+		return err
+	}
+
+	// A subtask whose parent is still in the trash is restored to the top level
+	if task.ParentID != nil && r.tasks[*task.ParentID].DeletedAt != nil {
+		task.ParentID = nil
+	}
+
+	for _, t := range r.subtree(task) {
+		t.DeletedAt = nil
+		r.record(userId, t.ID, model.ActionRestore, nil)
+	}
+
+	return nil
+}
+
+func (r *TaskRepository) Purge(userId int, taskId int) error {
+	task, err := r.trashed(userId, taskId)
+	if err != nil {
+		return err
+	}
+
+	r.purge(task)
+	return nil
+}
+
+func (r *TaskRepository) EmptyTrash(userId int) error {
+	for _, task := range r.tasks {
+		if task.UserID == userId && task.DeletedAt != nil {
+			r.purge(task)
+		}
+	}
+
+	return nil
+}
+
+func (r *TaskRepository) PurgeDeleted(before time.Time) (int, error) {
+	n := len(r.tasks)
+	for _, task := range r.tasks {
+		if task.DeletedAt != nil && task.DeletedAt.Before(before) {
+			r.purge(task)
+		}
+	}
+
+	return n - len(r.tasks), nil
+}
+
+func (r *TaskRepository) History(userId int, taskId int) ([]*model.TaskEvent, error) {
+	if task, ok := r.tasks[taskId]; !ok || !r.visible(userId, task) {
+		return nil, store.ErrInvalidTaskId
+	}
+
+	var events []*model.TaskEvent
+	for _, event := range r.events {
+		if event.TaskID == taskId {
+			events = append(events, event)
+		}
+	}
+
+	if len(events) == 0 {
+		return nil, store.ErrNoRecordsInTable
+	}
+
+	return events, nil
+}
+
+func (r *TaskRepository) ReportHistory(userId int, scope *store.ReportScope) ([]*model.Task, []*model.TaskEvent, error) {
+	var tasks []*model.Task
+	ids := map[int]bool{}
+	for _, task := range r.tasks {
+		if task.UserID != userId {
+			continue
+		}
+
+		if scope.ProjectID != nil && (task.ProjectID == nil || *task.ProjectID != *scope.ProjectID) {
+			continue
+		}
+
+		if scope.Tag != "" && !hasTag(task, scope.Tag) {
+			continue
+		}
+
+		tasks = append(tasks, task)
+		ids[task.ID] = true
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].ID < tasks[j].ID
+	})
+	r.refresh(tasks...)
+
+	var events []*model.TaskEvent
+	for _, event := range r.events {
+		if ids[event.TaskID] {
+			events = append(events, event)
+		}
+	}
+
+	return tasks, events, nil
+}
+
+func hasTag(task *model.Task, name string) bool {
+	for _, tag := range task.Tags {
+		if tag == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Search stands in for the full-text search of sqlstore: every word of the
+// query must appear in the title or the description and words starting with
+// "-" must not. There is no stemming, matches in the title rank higher.
+func (r *TaskRepository) Search(userId int, text string, limit int) ([]*model.TaskMatch, error) {
+	terms, excluded := map[string]bool{}, map[string]bool{}
+	for _, word := range strings.Fields(text) {
+		target := terms
+		if strings.HasPrefix(word, "-") {
+			target = excluded
+		}
+
+		for _, token := range tokenize(word) {
+			target[token] = true
+		}
+	}
+
+	var matches []*model.TaskMatch
+	for _, task := range r.tasks {
+		if !r.visible(userId, task) || task.DeletedAt != nil || len(terms) == 0 {
+			continue
+		}
+
+		title, description := countTokens(task.Title), countTokens(task.Description)
+		rank, matched := 0.0, true
+		for term := range terms {
+			if title[term] == 0 && description[term] == 0 {
+				matched = false
+				break
+			}
+
+			rank += float64(title[term]) + 0.4*float64(description[term])
+		}
+
+		for term := range excluded {
+			if title[term] > 0 || description[term] > 0 {
+				matched = false
+			}
+		}
+
+		if matched {
+			matches = append(matches, &model.TaskMatch{
+				Task:    task,
+				Rank:    rank,
+				Snippet: highlight(task.Title+" "+task.Description, terms),
+			})
+		}
+	}
+
+	if len(matches) == 0 {
+		return nil, store.ErrNoRecordsInTable
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Rank != matches[j].Rank {
+			return matches[i].Rank > matches[j].Rank
+		}
+
+		return matches[i].Task.ID < matches[j].Task.ID
+	})
+
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	for _, m := range matches {
+		r.refresh(m.Task)
+	}
+
+	return matches, nil
+}
+
+// tokenize splits text into lower case words
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), isSeparator)
+}
+
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+func countTokens(text string) map[string]int {
+	counts := map[string]int{}
+	for _, token := range tokenize(text) {
+		counts[token]++
+	}
+
+	return counts
+}
+
+// highlight wraps the words of text found among the terms into <b></b>, the
+// rest of the text is escaped so that only the highlights are markup
+func highlight(text string, terms map[string]bool) string {
+	var b strings.Builder
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+
+		word := text[start:end]
+		if terms[strings.ToLower(word)] {
+			word = "<b>" + html.EscapeString(word) + "</b>"
+		} else {
+			word = html.EscapeString(word)
+		}
+
+		b.WriteString(word)
+		start = -1
+	}
+
+	for i, r := range text {
+		if !isSeparator(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+
+		flush(i)
+		b.WriteString(html.EscapeString(string(r)))
+	}
+	flush(len(text))
+
+	return b.String()
+}
+
+// record adds an event made by the user to the task history
+func (r *TaskRepository) record(userId int, taskId int, action string, changes map[string]model.Change) {
+	if len(changes) == 0 {
+		changes = nil
+	}
+
+	r.lastEventId++
+	r.events = append(r.events, &model.TaskEvent{
+		ID:        r.lastEventId,
+		TaskID:    taskId,
+		UserID:    userId,
+		Action:    action,
+		Changes:   changes,
+		CreatedAt: time.Now(),
+	})
+}
+
+func (r *TaskRepository) Batch(userId int, ops []*store.TaskOperation, atomic bool) ([]*store.TaskOperationResult, error) {
+	// Snapshots stand in for the transaction and the savepoints of sqlstore
+	tx := r.snapshot()
+	failed := false
+	results := make([]*store.TaskOperationResult, 0, len(ops))
+	for _, op := range ops {
+		savepoint := r.snapshot()
+		task, err := r.apply(userId, op)
+		results = append(results, &store.TaskOperationResult{Task: task, Err: err})
+		if err != nil {
+			failed = true
+			r.rollback(savepoint)
+		}
+	}
+
+	if atomic && failed {
+		r.rollback(tx)
+		return results, store.ErrBatchRolledBack
+	}
+
+	return results, nil
+}
+
+func (r *TaskRepository) apply(userId int, op *store.TaskOperation) (*model.Task, error) {
+	switch op.Op {
+	case store.BatchCreate:
+		if op.Task == nil {
+			return nil, store.ErrInvalidOperation
+		}
+
+		op.Task.UserID = userId
+		if err := r.Create(op.Task); err != nil {
+			return nil, err
+		}
+
+		return r.GetById(userId, op.Task.ID)
+	case store.BatchComplete:
+		if err := r.Done(userId, op.TaskID); err != nil {
+			return nil, err
+		}
+
+		return r.GetById(userId, op.TaskID)
+	case store.BatchDelete:
+		return nil, r.Delete(userId, op.TaskID)
+	case store.BatchUpdate:
+		task, err := r.GetById(userId, op.TaskID)
+		if err != nil {
+			return nil, err
+		}
+
+		patched := *task
+		if err := patched.Patch(op.Patch); err != nil {
+			return nil, fmt.Errorf("%w: %v", store.ErrInvalidOperation, err)
+		}
+
+		if err := r.Update(userId, &patched); err != nil {
+			return nil, err
+		}
+
+		return r.GetById(userId, op.TaskID)
+	}
+
+	return nil, store.ErrInvalidOperation
+}
+
+// snapshot is the state of the repository to roll back to
+type snapshot struct {
+	tasks       map[int]*model.Task
+	values      map[int]model.Task
+	lastId      int
+	events      int
+	lastEventId int
+}
+
+func (r *TaskRepository) snapshot() *snapshot {
+	s := &snapshot{
+		tasks:       make(map[int]*model.Task, len(r.tasks)),
+		values:      make(map[int]model.Task, len(r.tasks)),
+		lastId:      r.lastId,
+		events:      len(r.events),
+		lastEventId: r.lastEventId,
+	}
+
+	for id, task := range r.tasks {
+		s.tasks[id] = task
+		s.values[id] = *task
+	}
+
+	return s
+}
+
+// rollback puts the snapshot values back into the same tasks the callers hold
+func (r *TaskRepository) rollback(s *snapshot) {
+	r.tasks = make(map[int]*model.Task, len(s.tasks))
+	for id, task := range s.tasks {
+		*task = s.values[id]
+		r.tasks[id] = task
+	}
+
+	r.lastId = s.lastId
+	r.events = r.events[:s.events]
+	r.lastEventId = s.lastEventId
+}
+
+func (r *TaskRepository) DeleteDone(userId int) (int, error) {
+	n := 0
+	now := time.Now()
+	for _, task := range r.tasks {
+		if task.UserID != userId || task.DeletedAt != nil || !task.Done {
+			continue
+		}
+
+		for _, t := range r.subtree(task) {
+			if t.DeletedAt == nil {
+				t.DeletedAt = &now
+				r.record(userId, t.ID, model.ActionDelete, nil)
+				n++
+			}
+		}
+	}
+
+	return n, nil
+}
+
+func (r *TaskRepository) CompleteAll(userId int) (int, []int, error) {
+	var ids []int
+	for _, task := range r.tasks {
+		if task.UserID == userId && task.DeletedAt == nil && !task.Done {
+			ids = append(ids, task.ID)
+		}
+	}
+	sort.Ints(ids)
+
+	blocked, err := store.CompleteInOrder(ids, func(id int) error {
+		return r.Done(userId, id)
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return len(ids) - len(blocked), blocked, nil
+}
+
+// trashed returns a task of the user that is in the trash
+func (r *TaskRepository) trashed(userId int, taskId int) (*model.Task, error) {
+	task, ok := r.tasks[taskId]
+	if !ok || task.UserID != userId || task.DeletedAt == nil {
+		return nil, store.ErrInvalidTaskId
+	}
+
+	return task, nil
+}
+
+// purge deletes the task with all its subtasks wherever they are, the same
+// way the database cascades
+func (r *TaskRepository) purge(root *model.Task) {
+	tasks := []*model.Task{root}
+	for i := 0; i < len(tasks); i++ {
+		for _, task := range r.tasks {
+			if task.ParentID != nil && *task.ParentID == tasks[i].ID {
+				tasks = append(tasks, task)
+			}
+		}
+	}
+
+	for _, task := range tasks {
+		delete(r.tasks, task.ID)
+		delete(r.blockers, task.ID)
+		r.store.timeEntries().deleteTask(task.ID)
+		r.store.comments().deleteTask(task.ID)
+		r.store.attachments().deleteTask(task.ID)
+		r.store.shares().deleteTask(task.ID)
+		for id, blockers := range r.blockers {
+			r.blockers[id] = removeId(blockers, task.ID)
+		}
+	}
+}
+
+func (r *TaskRepository) AddBlocker(userId int, taskId int, blockerId int) error {
+	task, err := r.editable(userId, taskId)
+	if err != nil {
+		return err
+	}
+
+	if _, err := r.GetById(userId, blockerId); err != nil {
+		return err
+	}
+
+	if _, err := r.GetById(task.UserID, blockerId); err != nil {
+		return store.ErrInvalidBlocker
+	}
+
+	if cycle := r.blockerPath(blockerId, taskId, map[int]bool{}); cycle != nil {
+		path := strconv.Itoa(taskId)
+		for _, id := range cycle {
+			path += fmt.Sprintf(" -> %d", id)
+		}
+
+		return fmt.Errorf("%w: %s", store.ErrDependencyCycle, path)
+	}
+
+	for _, id := range r.blockers[taskId] {
+		if id == blockerId {
+			return nil
+		}
+	}
+
+	r.blockers[taskId] = append(r.blockers[taskId], blockerId)
+	sort.Ints(r.blockers[taskId])
+	return nil
+}
+
+// blockerPath returns the chain of blockers leading from the task to the
+// target task including both, or nil if the task isn't blocked by it
+func (r *TaskRepository) blockerPath(taskId int, targetId int, seen map[int]bool) []int {
+	if taskId == targetId {
+		return []int{taskId}
+	}
+
+	seen[taskId] = true
+	for _, id := range r.blockers[taskId] {
+		if seen[id] {
+			continue
+		}
+
+		if path := r.blockerPath(id, targetId, seen); path != nil {
+			return append([]int{taskId}, path...)
+		}
+	}
+
+	return nil
 }
 
-func (r *TaskRepository) Create(task *model.Task) error {
-	if err := task.Validate(); err != nil {
+func (r *TaskRepository) RemoveBlocker(userId int, taskId int, blockerId int) error {
+	if _, err := r.editable(userId, taskId); err != nil {
 		return err
 	}
 
-	task.ID = len(r.tasks) + 1
-	r.tasks[task.ID] = task
+	blockers := removeId(r.blockers[taskId], blockerId)
+	if len(blockers) == len(r.blockers[taskId]) {
+		return store.ErrNoDependency
+	}
 
+	r.blockers[taskId] = blockers
 	return nil
 }
 
-func (r *TaskRepository) Delete(userId int, taskId int) error {
-	targetTaskId, err := getKeyFromMap(r.tasks, userId, taskId)
-	if err != nil {
-		return err
+// checkBlockers refuses to complete the tasks while any of them is blocked
+// by an open task other than them
+func (r *TaskRepository) checkBlockers(tasks ...*model.Task) error {
+	completed := map[int]bool{}
+	for _, task := range tasks {
+		completed[task.ID] = true
+	}
+
+	for _, task := range tasks {
+		for _, id := range r.blockers[task.ID] {
+			blocker := r.tasks[id]
+			if !completed[id] && !blocker.Done && blocker.DeletedAt == nil {
+				return store.ErrTaskBlocked
+			}
+		}
 	}
 
-	delete(r.tasks, targetTaskId)
 	return nil
 }
 
+func removeId(ids []int, id int) []int {
+	var kept []int
+	for _, i := range ids {
+		if i != id {
+			kept = append(kept, i)
+		}
+	}
+
+	return kept
+}
+
 func (r *TaskRepository) Done(userId int, taskId int) error {
-	targetTaskId, err := getKeyFromMap(r.tasks, userId, taskId)
+	task, err := r.editable(userId, taskId)
+	if err != nil {
+		return err
+	}
+
+	wasDone := task.Done
+	open := r.openSubtree(task)
+	if err := r.checkBlockers(open...); err != nil {
+		return err
+	}
+
+	// Every task of the subtree goes to the done status of its own workflow
+	for _, t := range open {
+		if !t.Done {
+			status := r.workflow(t).DoneStatus()
+			r.record(userId, t.ID, model.ActionDone, map[string]model.Change{
+				"done":   {From: false, To: true},
+				"status": {From: t.Status, To: status},
+			})
+			t.Done, t.Status = true, status
+		}
+	}
+
+	if wasDone {
+		return nil
+	}
+
+	return r.createNextOccurrence(task)
+}
+
+// openSubtree returns the task and its subtasks which are not completed
+func (r *TaskRepository) openSubtree(task *model.Task) []*model.Task {
+	var open []*model.Task
+	for _, t := range r.subtree(task) {
+		if !t.Done {
+			open = append(open, t)
+		}
+	}
+
+	return open
+}
+
+func (r *TaskRepository) createNextOccurrence(task *model.Task) error {
+	next, err := task.NextOccurrence()
+	if err != nil || next == nil {
+		return err
+	}
+
+	for _, t := range r.tasks {
+		if t.Series() == *next.SeriesID && t.DueAt != nil && t.DueAt.After(*task.DueAt) {
+			return nil
+		}
+	}
+
+	return r.Create(next)
+}
+
+func (r *TaskRepository) Update(userId int, task *model.Task) error {
+	if err := task.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := r.editable(userId, task.ID); err != nil {
+		return err
+	}
+
+	if err := r.checkProject(task); err != nil {
+		return err
+	}
+
+	if err := r.checkParent(task); err != nil {
+		return err
+	}
+
+	if err := r.checkAssignee(task); err != nil {
+		return err
+	}
+
+	targetTaskId, err := getKeyFromMap(r.tasks, task.UserID, task.ID)
 	if err != nil {
 		return err
 	}
 
-	r.tasks[targetTaskId].Done = true
+	t := r.tasks[targetTaskId]
+	if err := r.workflow(task).Apply(t, task); err != nil {
+		return err
+	}
+
+	// Completing a task goes through Done, so that its subtasks are completed
+	// too and a recurring task gets its next occurrence
+	completing := task.Done && !t.Done
+	saved := *task
+	if completing {
+		if err := r.checkBlockers(r.openSubtree(t)...); err != nil {
+			return err
+		}
+
+		saved.Done, saved.Status = false, t.Status
+	}
+
+	if changes := model.DiffTasks(t, &saved); len(changes) > 0 {
+		r.record(userId, task.ID, model.ActionUpdate, changes)
+	}
+
+	t.Title = saved.Title
+	t.Description = saved.Description
+	t.Done = saved.Done
+	t.Status = saved.Status
+	t.DueAt = saved.DueAt
+	t.ProjectID = saved.ProjectID
+	t.ParentID = saved.ParentID
+	t.Recurrence = saved.Recurrence
+	t.Priority = model.PriorityOfLevel(saved.Priority.Level())
+	t.Estimate = saved.Estimate
+	t.AssigneeID = saved.AssigneeID
+	if completing {
+		return r.Done(userId, task.ID)
+	}
+
+	return nil
+}
+
+func (r *TaskRepository) checkParent(task *model.Task) error {
+	if task.ParentID == nil {
+		return nil
+	}
+
+	parent, err := r.owned(task.UserID, *task.ParentID)
+	if err != nil {
+		return store.ErrInvalidParent
+	}
+
+	for parent != nil {
+		if parent.ID == task.ID {
+			return store.ErrInvalidParent
+		}
+
+		if parent.ParentID == nil {
+			break
+		}
+		parent = r.tasks[*parent.ParentID]
+	}
+
+	return nil
+}
+
+// subtree returns the task followed by all its subtasks at any depth. Only
+// subtasks moved to the trash together with the task belong to a trashed one.
+func (r *TaskRepository) subtree(root *model.Task) []*model.Task {
+	tasks := []*model.Task{root}
+	for i := 0; i < len(tasks); i++ {
+		for _, task := range r.tasks {
+			if task.ParentID != nil && *task.ParentID == tasks[i].ID && sameTime(task.DeletedAt, root.DeletedAt) {
+				tasks = append(tasks, task)
+			}
+		}
+	}
+
+	return tasks
+}
+
+// refresh recounts the direct subtasks, the blockers and the comments of the
+// tasks the same way sqlstore does on read
+func (r *TaskRepository) refresh(tasks ...*model.Task) {
+	for _, task := range tasks {
+		task.BlockedBy, task.Blocked = nil, false
+		for _, id := range r.blockers[task.ID] {
+			if blocker := r.tasks[id]; blocker.DeletedAt == nil {
+				task.BlockedBy = append(task.BlockedBy, id)
+				task.Blocked = task.Blocked || !blocker.Done
+			}
+		}
+
+		progress := &model.Progress{}
+		for _, subtask := range r.tasks {
+			if subtask.ParentID != nil && *subtask.ParentID == task.ID && subtask.DeletedAt == nil {
+				progress.Total++
+				if subtask.Done {
+					progress.Done++
+				}
+			}
+		}
+
+		task.Progress = nil
+		if progress.Total > 0 {
+			task.Progress = progress
+		}
+
+		task.CommentCount = 0
+		task.CommentCount = r.store.comments().count(task.ID)
+	}
+}
+
+func (r *TaskRepository) checkProject(task *model.Task) error {
+	if task.ProjectID == nil || r.orgMember(task.UserID, task.ProjectID) {
+		return nil
+	}
+
+	_, err := r.store.Project().GetById(task.UserID, *task.ProjectID)
+	return err
+}
+
+// checkAssignee makes sure the task is assigned to its owner or a member of its project
+func (r *TaskRepository) checkAssignee(task *model.Task) error {
+	if task.AssigneeID == nil || *task.AssigneeID == task.UserID {
+		return nil
+	}
+
+	if !r.projectMember(*task.AssigneeID, task.ProjectID) {
+		return store.ErrInvalidAssignee
+	}
+
 	return nil
 }
 
+// projectMember tells whether the project is shared with the user, either
+// directly or through its organization
+func (r *TaskRepository) projectMember(userId int, projectId *int) bool {
+	if projectId == nil {
+		return false
+	}
+
+	if r.store.shares().member(userId, *projectId) {
+		return true
+	}
+
+	return r.orgMember(userId, projectId)
+}
+
+// orgMember tells whether the user is a member of the organization of the project
+func (r *TaskRepository) orgMember(userId int, projectId *int) bool {
+	if projectId == nil {
+		return false
+	}
+
+	project, ok := r.store.projects().projects[*projectId]
+	if !ok || project.OrganizationID == nil {
+		return false
+	}
+
+	_, ok = r.store.organizations().members[*project.OrganizationID][userId]
+	return ok
+}
+
+// unassign takes the tasks of the owner off the assignees who are no longer
+// members of their project, e.g. after leaving it
+func (r *TaskRepository) unassign(userId int, ownerId int) {
+	r.unassignWhere(userId, func(task *model.Task) bool {
+		return task.UserID == ownerId
+	})
+}
+
+// unassignProjects does the same as unassign for the tasks in the projects,
+// whoever created them
+func (r *TaskRepository) unassignProjects(userId int, projectIds map[int]bool) {
+	r.unassignWhere(userId, func(task *model.Task) bool {
+		return task.ProjectID != nil && projectIds[*task.ProjectID]
+	})
+}
+
+func (r *TaskRepository) unassignWhere(userId int, match func(*model.Task) bool) {
+	for _, task := range r.tasks {
+		if !match(task) || task.AssigneeID == nil || *task.AssigneeID == task.UserID ||
+			r.projectMember(*task.AssigneeID, task.ProjectID) {
+			continue
+		}
+
+		r.record(userId, task.ID, model.ActionUpdate, map[string]model.Change{
+			"assignee_id": {From: *task.AssigneeID, To: nil},
+		})
+		task.AssigneeID = nil
+	}
+}
+
 func (r *TaskRepository) GetById(userId int, taskId int) (*model.Task, error) {
-	tagrgetid, err := getKeyFromMap(r.tasks, userId, taskId)
+	task, ok := r.tasks[taskId]
+	if !ok || task.DeletedAt != nil || !r.visible(userId, task) {
+		return nil, store.ErrInvalidTaskId
+	}
+
+	r.refresh(task)
+	return task, nil
+}
+
+// owned returns a task of the user which is not in the trash
+func (r *TaskRepository) owned(userId int, taskId int) (*model.Task, error) {
+	targetTaskId, err := getKeyFromMap(r.tasks, userId, taskId)
 	if err != nil {
 		return nil, err
 	}
 
-	return r.tasks[tagrgetid], nil
+	return r.tasks[targetTaskId], nil
+}
+
+// visible tells whether the task belongs to the user or is shared with them
+func (r *TaskRepository) visible(userId int, task *model.Task) bool {
+	return task.UserID == userId || r.role(userId, task) != ""
+}
+
+// editable returns a task the user can change: its owner and the editors it
+// is shared with can, the viewers get ErrReadOnly
+func (r *TaskRepository) editable(userId int, taskId int) (*model.Task, error) {
+	task, ok := r.tasks[taskId]
+	if !ok || task.DeletedAt != nil || !r.visible(userId, task) {
+		return nil, store.ErrInvalidTaskId
+	}
+
+	if task.UserID != userId && r.role(userId, task) != model.RoleEditor {
+		return nil, store.ErrReadOnly
+	}
+
+	return task, nil
+}
+
+// role returns the role the user has on the task through its shares, the
+// members of the organization of its project are editors
+func (r *TaskRepository) role(userId int, task *model.Task) string {
+	if r.orgMember(userId, task.ProjectID) {
+		return model.RoleEditor
+	}
+
+	return r.store.shares().role(userId, task)
 }
 
 func (r *TaskRepository) GetBool(userId int, done bool) ([]*model.Task, error) {
 	var tasks []*model.Task
 	for _, task := range r.tasks {
-		if task.UserID == userId && task.Done == done {
+		if r.visible(userId, task) && task.DeletedAt == nil && task.Done == done {
 			tasks = append(tasks, task)
 		}
 	}
@@ -61,28 +1058,267 @@ func (r *TaskRepository) GetBool(userId int, done bool) ([]*model.Task, error) {
 		return nil, store.ErrNoRecordsInTable
 	}
 
+	r.refresh(tasks...)
 	return tasks, nil
 }
 
 func (r *TaskRepository) GetAll(userId int) ([]*model.Task, error) {
 	var tasks []*model.Task
 	for _, task := range r.tasks {
-		if task.UserID == userId {
+		if r.visible(userId, task) && task.DeletedAt == nil {
+			tasks = append(tasks, task)
+		}
+	}
+
+	if len(tasks) == 0 {
+		return nil, store.ErrNoRecordsInTable
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		return taskLess[store.SortRank](tasks[i], tasks[j])
+	})
+	r.refresh(tasks...)
+	return tasks, nil
+}
+
+func (r *TaskRepository) GetDue(userId int, after, before *time.Time) ([]*model.Task, error) {
+	var tasks []*model.Task
+	for _, task := range r.tasks {
+		if !r.visible(userId, task) || task.DeletedAt != nil || task.DueAt == nil {
+			continue
+		}
+
+		if after != nil && !task.DueAt.After(*after) {
+			continue
+		}
+
+		if before != nil && !task.DueAt.Before(*before) {
+			continue
+		}
+
+		tasks = append(tasks, task)
+	}
+
+	r.refresh(tasks...)
+	return sortByDueAt(tasks)
+}
+
+func (r *TaskRepository) GetOverdue(userId int, now time.Time) ([]*model.Task, error) {
+	var tasks []*model.Task
+	for _, task := range r.tasks {
+		if r.visible(userId, task) && task.DeletedAt == nil && task.IsOverdue(now) {
 			tasks = append(tasks, task)
 		}
 	}
 
+	r.refresh(tasks...)
+	return sortByDueAt(tasks)
+}
+
+func (r *TaskRepository) Find(userId int, q *store.TaskQuery) ([]*model.Task, string, error) {
+	sortOrder := q.SortOrDefault()
+	less, ok := taskLess[sortOrder]
+	if !ok {
+		return nil, "", store.ErrInvalidSort
+	}
+
+	if q.Desc {
+		asc := less
+		less = func(a, b *model.Task) bool { return asc(b, a) }
+	}
+
+	var after *model.Task
+	if q.Cursor != "" {
+		c, err := store.DecodeCursor(q.Cursor, sortOrder)
+		if err != nil {
+			return nil, "", err
+		}
+
+		after, _ = c.Task()
+	}
+
+	var tasks []*model.Task
+	for _, task := range r.tasks {
+		if !r.visible(userId, task) || task.DeletedAt != nil || !matchesQuery(task, q) {
+			continue
+		}
+
+		if after != nil && !less(after, task) {
+			continue
+		}
+
+		tasks = append(tasks, task)
+	}
+
+	if len(tasks) == 0 {
+		// An empty page is a list without tasks rather than missing
+		return []*model.Task{}, "", nil
+	}
+
+	sort.Slice(tasks, func(i, j int) bool {
+		return less(tasks[i], tasks[j])
+	})
+	r.refresh(tasks...)
+
+	if q.Limit > 0 && len(tasks) > q.Limit {
+		tasks = tasks[:q.Limit]
+		return tasks, store.NewCursor(sortOrder, tasks[len(tasks)-1]), nil
+	}
+
+	return tasks, "", nil
+}
+
+func matchesQuery(task *model.Task, q *store.TaskQuery) bool {
+	if q.Done != nil && task.Done != *q.Done {
+		return false
+	}
+
+	if q.DueAfter != nil && (task.DueAt == nil || !task.DueAt.After(*q.DueAfter)) {
+		return false
+	}
+
+	if q.DueBefore != nil && (task.DueAt == nil || !task.DueAt.Before(*q.DueBefore)) {
+		return false
+	}
+
+	if q.OverdueAt != nil && !task.IsOverdue(*q.OverdueAt) {
+		return false
+	}
+
+	if q.ProjectID != nil && (task.ProjectID == nil || *task.ProjectID != *q.ProjectID) {
+		return false
+	}
+
+	if q.ParentID != nil && (task.ParentID == nil || *task.ParentID != *q.ParentID) {
+		return false
+	}
+
+	if q.SeriesID != nil && task.Series() != *q.SeriesID {
+		return false
+	}
+
+	if q.AssigneeID != nil && !sameId(task.AssigneeID, q.AssigneeID) {
+		return false
+	}
+
+	if len(q.Statuses) > 0 {
+		found := false
+		for _, status := range q.Statuses {
+			if task.Status == status {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if len(q.Priorities) > 0 {
+		found := false
+		for _, priority := range q.Priorities {
+			if task.Priority.Level() == priority.Level() {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if q.Filter != nil && !matchesFilter(task, q.Filter) {
+		return false
+	}
+
+	if len(q.Tags) > 0 {
+		matched := 0
+		for _, name := range q.Tags {
+			for _, tag := range task.Tags {
+				if tag == name {
+					matched++
+					break
+				}
+			}
+		}
+
+		if matched == 0 || q.AllTags && matched < len(q.Tags) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// taskLess holds the ascending order of tasks for every sort order,
+// ties are broken by id the same way sqlstore does
+var taskLess = map[string]func(a, b *model.Task) bool{
+	store.SortCreated: func(a, b *model.Task) bool {
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+
+		return a.ID < b.ID
+	},
+	store.SortTitle: func(a, b *model.Task) bool {
+		if a.Title != b.Title {
+			return a.Title < b.Title
+		}
+
+		return a.ID < b.ID
+	},
+	store.SortRank: func(a, b *model.Task) bool {
+		if a.UserID != b.UserID {
+			return a.UserID < b.UserID
+		}
+
+		if a.Rank != b.Rank {
+			return a.Rank < b.Rank
+		}
+
+		return a.ID < b.ID
+	},
+	store.SortDue: func(a, b *model.Task) bool {
+		// Tasks without a due date go last
+		switch {
+		case a.DueAt == nil && b.DueAt == nil:
+		case a.DueAt == nil:
+			return false
+		case b.DueAt == nil:
+			return true
+		case !a.DueAt.Equal(*b.DueAt):
+			return a.DueAt.Before(*b.DueAt)
+		}
+
+		return a.ID < b.ID
+	},
+	store.SortPriority: func(a, b *model.Task) bool {
+		if ra, rb := store.PriorityRank(a), store.PriorityRank(b); ra != rb {
+			return ra < rb
+		}
+
+		return a.ID < b.ID
+	},
+}
+
+func sortByDueAt(tasks []*model.Task) ([]*model.Task, error) {
 	if len(tasks) == 0 {
 		return nil, store.ErrNoRecordsInTable
 	}
 
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].DueAt.Before(*tasks[j].DueAt)
+	})
+
 	return tasks, nil
 }
 
 func getKeyFromMap(tasks map[int]*model.Task, userId int, taskId int) (int, error) {
 	var targetTaskId int
 	for k, task := range tasks {
-		if task.UserID == userId && task.ID == taskId {
+		if task.UserID == userId && task.ID == taskId && task.DeletedAt == nil {
 			targetTaskId = k
 			break
 		}
@@ -94,3 +1330,11 @@ func getKeyFromMap(tasks map[int]*model.Task, userId int, taskId int) (int, erro
 
 	return targetTaskId, nil
 }
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Equal(*b)
+}
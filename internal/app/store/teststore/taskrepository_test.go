@@ -1,10 +1,14 @@
 package teststore_test
 
 import (
+	"encoding/json"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/pyuldashev912/todoapp/internal/app/model"
 	"github.com/pyuldashev912/todoapp/internal/app/store"
+	"github.com/pyuldashev912/todoapp/internal/app/store/filter"
 	"github.com/pyuldashev912/todoapp/internal/app/store/teststore"
 	"github.com/stretchr/testify/assert"
 )
@@ -67,3 +71,871 @@ func TestTaskRepository_GetAll(t *testing.T) {
 	_, err = s.Task().GetAll(task.UserID)
 	assert.NoError(t, err)
 }
+
+func TestTaskRepository_GetDue(t *testing.T) {
+	s := teststore.New()
+	now := time.Now()
+	_, err := s.Task().GetDue(1, nil, nil)
+	assert.EqualError(t, err, store.ErrNoRecordsInTable.Error())
+
+	for _, d := range []time.Duration{-time.Hour, time.Hour, 48 * time.Hour} {
+		task := model.TestTask(t)
+		dueAt := now.Add(d)
+		task.DueAt = &dueAt
+		s.Task().Create(task)
+	}
+	s.Task().Create(model.TestTask(t))
+
+	res, err := s.Task().GetDue(1, nil, nil)
+	assert.NoError(t, err)
+	assert.Len(t, res, 3)
+
+	tomorrow := now.Add(24 * time.Hour)
+	res, err = s.Task().GetDue(1, &now, &tomorrow)
+	assert.NoError(t, err)
+	assert.Len(t, res, 1)
+
+	res, err = s.Task().GetDue(1, nil, &tomorrow)
+	assert.NoError(t, err)
+	assert.Len(t, res, 2)
+	assert.True(t, res[0].DueAt.Before(*res[1].DueAt))
+}
+
+func TestTaskRepository_GetOverdue(t *testing.T) {
+	s := teststore.New()
+	now := time.Now()
+	_, err := s.Task().GetOverdue(1, now)
+	assert.EqualError(t, err, store.ErrNoRecordsInTable.Error())
+
+	yesterday := now.Add(-24 * time.Hour)
+	overdue := model.TestTask(t)
+	overdue.DueAt = &yesterday
+	s.Task().Create(overdue)
+
+	completed := model.TestTask(t)
+	completed.DueAt = &yesterday
+	s.Task().Create(completed)
+	s.Task().Done(completed.UserID, completed.ID)
+
+	res, err := s.Task().GetOverdue(1, now)
+	assert.NoError(t, err)
+	assert.Len(t, res, 1)
+	assert.Equal(t, overdue.ID, res[0].ID)
+}
+
+func TestTaskRepository_Update(t *testing.T) {
+	s := teststore.New()
+	task := model.TestTask(t)
+	s.Task().Create(task)
+	s.Task().Done(task.UserID, task.ID)
+
+	updated := *task
+	updated.Title = "Renamed"
+	updated.Done = false
+	assert.NoError(t, s.Task().Update(1, &updated))
+
+	res, _ := s.Task().GetById(task.UserID, task.ID)
+	assert.Equal(t, "Renamed", res.Title)
+	assert.False(t, res.Done)
+
+	updated.Title = ""
+	assert.Error(t, s.Task().Update(1, &updated))
+
+	updated = *task
+	updated.ID = 5
+	assert.EqualError(t, s.Task().Update(1, &updated), store.ErrInvalidTaskId.Error())
+}
+
+func TestTaskRepository_Find(t *testing.T) {
+	s := teststore.New()
+	empty, _, err := s.Task().Find(1, &store.TaskQuery{})
+	assert.NoError(t, err)
+	assert.Equal(t, []*model.Task{}, empty)
+
+	now := time.Now()
+	for i, title := range []string{"b", "a", "c", "a"} {
+		task := model.TestTask(t)
+		task.Title = title
+		dueAt := now.Add(time.Duration(i) * time.Hour)
+		task.DueAt = &dueAt
+		s.Task().Create(task)
+	}
+	s.Task().Done(1, 3)
+
+	// Walk through all pages
+	var titles []string
+	query := &store.TaskQuery{Sort: store.SortTitle, Limit: 3}
+	for {
+		tasks, cursor, err := s.Task().Find(1, query)
+		assert.NoError(t, err)
+		for _, task := range tasks {
+			titles = append(titles, task.Title)
+		}
+
+		if cursor == "" {
+			break
+		}
+		query.Cursor = cursor
+	}
+	assert.Equal(t, []string{"a", "a", "b", "c"}, titles)
+
+	// A page past the end is empty
+	tasks, _, err := s.Task().Find(1, &store.TaskQuery{
+		Sort:   store.SortTitle,
+		Cursor: store.NewCursor(store.SortTitle, &model.Task{ID: 1000, Title: "z"}),
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, tasks)
+
+	tasks, _, err = s.Task().Find(1, &store.TaskQuery{Sort: store.SortDue, Desc: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, tasks[0].ID)
+
+	done := false
+	tasks, _, err = s.Task().Find(1, &store.TaskQuery{Done: &done})
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 3)
+	assert.Equal(t, 1, tasks[0].ID)
+
+	_, _, err = s.Task().Find(1, &store.TaskQuery{Sort: "color"})
+	assert.EqualError(t, err, store.ErrInvalidSort.Error())
+
+	_, _, err = s.Task().Find(1, &store.TaskQuery{Sort: store.SortDue, Cursor: query.Cursor})
+	assert.EqualError(t, err, store.ErrInvalidCursor.Error())
+}
+
+func TestTaskRepository_FindByPriority(t *testing.T) {
+	s := teststore.New()
+	priorities := []model.Priority{model.PriorityLow, "", model.PriorityUrgent, model.PriorityHigh, model.PriorityUrgent}
+	for _, priority := range priorities {
+		task := model.TestTask(t)
+		task.Priority = priority
+		s.Task().Create(task)
+	}
+	s.Task().Done(1, 3)
+
+	// Open tasks from the most important, completed ones last
+	var ids []int
+	query := &store.TaskQuery{Sort: store.SortPriority, Limit: 2}
+	for {
+		tasks, cursor, err := s.Task().Find(1, query)
+		assert.NoError(t, err)
+		for _, task := range tasks {
+			ids = append(ids, task.ID)
+		}
+
+		if cursor == "" {
+			break
+		}
+		query.Cursor = cursor
+	}
+	assert.Equal(t, []int{5, 4, 1, 2, 3}, ids)
+
+	tasks, _, err := s.Task().Find(1, &store.TaskQuery{
+		Priorities: []model.Priority{model.PriorityNone, model.PriorityHigh},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 2)
+	assert.Equal(t, model.PriorityNone, tasks[0].Priority)
+	assert.Equal(t, model.PriorityHigh, tasks[1].Priority)
+}
+
+func TestTaskRepository_FindByTags(t *testing.T) {
+	s := teststore.New()
+	bug := &model.Tag{UserID: 1, Name: "bug"}
+	home := &model.Tag{UserID: 1, Name: "home"}
+	s.Tag().Create(bug)
+	s.Tag().Create(home)
+
+	both, onlyBug := model.TestTask(t), model.TestTask(t)
+	s.Task().Create(both)
+	s.Task().Create(onlyBug)
+	s.Task().Create(model.TestTask(t))
+	s.Tag().Attach(1, both.ID, bug.ID)
+	s.Tag().Attach(1, both.ID, home.ID)
+	s.Tag().Attach(1, onlyBug.ID, bug.ID)
+
+	tasks, _, err := s.Task().Find(1, &store.TaskQuery{Tags: []string{"bug", "home"}})
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 2)
+
+	tasks, _, err = s.Task().Find(1, &store.TaskQuery{Tags: []string{"bug", "home"}, AllTags: true})
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 1)
+	assert.Equal(t, both.ID, tasks[0].ID)
+}
+
+func TestTaskRepository_Subtasks(t *testing.T) {
+	s := teststore.New()
+	parent := model.TestTask(t)
+	s.Task().Create(parent)
+
+	child, grandchild := model.TestTask(t), model.TestTask(t)
+	child.ParentID = &parent.ID
+	assert.NoError(t, s.Task().Create(child))
+	grandchild.ParentID = &child.ID
+	assert.NoError(t, s.Task().Create(grandchild))
+
+	invalid := model.TestTask(t)
+	missing := 10
+	invalid.ParentID = &missing
+	assert.EqualError(t, s.Task().Create(invalid), store.ErrInvalidParent.Error())
+
+	// A task can't become a subtask of its own subtask
+	cycle := *parent
+	cycle.ParentID = &grandchild.ID
+	assert.EqualError(t, s.Task().Update(1, &cycle), store.ErrInvalidParent.Error())
+
+	res, _ := s.Task().GetById(parent.UserID, parent.ID)
+	assert.Equal(t, &model.Progress{Done: 0, Total: 1}, res.Progress)
+
+	children, _, err := s.Task().Find(parent.UserID, &store.TaskQuery{ParentID: &parent.ID})
+	assert.NoError(t, err)
+	assert.Len(t, children, 1)
+
+	// Completing a task completes its whole subtree
+	assert.NoError(t, s.Task().Done(parent.UserID, parent.ID))
+	res, _ = s.Task().GetById(grandchild.UserID, grandchild.ID)
+	assert.True(t, res.Done)
+	res, _ = s.Task().GetById(parent.UserID, parent.ID)
+	assert.Equal(t, &model.Progress{Done: 1, Total: 1}, res.Progress)
+
+	// Deleting a task deletes its whole subtree
+	assert.NoError(t, s.Task().Delete(parent.UserID, parent.ID))
+	_, err = s.Task().GetById(grandchild.UserID, grandchild.ID)
+	assert.EqualError(t, err, store.ErrInvalidTaskId.Error())
+}
+
+func TestTaskRepository_DoneRecurring(t *testing.T) {
+	s := teststore.New()
+	task := model.TestTask(t)
+	dueAt := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	task.DueAt = &dueAt
+	task.Recurrence = "FREQ=DAILY;COUNT=2"
+	assert.NoError(t, s.Task().Create(task))
+
+	assert.NoError(t, s.Task().Done(task.UserID, task.ID))
+	series, _, err := s.Task().Find(task.UserID, &store.TaskQuery{SeriesID: &task.ID})
+	assert.NoError(t, err)
+	assert.Len(t, series, 2)
+	next := series[1]
+	assert.False(t, next.Done)
+	assert.Equal(t, dueAt.AddDate(0, 0, 1), *next.DueAt)
+
+	// Completing the same occurrence again doesn't repeat the series
+	s.Task().Update(1, &model.Task{
+		ID: task.ID, UserID: task.UserID, Title: task.Title, Description: task.Description,
+		DueAt: task.DueAt, Recurrence: task.Recurrence,
+	})
+	assert.NoError(t, s.Task().Done(task.UserID, task.ID))
+
+	// The last occurrence ends the series
+	assert.NoError(t, s.Task().Done(next.UserID, next.ID))
+	series, _, _ = s.Task().Find(task.UserID, &store.TaskQuery{SeriesID: &task.ID})
+	assert.Len(t, series, 2)
+}
+
+func TestTaskRepository_UpdateDoneSubtasks(t *testing.T) {
+	s := teststore.New()
+	parent, child, blocker := model.TestTask(t), model.TestTask(t), model.TestTask(t)
+	s.Task().Create(parent)
+	child.ParentID = &parent.ID
+	s.Task().Create(child)
+	s.Task().Create(blocker)
+
+	update := &model.Task{
+		ID: parent.ID, UserID: parent.UserID, Title: "Renamed", Description: parent.Description,
+		Status: parent.Status, Done: true,
+	}
+
+	// Completing through an update checks the blockers of the subtasks like Done
+	assert.NoError(t, s.Task().AddBlocker(child.UserID, child.ID, blocker.ID))
+	assert.EqualError(t, s.Task().Update(parent.UserID, update), store.ErrTaskBlocked.Error())
+	res, _ := s.Task().GetById(parent.UserID, parent.ID)
+	assert.False(t, res.Done)
+	assert.NotEqual(t, "Renamed", res.Title)
+
+	// and completes them
+	assert.NoError(t, s.Task().RemoveBlocker(child.UserID, child.ID, blocker.ID))
+	update.Status, update.Done = parent.Status, true
+	assert.NoError(t, s.Task().Update(parent.UserID, update))
+	res, _ = s.Task().GetById(parent.UserID, parent.ID)
+	assert.True(t, res.Done)
+	assert.Equal(t, "Renamed", res.Title)
+	res, _ = s.Task().GetById(child.UserID, child.ID)
+	assert.True(t, res.Done)
+	assert.Equal(t, update.Status, res.Status)
+}
+
+func TestTaskRepository_UpdateDoneRecurring(t *testing.T) {
+	s := teststore.New()
+	task := model.TestTask(t)
+	dueAt := time.Date(2026, 1, 5, 9, 0, 0, 0, time.UTC)
+	task.DueAt = &dueAt
+	task.Recurrence = "FREQ=DAILY;COUNT=3"
+	assert.NoError(t, s.Task().Create(task))
+
+	// Completing through an update creates the next occurrence like Done
+	update := &model.Task{
+		ID: task.ID, UserID: task.UserID, Title: task.Title, Description: task.Description,
+		Status: task.Status, DueAt: task.DueAt, Recurrence: task.Recurrence, Done: true,
+	}
+	assert.NoError(t, s.Task().Update(task.UserID, update))
+	series, _, err := s.Task().Find(task.UserID, &store.TaskQuery{SeriesID: &task.ID})
+	assert.NoError(t, err)
+	assert.Len(t, series, 2)
+	assert.True(t, series[0].Done)
+	assert.False(t, series[1].Done)
+	assert.True(t, dueAt.AddDate(0, 0, 1).Equal(*series[1].DueAt))
+
+	// Saving a completed task again doesn't repeat the series
+	assert.NoError(t, s.Task().Update(task.UserID, update))
+	series, _, _ = s.Task().Find(task.UserID, &store.TaskQuery{SeriesID: &task.ID})
+	assert.Len(t, series, 2)
+}
+
+func TestTaskRepository_Trash(t *testing.T) {
+	s := teststore.New()
+	parent, child, other := model.TestTask(t), model.TestTask(t), model.TestTask(t)
+	s.Task().Create(parent)
+	child.ParentID = &parent.ID
+	s.Task().Create(child)
+	s.Task().Create(other)
+
+	_, err := s.Task().GetTrash(parent.UserID)
+	assert.EqualError(t, err, store.ErrNoRecordsInTable.Error())
+
+	// Deleting a task moves its whole subtree to the trash
+	assert.NoError(t, s.Task().Delete(parent.UserID, parent.ID))
+	tasks, err := s.Task().GetAll(parent.UserID)
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 1)
+	assert.EqualError(t, s.Task().Done(child.UserID, child.ID), store.ErrInvalidTaskId.Error())
+	assert.EqualError(t, s.Task().Delete(parent.UserID, parent.ID), store.ErrInvalidTaskId.Error())
+
+	trash, err := s.Task().GetTrash(parent.UserID)
+	assert.NoError(t, err)
+	assert.Len(t, trash, 2)
+	assert.NotNil(t, trash[0].DeletedAt)
+
+	// A subtask restored without its parent becomes a top level task
+	assert.NoError(t, s.Task().Restore(child.UserID, child.ID))
+	res, err := s.Task().GetById(child.UserID, child.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, res.ParentID)
+	assert.Nil(t, res.DeletedAt)
+	assert.EqualError(t, s.Task().Restore(child.UserID, child.ID), store.ErrInvalidTaskId.Error())
+
+	// Only tasks in the trash can be purged
+	assert.EqualError(t, s.Task().Purge(other.UserID, other.ID), store.ErrInvalidTaskId.Error())
+	assert.NoError(t, s.Task().Purge(parent.UserID, parent.ID))
+	assert.EqualError(t, s.Task().Restore(parent.UserID, parent.ID), store.ErrInvalidTaskId.Error())
+
+	s.Task().Delete(other.UserID, other.ID)
+	n, err := s.Task().PurgeDeleted(time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	n, err = s.Task().PurgeDeleted(time.Now().Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	s.Task().Delete(child.UserID, child.ID)
+	assert.NoError(t, s.Task().EmptyTrash(child.UserID))
+	_, err = s.Task().GetTrash(child.UserID)
+	assert.EqualError(t, err, store.ErrNoRecordsInTable.Error())
+}
+
+func TestTaskRepository_History(t *testing.T) {
+	s := teststore.New()
+	task := model.TestTask(t)
+	s.Task().Create(task)
+	child := model.TestTask(t)
+	child.ParentID = &task.ID
+	s.Task().Create(child)
+
+	_, err := s.Task().History(task.UserID+1, task.ID)
+	assert.EqualError(t, err, store.ErrInvalidTaskId.Error())
+
+	edited := *task
+	edited.Title = "Fixed title"
+	assert.NoError(t, s.Task().Update(1, &edited))
+	// Saving the same state again isn't a change
+	assert.NoError(t, s.Task().Update(1, &edited))
+	assert.NoError(t, s.Task().Done(task.UserID, task.ID))
+	assert.NoError(t, s.Task().Delete(task.UserID, task.ID))
+	assert.NoError(t, s.Task().Restore(task.UserID, task.ID))
+
+	events, err := s.Task().History(task.UserID, task.ID)
+	assert.NoError(t, err)
+	var actions []string
+	for _, event := range events {
+		actions = append(actions, event.Action)
+		assert.Equal(t, task.UserID, event.UserID)
+	}
+	assert.Equal(t, []string{
+		model.ActionCreate, model.ActionUpdate, model.ActionDone, model.ActionDelete, model.ActionRestore,
+	}, actions)
+	assert.Equal(t, model.Change{From: model.TestTask(t).Title, To: "Fixed title"}, events[1].Changes["title"])
+	assert.Equal(t, model.Change{From: false, To: true}, events[2].Changes["done"])
+
+	// Cascading changes are recorded for every subtask
+	events, err = s.Task().History(child.UserID, child.ID)
+	assert.NoError(t, err)
+	assert.Len(t, events, 4)
+}
+
+func TestTaskRepository_Search(t *testing.T) {
+	s := teststore.New()
+	texts := [][2]string{
+		{"Buy milk", "From the shop near home"},
+		{"Call mom", "Ask about the milk recipe"},
+		{"Buy bread", "Whole grain"},
+	}
+	for _, text := range texts {
+		task := model.TestTask(t)
+		task.Title, task.Description = text[0], text[1]
+		s.Task().Create(task)
+	}
+	s.Task().Delete(1, 3)
+
+	matches, err := s.Task().Search(1, "MILK", 0)
+	assert.NoError(t, err)
+	assert.Len(t, matches, 2)
+	// Matches in the title rank higher
+	assert.Equal(t, 1, matches[0].Task.ID)
+	assert.Equal(t, "Buy <b>milk</b> From the shop near home", matches[0].Snippet)
+
+	matches, err = s.Task().Search(1, "milk -recipe", 0)
+	assert.NoError(t, err)
+	assert.Len(t, matches, 1)
+
+	matches, err = s.Task().Search(1, "milk", 1)
+	assert.NoError(t, err)
+	assert.Len(t, matches, 1)
+
+	// Tasks in the trash and of other users aren't found
+	_, err = s.Task().Search(1, "bread", 0)
+	assert.EqualError(t, err, store.ErrNoRecordsInTable.Error())
+	_, err = s.Task().Search(2, "milk", 0)
+	assert.EqualError(t, err, store.ErrNoRecordsInTable.Error())
+
+	// Only the highlights are markup
+	task := model.TestTask(t)
+	task.Title, task.Description = "<script>alert('milk')</script>", "Fish & chips"
+	s.Task().Create(task)
+	matches, err = s.Task().Search(1, "alert", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "&lt;script&gt;<b>alert</b>(&#39;milk&#39;)&lt;/script&gt; Fish &amp; chips", matches[0].Snippet)
+}
+
+func TestTaskRepository_FindByFilter(t *testing.T) {
+	s := teststore.New()
+	dueAt := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	specs := []struct {
+		title    string
+		priority model.Priority
+		dueAt    *time.Time
+	}{
+		{"Weekly report", model.PriorityHigh, &dueAt},
+		{"Monthly REPORT", model.PriorityLow, nil},
+		{"Buy milk", model.PriorityUrgent, nil},
+	}
+	var ids []int
+	for _, spec := range specs {
+		task := model.TestTask(t)
+		task.Title, task.Priority, task.DueAt = spec.title, spec.priority, spec.dueAt
+		s.Task().Create(task)
+		ids = append(ids, task.ID)
+	}
+	s.Task().Done(1, ids[1])
+
+	testCases := []struct {
+		filter   string
+		expected []int
+	}{
+		{`done:false AND title~"report"`, []int{ids[0]}},
+		{"title~report OR priority>=urgent", ids},
+		{"NOT title~report", []int{ids[2]}},
+		{"due:null", []int{ids[1], ids[2]}},
+		{"due<2026-02-01", []int{ids[0]}},
+		// Ordered comparisons don't match tasks without a due date
+		{"NOT due<2026-02-01", []int{ids[1], ids[2]}},
+		{"priority!=high AND created>2000-01-01", []int{ids[1], ids[2]}},
+		{"project:null AND title:\"Buy milk\"", []int{ids[2]}},
+	}
+
+	for _, tc := range testCases {
+		e, err := filter.Parse(tc.filter)
+		assert.NoError(t, err)
+		tasks, _, err := s.Task().Find(1, &store.TaskQuery{Filter: e})
+		assert.NoError(t, err, tc.filter)
+		var found []int
+		for _, task := range tasks {
+			found = append(found, task.ID)
+		}
+		assert.Equal(t, tc.expected, found, tc.filter)
+	}
+
+	e, _ := filter.Parse("tag:home")
+	tasks, _, err := s.Task().Find(1, &store.TaskQuery{Filter: e})
+	assert.NoError(t, err)
+	assert.Empty(t, tasks)
+}
+
+func TestTaskRepository_Batch(t *testing.T) {
+	s := teststore.New()
+	task := model.TestTask(t)
+	s.Task().Create(task)
+
+	results, err := s.Task().Batch(task.UserID, []*store.TaskOperation{
+		{Op: store.BatchCreate, Task: model.TestTask(t)},
+		{Op: store.BatchUpdate, TaskID: task.ID, Patch: json.RawMessage(`{"title": "Renamed"}`)},
+		{Op: store.BatchComplete, TaskID: task.ID + 10},
+		{Op: store.BatchUpdate, TaskID: task.ID, Patch: json.RawMessage(`{"title": ""}`)},
+		{Op: "archive", TaskID: task.ID},
+	}, false)
+	assert.NoError(t, err)
+	assert.Len(t, results, 5)
+	assert.Equal(t, 2, results[0].Task.ID)
+	assert.Equal(t, "Renamed", results[1].Task.Title)
+	assert.ErrorIs(t, results[2].Err, store.ErrInvalidTaskId)
+	assert.Error(t, results[3].Err)
+	assert.ErrorIs(t, results[4].Err, store.ErrInvalidOperation)
+
+	// A failed operation doesn't affect the others
+	res, err := s.Task().GetById(task.UserID, task.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Renamed", res.Title)
+
+	// An atomic batch is rolled back as a whole
+	results, err = s.Task().Batch(task.UserID, []*store.TaskOperation{
+		{Op: store.BatchComplete, TaskID: task.ID},
+		{Op: store.BatchCreate, Task: model.TestTask(t)},
+		{Op: store.BatchDelete, TaskID: task.ID + 10},
+	}, true)
+	assert.EqualError(t, err, store.ErrBatchRolledBack.Error())
+	assert.NoError(t, results[0].Err)
+	assert.ErrorIs(t, results[2].Err, store.ErrInvalidTaskId)
+	res, err = s.Task().GetById(task.UserID, task.ID)
+	assert.NoError(t, err)
+	assert.False(t, res.Done)
+	tasks, err := s.Task().GetAll(task.UserID)
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 2)
+}
+
+func TestTaskRepository_CompleteAll(t *testing.T) {
+	s := teststore.New()
+	for i := 0; i < 3; i++ {
+		s.Task().Create(model.TestTask(t))
+	}
+	s.Task().Done(1, 1)
+
+	n, blocked, err := s.Task().CompleteAll(1)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Empty(t, blocked)
+	n, _, err = s.Task().CompleteAll(1)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+}
+
+func TestTaskRepository_DeleteDone(t *testing.T) {
+	s := teststore.New()
+	parent, child, open := model.TestTask(t), model.TestTask(t), model.TestTask(t)
+	s.Task().Create(parent)
+	child.ParentID = &parent.ID
+	s.Task().Create(child)
+	s.Task().Create(open)
+	s.Task().Done(parent.UserID, parent.ID)
+
+	// Completed tasks go to the trash with their subtasks
+	n, err := s.Task().DeleteDone(parent.UserID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	trash, err := s.Task().GetTrash(parent.UserID)
+	assert.NoError(t, err)
+	assert.Len(t, trash, 2)
+	tasks, err := s.Task().GetAll(parent.UserID)
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 1)
+}
+
+func TestTaskRepository_Move(t *testing.T) {
+	s := teststore.New()
+	var ids []int
+	for i := 0; i < 4; i++ {
+		task := model.TestTask(t)
+		s.Task().Create(task)
+		ids = append(ids, task.ID)
+	}
+
+	order := func() []int {
+		tasks, err := s.Task().GetAll(1)
+		assert.NoError(t, err)
+		var found []int
+		for _, task := range tasks {
+			found = append(found, task.ID)
+		}
+		return found
+	}
+
+	// New tasks are appended to the end
+	assert.Equal(t, ids, order())
+
+	assert.NoError(t, s.Task().Move(1, ids[3], ids[0], false))
+	assert.Equal(t, []int{ids[3], ids[0], ids[1], ids[2]}, order())
+	assert.NoError(t, s.Task().Move(1, ids[3], ids[1], true))
+	assert.Equal(t, []int{ids[0], ids[1], ids[3], ids[2]}, order())
+	assert.NoError(t, s.Task().Move(1, ids[0], ids[2], true))
+	assert.Equal(t, []int{ids[1], ids[3], ids[2], ids[0]}, order())
+
+	// Only the moved task gets a new rank
+	task, _ := s.Task().GetById(1, ids[1])
+	rank := task.Rank
+	assert.NoError(t, s.Task().Move(1, ids[2], ids[1], true))
+	assert.Equal(t, rank, task.Rank)
+
+	assert.EqualError(t, s.Task().Move(1, ids[0], ids[0], true), store.ErrInvalidMove.Error())
+	assert.EqualError(t, s.Task().Move(1, ids[0], 100, true), store.ErrInvalidTaskId.Error())
+	assert.EqualError(t, s.Task().Move(2, ids[0], ids[1], true), store.ErrInvalidTaskId.Error())
+
+	tasks, _, err := s.Task().Find(1, &store.TaskQuery{Sort: store.SortRank, Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{ids[1], ids[2]}, []int{tasks[0].ID, tasks[1].ID})
+}
+
+func TestTaskRepository_MoveShared(t *testing.T) {
+	s := teststore.New()
+	var ids []int
+	for _, userId := range []int{2, 1, 1, 1} {
+		task := model.TestTask(t)
+		task.UserID = userId
+		s.Task().Create(task)
+		ids = append(ids, task.ID)
+	}
+
+	for i := range ids[1:] {
+		share := model.TestShare(t)
+		share.TaskID = &ids[i+1]
+		s.Share().Create(share)
+	}
+
+	order := func(userId int) []int {
+		var found []int
+		query := &store.TaskQuery{Sort: store.SortRank, Limit: 1}
+		for {
+			tasks, cursor, err := s.Task().Find(userId, query)
+			assert.NoError(t, err)
+			for _, task := range tasks {
+				found = append(found, task.ID)
+			}
+
+			if cursor == "" {
+				return found
+			}
+			query.Cursor = cursor
+		}
+	}
+
+	// The tasks of each owner are listed together in the order of the owner
+	tasks, err := s.Task().GetAll(2)
+	assert.NoError(t, err)
+	var all []int
+	for _, task := range tasks {
+		all = append(all, task.ID)
+	}
+	assert.Equal(t, []int{ids[1], ids[2], ids[3], ids[0]}, all)
+	assert.Equal(t, all, order(2))
+
+	assert.NoError(t, s.Task().Move(1, ids[3], ids[1], false))
+	assert.Equal(t, []int{ids[3], ids[1], ids[2], ids[0]}, order(2))
+
+	// Ranks without room between them are spread out again
+	for id, rank := range map[int]string{ids[1]: "a", ids[2]: "a0"} {
+		task, _ := s.Task().GetById(1, id)
+		task.Rank = rank
+	}
+	assert.NoError(t, s.Task().Move(1, ids[3], ids[1], true))
+	assert.Equal(t, []int{ids[1], ids[3], ids[2]}, order(1))
+}
+
+func TestTaskRepository_Status(t *testing.T) {
+	s := teststore.New()
+	task := model.TestTask(t)
+	assert.NoError(t, s.Task().Create(task))
+	assert.Equal(t, "todo", task.Status)
+
+	invalid := model.TestTask(t)
+	invalid.Status = "review"
+	assert.EqualError(t, s.Task().Create(invalid), model.ErrInvalidStatus.Error())
+
+	edited, _ := s.Task().GetById(task.UserID, task.ID)
+	blocked := *edited
+	blocked.Status = "blocked"
+	assert.NoError(t, s.Task().Update(1, &blocked))
+	assert.False(t, blocked.Done)
+
+	edited, _ = s.Task().GetById(task.UserID, task.ID)
+	done := *edited
+	done.Status = "done"
+	assert.EqualError(t, s.Task().Update(1, &done), model.ErrInvalidTransition.Error())
+
+	// Completing a task moves it to the done status whatever the transitions
+	assert.NoError(t, s.Task().Done(task.UserID, task.ID))
+	res, err := s.Task().GetById(task.UserID, task.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "done", res.Status)
+	assert.True(t, res.Done)
+
+	// Reopening a task moves it to the initial status
+	reopened := *res
+	reopened.Done = false
+	assert.NoError(t, s.Task().Update(1, &reopened))
+	assert.Equal(t, "todo", reopened.Status)
+
+	tasks, _, err := s.Task().Find(task.UserID, &store.TaskQuery{Statuses: []string{"todo", "blocked"}})
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 1)
+	tasks, _, err = s.Task().Find(task.UserID, &store.TaskQuery{Statuses: []string{"in_progress"}})
+	assert.NoError(t, err)
+	assert.Empty(t, tasks)
+}
+
+func TestTaskRepository_Blockers(t *testing.T) {
+	s := teststore.New()
+	var ids []int
+	for i := 0; i < 3; i++ {
+		task := model.TestTask(t)
+		s.Task().Create(task)
+		ids = append(ids, task.ID)
+	}
+
+	assert.NoError(t, s.Task().AddBlocker(1, ids[1], ids[0]))
+	assert.NoError(t, s.Task().AddBlocker(1, ids[2], ids[1]))
+	// Adding a blocker twice changes nothing
+	assert.NoError(t, s.Task().AddBlocker(1, ids[2], ids[1]))
+
+	task, _ := s.Task().GetById(1, ids[2])
+	assert.Equal(t, []int{ids[1]}, task.BlockedBy)
+	assert.True(t, task.Blocked)
+
+	err := s.Task().AddBlocker(1, ids[0], ids[2])
+	assert.ErrorIs(t, err, store.ErrDependencyCycle)
+	assert.Contains(t, err.Error(), fmt.Sprintf("%d -> %d -> %d -> %d", ids[0], ids[2], ids[1], ids[0]))
+	assert.ErrorIs(t, s.Task().AddBlocker(1, ids[0], ids[0]), store.ErrDependencyCycle)
+	assert.EqualError(t, s.Task().AddBlocker(2, ids[0], ids[1]), store.ErrInvalidTaskId.Error())
+
+	assert.EqualError(t, s.Task().Done(1, ids[1]), store.ErrTaskBlocked.Error())
+	assert.NoError(t, s.Task().Done(1, ids[0]))
+	assert.NoError(t, s.Task().Done(1, ids[1]))
+
+	// A completed blocker doesn't block anymore
+	task, _ = s.Task().GetById(1, ids[2])
+	assert.Equal(t, []int{ids[1]}, task.BlockedBy)
+	assert.False(t, task.Blocked)
+
+	assert.NoError(t, s.Task().RemoveBlocker(1, ids[2], ids[1]))
+	assert.EqualError(t, s.Task().RemoveBlocker(1, ids[2], ids[1]), store.ErrNoDependency.Error())
+	task, _ = s.Task().GetById(1, ids[2])
+	assert.Empty(t, task.BlockedBy)
+}
+
+func TestTaskRepository_CompleteAllBlocked(t *testing.T) {
+	s := teststore.New()
+	var ids []int
+	for i := 0; i < 3; i++ {
+		task := model.TestTask(t)
+		s.Task().Create(task)
+		ids = append(ids, task.ID)
+	}
+
+	// Blockers created later are completed first
+	assert.NoError(t, s.Task().AddBlocker(1, ids[0], ids[1]))
+	assert.NoError(t, s.Task().AddBlocker(1, ids[1], ids[2]))
+
+	n, blocked, err := s.Task().CompleteAll(1)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.Empty(t, blocked)
+}
+
+func TestTaskRepository_CompleteAllBlockedOutside(t *testing.T) {
+	s := teststore.New()
+	var ids []int
+	for i := 0; i < 3; i++ {
+		task := model.TestTask(t)
+		s.Task().Create(task)
+		ids = append(ids, task.ID)
+	}
+
+	other := model.TestTask(t)
+	other.UserID = 2
+	s.Task().Create(other)
+	share := model.TestShare(t)
+	share.OwnerID, share.UserID, share.TaskID = 2, 1, &other.ID
+	s.Share().Create(share)
+	assert.NoError(t, s.Task().AddBlocker(1, ids[1], other.ID))
+	assert.NoError(t, s.Task().AddBlocker(1, ids[2], ids[1]))
+
+	// The tasks blocked by the other user, directly or not, are left open
+	n, blocked, err := s.Task().CompleteAll(1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []int{ids[1], ids[2]}, blocked)
+	for i, id := range ids {
+		task, err := s.Task().GetById(1, id)
+		assert.NoError(t, err)
+		assert.Equal(t, i == 0, task.Done)
+	}
+
+	assert.NoError(t, s.Task().Done(2, other.ID))
+	n, blocked, err = s.Task().CompleteAll(1)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Empty(t, blocked)
+}
+
+func TestTaskRepository_ReportHistory(t *testing.T) {
+	s := teststore.New()
+	p := model.TestProject(t)
+	s.Project().Create(p)
+	tag := model.TestTag(t)
+	s.Tag().Create(tag)
+
+	estimate := 3
+	var ids []int
+	for i := 0; i < 3; i++ {
+		task := model.TestTask(t)
+		task.ProjectID = &p.ID
+		task.Estimate = &estimate
+		s.Task().Create(task)
+		ids = append(ids, task.ID)
+	}
+	s.Task().Create(model.TestTask(t))
+	s.Tag().Attach(1, ids[0], tag.ID)
+
+	edited, _ := s.Task().GetById(1, ids[0])
+	reestimated := *edited
+	five := 5
+	reestimated.Estimate = &five
+	assert.NoError(t, s.Task().Update(1, &reestimated))
+	assert.NoError(t, s.Task().Done(1, ids[1]))
+	assert.NoError(t, s.Task().Delete(1, ids[2]))
+
+	// Tasks in the trash are reported too
+	tasks, events, err := s.Task().ReportHistory(1, &store.ReportScope{ProjectID: &p.ID})
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 3)
+	assert.Len(t, events, 6)
+
+	now := time.Now()
+	points := model.Burndown(tasks, events, now, now, now)
+	assert.Equal(t, 5, *points[0].Remaining)
+	assert.Equal(t, 3, *points[0].Completed)
+
+	tasks, _, err = s.Task().ReportHistory(1, &store.ReportScope{Tag: tag.Name})
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 1)
+	assert.Equal(t, 5, *tasks[0].Estimate)
+}
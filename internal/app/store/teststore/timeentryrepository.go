@@ -0,0 +1,136 @@
+package teststore
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/pyuldashev912/todoapp/internal/app/store"
+)
+
+type TimeEntryRepository struct {
+	store   *Store
+	entries map[int]*model.TimeEntry
+	lastId  int
+}
+
+func (r *TimeEntryRepository) Start(userId int, taskId int) (*model.TimeEntry, error) {
+	if _, err := r.store.tasks().editable(userId, taskId); err != nil {
+		return nil, err
+	}
+
+	if _, err := r.Running(userId); err == nil {
+		return nil, store.ErrTimerRunning
+	}
+
+	r.lastId++
+	e := &model.TimeEntry{ID: r.lastId, UserID: userId, TaskID: taskId, StartedAt: time.Now()}
+	r.entries[e.ID] = e
+
+	return r.copy(e), nil
+}
+
+func (r *TimeEntryRepository) Stop(userId int, taskId int) (*model.TimeEntry, error) {
+	e, err := r.Running(userId)
+	if err != nil || e.TaskID != taskId {
+		return nil, store.ErrNoTimerRunning
+	}
+
+	now := time.Now()
+	r.entries[e.ID].StoppedAt = &now
+
+	return r.copy(r.entries[e.ID]), nil
+}
+
+func (r *TimeEntryRepository) Running(userId int) (*model.TimeEntry, error) {
+	for _, e := range r.entries {
+		if e.UserID == userId && e.Running() {
+			return r.copy(e), nil
+		}
+	}
+
+	return nil, store.ErrNoRecordsInTable
+}
+
+func (r *TimeEntryRepository) Create(e *model.TimeEntry) error {
+	if err := e.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := r.store.tasks().editable(e.UserID, e.TaskID); err != nil {
+		return err
+	}
+
+	r.lastId++
+	e.ID = r.lastId
+	e.SetDuration(time.Now())
+	r.entries[e.ID] = r.copy(e)
+
+	return nil
+}
+
+func (r *TimeEntryRepository) Delete(userId int, entryId int) error {
+	e, ok := r.entries[entryId]
+	if !ok || e.UserID != userId {
+		return store.ErrNoRecordsInTable
+	}
+
+	delete(r.entries, entryId)
+	return nil
+}
+
+func (r *TimeEntryRepository) GetByTask(userId int, taskId int) ([]*model.TimeEntry, error) {
+	if _, err := r.store.Task().GetById(userId, taskId); err != nil {
+		return nil, err
+	}
+
+	return r.filter(func(e *model.TimeEntry) bool {
+		return e.UserID == userId && e.TaskID == taskId
+	}), nil
+}
+
+func (r *TimeEntryRepository) Find(userId int, from time.Time, to time.Time) ([]*model.TimeEntry, error) {
+	tasks := r.store.tasks().tasks
+
+	return r.filter(func(e *model.TimeEntry) bool {
+		task, ok := tasks[e.TaskID]
+		return e.UserID == userId && ok && task.DeletedAt == nil &&
+			e.StartedAt.Before(to) && (e.Running() || e.StoppedAt.After(from))
+	}), nil
+}
+
+// filter returns copies of the matching entries from the oldest
+func (r *TimeEntryRepository) filter(match func(*model.TimeEntry) bool) []*model.TimeEntry {
+	entries := []*model.TimeEntry{}
+	for _, e := range r.entries {
+		if match(e) {
+			entries = append(entries, r.copy(e))
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].StartedAt.Equal(entries[j].StartedAt) {
+			return entries[i].StartedAt.Before(entries[j].StartedAt)
+		}
+		return entries[i].ID < entries[j].ID
+	})
+
+	return entries
+}
+
+// copy returns a copy of the entry with its current duration, so callers
+// can't change stored entries
+func (r *TimeEntryRepository) copy(e *model.TimeEntry) *model.TimeEntry {
+	c := *e
+	c.SetDuration(time.Now())
+	return &c
+}
+
+// deleteTask drops the entries of a purged task the same way the database cascades
+func (r *TimeEntryRepository) deleteTask(taskId int) {
+	for id, e := range r.entries {
+		if e.TaskID == taskId {
+			delete(r.entries, id)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package teststore_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/pyuldashev912/todoapp/internal/app/store"
+	"github.com/pyuldashev912/todoapp/internal/app/store/teststore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeEntryRepository_Timer(t *testing.T) {
+	s := teststore.New()
+	first, second := model.TestTask(t), model.TestTask(t)
+	s.Task().Create(first)
+	s.Task().Create(second)
+
+	e, err := s.TimeEntry().Start(1, first.ID)
+	assert.NoError(t, err)
+	assert.True(t, e.Running())
+
+	// One running timer per user
+	_, err = s.TimeEntry().Start(1, second.ID)
+	assert.EqualError(t, err, store.ErrTimerRunning.Error())
+	_, err = s.TimeEntry().Stop(1, second.ID)
+	assert.EqualError(t, err, store.ErrNoTimerRunning.Error())
+
+	running, err := s.TimeEntry().Running(1)
+	assert.NoError(t, err)
+	assert.Equal(t, e.ID, running.ID)
+
+	stopped, err := s.TimeEntry().Stop(1, first.ID)
+	assert.NoError(t, err)
+	assert.False(t, stopped.Running())
+	_, err = s.TimeEntry().Running(1)
+	assert.EqualError(t, err, store.ErrNoRecordsInTable.Error())
+
+	_, err = s.TimeEntry().Start(1, second.ID)
+	assert.NoError(t, err)
+	_, err = s.TimeEntry().Start(2, first.ID)
+	assert.EqualError(t, err, store.ErrInvalidTaskId.Error())
+}
+
+func TestTimeEntryRepository_Create(t *testing.T) {
+	s := teststore.New()
+	task := model.TestTask(t)
+	s.Task().Create(task)
+
+	e := model.TestTimeEntry(t)
+	e.TaskID = task.ID
+	assert.NoError(t, s.TimeEntry().Create(e))
+	assert.NotZero(t, e.ID)
+	assert.Equal(t, int64(90*60), e.Duration)
+
+	invalid := model.TestTimeEntry(t)
+	invalid.TaskID = task.ID
+	invalid.StoppedAt = nil
+	assert.Error(t, s.TimeEntry().Create(invalid))
+
+	entries, err := s.TimeEntry().GetByTask(1, task.ID)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	assert.EqualError(t, s.TimeEntry().Delete(2, e.ID), store.ErrNoRecordsInTable.Error())
+	assert.NoError(t, s.TimeEntry().Delete(1, e.ID))
+	entries, _ = s.TimeEntry().GetByTask(1, task.ID)
+	assert.Empty(t, entries)
+}
+
+func TestTimeEntryRepository_Find(t *testing.T) {
+	s := teststore.New()
+	task, trashed := model.TestTask(t), model.TestTask(t)
+	s.Task().Create(task)
+	s.Task().Create(trashed)
+
+	for _, taskId := range []int{task.ID, trashed.ID} {
+		e := model.TestTimeEntry(t)
+		e.TaskID = taskId
+		s.TimeEntry().Create(e)
+	}
+	s.Task().Delete(1, trashed.ID)
+
+	from := model.TestTimeEntry(t).StartedAt.Add(-time.Hour)
+	entries, err := s.TimeEntry().Find(1, from, from.Add(24*time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	entries, err = s.TimeEntry().Find(1, from.Add(24*time.Hour), from.Add(48*time.Hour))
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -1,12 +1,20 @@
 package teststore
 
 import (
+	"time"
+
 	"github.com/pyuldashev912/todoapp/internal/app/model"
 	"github.com/pyuldashev912/todoapp/internal/app/store"
 )
 
 type UserRepository struct {
 	users map[int]*model.User
+	// resets holds the password resets by the hash of their token
+	resets      map[string]*model.PasswordReset
+	lastResetId int
+	// verifications holds the email verifications by the hash of their token
+	verifications      map[string]*model.EmailVerification
+	lastVerificationId int
 }
 
 func (r *UserRepository) Create(user *model.User) error {
@@ -41,3 +49,100 @@ func (r *UserRepository) FindById(id int) (*model.User, error) {
 
 	return u, nil
 }
+
+func (r *UserRepository) CreatePasswordReset(p *model.PasswordReset) error {
+	if err := p.BeforeCreate(time.Now()); err != nil {
+		return err
+	}
+
+	r.deleteResets(p.UserID)
+	r.lastResetId++
+	p.ID = r.lastResetId
+	p.CreatedAt = time.Now()
+	stored := *p
+	stored.Token = ""
+	r.resets[p.TokenHash] = &stored
+
+	return nil
+}
+
+func (r *UserRepository) ResetPassword(token string, password string) (*model.User, error) {
+	user := &model.User{Password: password}
+	if err := user.ValidatePassword(); err != nil {
+		return nil, err
+	}
+
+	p, ok := r.resets[model.HashToken(token)]
+	if !ok || !p.ExpiresAt.After(time.Now()) {
+		return nil, store.ErrInvalidResetToken
+	}
+
+	stored, ok := r.users[p.UserID]
+	if !ok {
+		return nil, store.ErrInvalidResetToken
+	}
+
+	if err := user.EncryptPassword(); err != nil {
+		return nil, err
+	}
+
+	r.deleteResets(p.UserID)
+	stored.EncryptedPassword = user.EncryptedPassword
+	stored.SessionVersion++
+	stored.Verified = true
+
+	found := *stored
+	found.Sanitize()
+	return &found, nil
+}
+
+func (r *UserRepository) CreateVerification(v *model.EmailVerification) error {
+	if err := v.BeforeCreate(time.Now()); err != nil {
+		return err
+	}
+
+	r.deleteVerifications(v.UserID)
+	r.lastVerificationId++
+	v.ID = r.lastVerificationId
+	v.CreatedAt = time.Now()
+	stored := *v
+	stored.Token = ""
+	r.verifications[v.TokenHash] = &stored
+
+	return nil
+}
+
+func (r *UserRepository) Verify(token string) (*model.User, error) {
+	v, ok := r.verifications[model.HashToken(token)]
+	if !ok || !v.ExpiresAt.After(time.Now()) {
+		return nil, store.ErrInvalidVerification
+	}
+
+	stored, ok := r.users[v.UserID]
+	if !ok {
+		return nil, store.ErrInvalidVerification
+	}
+
+	r.deleteVerifications(v.UserID)
+	stored.Verified = true
+
+	found := *stored
+	found.Sanitize()
+	return &found, nil
+}
+
+func (r *UserRepository) deleteResets(userId int) {
+	for hash, p := range r.resets {
+		if p.UserID == userId {
+			delete(r.resets, hash)
+		}
+	}
+}
+
+func (r *UserRepository) deleteVerifications(userId int) {
+	for hash, v := range r.verifications {
+		if v.UserID == userId {
+			delete(r.verifications, hash)
+		}
+	}
+}
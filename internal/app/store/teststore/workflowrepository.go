@@ -0,0 +1,110 @@
+package teststore
+
+import (
+	"github.com/pyuldashev912/todoapp/internal/app/model"
+	"github.com/pyuldashev912/todoapp/internal/app/store"
+)
+
+type WorkflowRepository struct {
+	store     *Store
+	workflows map[int]*model.Workflow
+	lastId    int
+}
+
+func (r *WorkflowRepository) Get(userId int, projectId *int) (*model.Workflow, error) {
+	if err := r.checkProject(userId, projectId); err != nil {
+		return nil, err
+	}
+
+	return r.of(userId, projectId), nil
+}
+
+func (r *WorkflowRepository) Save(w *model.Workflow) error {
+	if err := w.Validate(); err != nil {
+		return err
+	}
+
+	if err := r.checkProject(w.UserID, w.ProjectID); err != nil {
+		return err
+	}
+
+	if own := r.own(w.UserID, w.ProjectID); own != nil {
+		delete(r.workflows, own.ID)
+	}
+
+	r.lastId++
+	w.ID = r.lastId
+	r.workflows[w.ID] = w
+	r.remap(w.UserID)
+
+	return nil
+}
+
+func (r *WorkflowRepository) Delete(userId int, projectId *int) error {
+	own := r.own(userId, projectId)
+	if own == nil {
+		return store.ErrNoRecordsInTable
+	}
+
+	delete(r.workflows, own.ID)
+	r.remap(userId)
+
+	return nil
+}
+
+// of returns the workflow followed by the tasks of the project, or by the
+// tasks outside of projects if projectId is nil
+func (r *WorkflowRepository) of(userId int, projectId *int) *model.Workflow {
+	if projectId != nil {
+		if w := r.own(userId, projectId); w != nil {
+			return w
+		}
+	}
+
+	if w := r.own(userId, nil); w != nil {
+		return w
+	}
+
+	return model.DefaultWorkflow()
+}
+
+// own returns the workflow saved for the user or the project
+func (r *WorkflowRepository) own(userId int, projectId *int) *model.Workflow {
+	for _, w := range r.workflows {
+		if w.UserID != userId || (w.ProjectID == nil) != (projectId == nil) {
+			continue
+		}
+
+		if projectId == nil || *w.ProjectID == *projectId {
+			return w
+		}
+	}
+
+	return nil
+}
+
+func (r *WorkflowRepository) checkProject(userId int, projectId *int) error {
+	if projectId == nil {
+		return nil
+	}
+
+	_, err := r.store.Project().GetById(userId, *projectId)
+	return err
+}
+
+// remap moves the user's tasks with a status their workflow doesn't have to
+// its initial or done status, and drops workflows of deleted projects the
+// same way the database cascades
+func (r *WorkflowRepository) remap(userId int) {
+	for id, w := range r.workflows {
+		if w.UserID == userId && w.ProjectID != nil && r.checkProject(userId, w.ProjectID) != nil {
+			delete(r.workflows, id)
+		}
+	}
+
+	for _, task := range r.store.tasks().tasks {
+		if task.UserID == userId {
+			task.Status = r.of(userId, task.ProjectID).StatusOf(task)
+		}
+	}
+}
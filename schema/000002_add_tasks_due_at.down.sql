@@ -0,0 +1,3 @@
+DROP INDEX tasks_user_id_due_at_idx;
+
+ALTER TABLE tasks DROP COLUMN due_at;
\ No newline at end of file
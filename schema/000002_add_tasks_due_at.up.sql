@@ -0,0 +1,3 @@
+ALTER TABLE tasks ADD COLUMN due_at TIMESTAMPTZ;
+
+CREATE INDEX tasks_user_id_due_at_idx ON tasks (user_id, due_at);
\ No newline at end of file
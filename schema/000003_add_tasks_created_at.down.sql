@@ -0,0 +1,5 @@
+DROP INDEX tasks_user_id_title_id_idx;
+
+DROP INDEX tasks_user_id_created_at_id_idx;
+
+ALTER TABLE tasks DROP COLUMN created_at;
\ No newline at end of file
@@ -0,0 +1,8 @@
+ALTER TABLE tasks ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+UPDATE tasks SET created_at = to_timestamp(creation_date, 'DD/MM/YY')
+WHERE creation_date ~ '^\d{2}/\d{2}/\d{2}$';
+
+CREATE INDEX tasks_user_id_created_at_id_idx ON tasks (user_id, created_at, id);
+
+CREATE INDEX tasks_user_id_title_id_idx ON tasks (user_id, title, id);
\ No newline at end of file
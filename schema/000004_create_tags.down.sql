@@ -0,0 +1,3 @@
+DROP TABLE task_tags;
+
+DROP TABLE tags;
\ No newline at end of file
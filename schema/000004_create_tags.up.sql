@@ -0,0 +1,14 @@
+CREATE TABLE tags (
+  id BIGSERIAL not NULL PRIMARY KEY,
+  user_id BIGINT NOT NULL,
+  name VARCHAR NOT NULL,
+  UNIQUE (user_id, name)
+);
+
+CREATE TABLE task_tags (
+  task_id BIGINT NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+  tag_id BIGINT NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+  PRIMARY KEY (task_id, tag_id)
+);
+
+CREATE INDEX task_tags_tag_id_idx ON task_tags (tag_id);
\ No newline at end of file
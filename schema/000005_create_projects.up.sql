@@ -0,0 +1,13 @@
+CREATE TABLE projects (
+  id BIGSERIAL not NULL PRIMARY KEY,
+  user_id BIGINT NOT NULL,
+  name VARCHAR NOT NULL,
+  description VARCHAR NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX projects_user_id_idx ON projects (user_id);
+
+ALTER TABLE tasks ADD COLUMN project_id BIGINT REFERENCES projects (id) ON DELETE SET NULL;
+
+CREATE INDEX tasks_project_id_idx ON tasks (project_id);
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE tasks DROP COLUMN parent_id;
\ No newline at end of file
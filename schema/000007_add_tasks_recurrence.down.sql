@@ -0,0 +1,5 @@
+DROP INDEX tasks_series_idx;
+
+ALTER TABLE tasks DROP COLUMN series_id;
+
+ALTER TABLE tasks DROP COLUMN recurrence;
\ No newline at end of file
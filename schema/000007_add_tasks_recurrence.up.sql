@@ -0,0 +1,5 @@
+ALTER TABLE tasks ADD COLUMN recurrence VARCHAR NOT NULL DEFAULT '';
+
+ALTER TABLE tasks ADD COLUMN series_id BIGINT;
+
+CREATE INDEX tasks_series_idx ON tasks ((COALESCE(series_id, id)));
\ No newline at end of file
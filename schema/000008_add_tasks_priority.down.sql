@@ -0,0 +1,3 @@
+DROP INDEX tasks_user_id_priority_rank_id_idx;
+
+ALTER TABLE tasks DROP COLUMN priority;
\ No newline at end of file
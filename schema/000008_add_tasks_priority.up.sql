@@ -0,0 +1,4 @@
+ALTER TABLE tasks ADD COLUMN priority SMALLINT NOT NULL DEFAULT 0;
+
+CREATE INDEX tasks_user_id_priority_rank_id_idx ON tasks
+    (user_id, (CASE WHEN done THEN 5 - priority ELSE -priority END), id);
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE task_events;
\ No newline at end of file
@@ -0,0 +1,10 @@
+CREATE TABLE task_events (
+  id BIGSERIAL not NULL PRIMARY KEY,
+  task_id BIGINT NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+  user_id BIGINT NOT NULL,
+  action VARCHAR NOT NULL,
+  changes JSONB NOT NULL DEFAULT '{}',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX task_events_task_id_created_at_idx ON task_events (task_id, created_at, id);
\ No newline at end of file
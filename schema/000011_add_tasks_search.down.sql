@@ -0,0 +1,3 @@
+DROP INDEX tasks_search_idx;
+
+ALTER TABLE tasks DROP COLUMN search;
\ No newline at end of file
@@ -0,0 +1,5 @@
+ALTER TABLE tasks ADD COLUMN search tsvector GENERATED ALWAYS AS (
+  setweight(to_tsvector('english', title), 'A') || setweight(to_tsvector('english', description), 'B')
+) STORED;
+
+CREATE INDEX tasks_search_idx ON tasks USING GIN (search);
\ No newline at end of file
@@ -0,0 +1,9 @@
+ALTER TABLE tasks ADD COLUMN rank VARCHAR COLLATE "C" NOT NULL DEFAULT '';
+
+-- Existing tasks keep their creation order
+UPDATE tasks SET rank = ranked.rank FROM (
+    SELECT id, lpad(to_hex(row_number() OVER (PARTITION BY user_id ORDER BY created_at, id)), 6, '0') AS rank
+    FROM tasks
+) AS ranked WHERE tasks.id = ranked.id;
+
+CREATE INDEX tasks_user_id_rank_id_idx ON tasks (user_id, rank, id);
\ No newline at end of file
@@ -0,0 +1,5 @@
+DROP INDEX tasks_user_id_status_idx;
+
+ALTER TABLE tasks DROP COLUMN status;
+
+DROP TABLE workflows;
\ No newline at end of file
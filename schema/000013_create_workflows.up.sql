@@ -0,0 +1,17 @@
+CREATE TABLE workflows (
+  id BIGSERIAL not NULL PRIMARY KEY,
+  user_id BIGINT NOT NULL,
+  project_id BIGINT REFERENCES projects (id) ON DELETE CASCADE,
+  statuses JSONB NOT NULL,
+  transitions JSONB
+);
+
+CREATE UNIQUE INDEX workflows_user_id_idx ON workflows (user_id) WHERE project_id IS NULL;
+CREATE UNIQUE INDEX workflows_project_id_idx ON workflows (project_id) WHERE project_id IS NOT NULL;
+
+ALTER TABLE tasks ADD COLUMN status VARCHAR NOT NULL DEFAULT 'todo';
+
+-- Completed tasks start in the done status of the default workflow
+UPDATE tasks SET status = 'done' WHERE done;
+
+CREATE INDEX tasks_user_id_status_idx ON tasks (user_id, status);
\ No newline at end of file
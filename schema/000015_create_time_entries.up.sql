@@ -0,0 +1,15 @@
+CREATE TABLE time_entries (
+  id BIGSERIAL not NULL PRIMARY KEY,
+  user_id BIGINT NOT NULL,
+  task_id BIGINT NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+  started_at TIMESTAMPTZ NOT NULL,
+  stopped_at TIMESTAMPTZ,
+  note VARCHAR NOT NULL DEFAULT '',
+  CHECK (stopped_at >= started_at)
+);
+
+CREATE INDEX time_entries_task_id_idx ON time_entries (task_id);
+CREATE INDEX time_entries_user_id_started_at_idx ON time_entries (user_id, started_at);
+
+-- At most one running timer per user
+CREATE UNIQUE INDEX time_entries_running_idx ON time_entries (user_id) WHERE stopped_at IS NULL;
\ No newline at end of file
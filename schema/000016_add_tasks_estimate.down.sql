@@ -0,0 +1 @@
+ALTER TABLE tasks DROP COLUMN estimate;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE tasks ADD COLUMN estimate INTEGER CHECK (estimate >= 0);
\ No newline at end of file
@@ -0,0 +1,10 @@
+CREATE TABLE comments (
+  id BIGSERIAL not NULL PRIMARY KEY,
+  task_id BIGINT NOT NULL REFERENCES tasks (id) ON DELETE CASCADE,
+  user_id BIGINT NOT NULL,
+  body TEXT NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  updated_at TIMESTAMPTZ
+);
+
+CREATE INDEX comments_task_id_created_at_idx ON comments (task_id, created_at, id);
\ No newline at end of file
@@ -0,0 +1,14 @@
+CREATE TABLE attachments (
+  id BIGSERIAL not NULL PRIMARY KEY,
+  -- Purging a task keeps its attachments until their blobs are deleted
+  task_id BIGINT REFERENCES tasks (id) ON DELETE SET NULL,
+  user_id BIGINT NOT NULL,
+  name VARCHAR NOT NULL,
+  content_type VARCHAR NOT NULL,
+  size BIGINT NOT NULL,
+  key VARCHAR NOT NULL UNIQUE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX attachments_task_id_idx ON attachments (task_id);
+CREATE INDEX attachments_orphans_idx ON attachments (id) WHERE task_id IS NULL;
\ No newline at end of file
@@ -0,0 +1,14 @@
+CREATE TABLE shares (
+  id BIGSERIAL not NULL PRIMARY KEY,
+  owner_id BIGINT NOT NULL,
+  user_id BIGINT NOT NULL,
+  task_id BIGINT REFERENCES tasks (id) ON DELETE CASCADE,
+  project_id BIGINT REFERENCES projects (id) ON DELETE CASCADE,
+  role VARCHAR NOT NULL CHECK (role IN ('viewer', 'editor')),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  CHECK ((task_id IS NULL) <> (project_id IS NULL)),
+  UNIQUE (task_id, user_id),
+  UNIQUE (project_id, user_id)
+);
+
+CREATE INDEX shares_user_id_idx ON shares (user_id);
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE tasks DROP COLUMN assignee_id;
\ No newline at end of file
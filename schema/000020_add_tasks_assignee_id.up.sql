@@ -0,0 +1,3 @@
+ALTER TABLE tasks ADD COLUMN assignee_id BIGINT;
+
+CREATE INDEX tasks_assignee_id_idx ON tasks (assignee_id);
\ No newline at end of file
@@ -0,0 +1,7 @@
+ALTER TABLE projects DROP COLUMN organization_id;
+
+DROP TABLE invitations;
+
+DROP TABLE memberships;
+
+DROP TABLE organizations;
\ No newline at end of file
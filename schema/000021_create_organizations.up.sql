@@ -0,0 +1,32 @@
+CREATE TABLE organizations (
+  id BIGSERIAL not NULL PRIMARY KEY,
+  name VARCHAR NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE memberships (
+  organization_id BIGINT NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+  user_id BIGINT NOT NULL,
+  role VARCHAR NOT NULL CHECK (role IN ('owner', 'admin', 'member')),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (organization_id, user_id)
+);
+
+CREATE INDEX memberships_user_id_idx ON memberships (user_id);
+
+CREATE TABLE invitations (
+  id BIGSERIAL not NULL PRIMARY KEY,
+  organization_id BIGINT NOT NULL REFERENCES organizations (id) ON DELETE CASCADE,
+  email VARCHAR NOT NULL,
+  role VARCHAR NOT NULL CHECK (role IN ('admin', 'member')),
+  token_hash VARCHAR NOT NULL UNIQUE,
+  invited_by BIGINT NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX invitations_organization_id_idx ON invitations (organization_id);
+
+ALTER TABLE projects ADD COLUMN organization_id BIGINT REFERENCES organizations (id) ON DELETE SET NULL;
+
+CREATE INDEX projects_organization_id_idx ON projects (organization_id);
\ No newline at end of file
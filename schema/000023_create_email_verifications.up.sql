@@ -0,0 +1,13 @@
+ALTER TABLE users ADD COLUMN verified BOOLEAN NOT NULL DEFAULT false;
+
+UPDATE users SET verified = true;
+
+CREATE TABLE email_verifications (
+  id BIGSERIAL not NULL PRIMARY KEY,
+  user_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+  token_hash VARCHAR NOT NULL UNIQUE,
+  expires_at TIMESTAMPTZ NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX email_verifications_user_id_idx ON email_verifications (user_id);
\ No newline at end of file